@@ -0,0 +1,233 @@
+// Command gokv gets, sets, deletes, lists, dumps and loads the key values of a store.
+//
+// Usage:
+//
+//	gokv [flags] get <key>
+//	gokv [flags] set <key> <value>
+//	gokv [flags] del <key>
+//	gokv [flags] list
+//	gokv [flags] dump > dump.json
+//	gokv [flags] load < dump.json
+//
+// The output is JSON by default, or plain text with -human.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/bingoohuang/gokv"
+	"github.com/bingoohuang/gokv/pkg/codec"
+	"github.com/bingoohuang/gokv/pkg/memory"
+	"github.com/bingoohuang/gokv/pkg/sqlc"
+	_ "github.com/go-sql-driver/mysql"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// env is the environment of a command.
+type env struct {
+	store gokv.Store
+	// codec encodes the dump and decodes the load.
+	codec codec.Codec
+	human bool
+	in    io.Reader
+	out   io.Writer
+}
+
+// command runs with the arguments after the command name.
+type command func(e *env, args []string) error
+
+var commands = map[string]command{
+	"get":  cmdGet,
+	"set":  cmdSet,
+	"del":  cmdDel,
+	"list": cmdList,
+	"dump": cmdDump,
+	"load": cmdLoad,
+}
+
+var errUsage = errors.New("usage: gokv [flags] get|set|del|list|dump|load [args]")
+
+func run(args []string, in io.Reader, out, errOut io.Writer) int {
+	f := flag.NewFlagSet("gokv", flag.ContinueOnError)
+	f.SetOutput(errOut)
+	backend := f.String("backend", "sql", "the backend, sql or memory")
+	driver := f.String("driver", "mysql", "the SQL driver name of the sql backend")
+	dsn := f.String("dsn", "", "the data source name of the sql backend")
+	codecName := f.String("codec", "json", "the codec of dump/load, json or gob")
+	human := f.Bool("human", false, "print in human readable plain text instead of JSON")
+
+	if err := f.Parse(args); err != nil {
+		return 2
+	}
+
+	cmd, ok := commands[f.Arg(0)]
+	if !ok {
+		fmt.Fprintln(errOut, errUsage)
+		return 2
+	}
+
+	c, err := newCodec(*codecName)
+	if err != nil {
+		fmt.Fprintln(errOut, err)
+		return 2
+	}
+
+	store, err := newStore(*backend, *driver, *dsn)
+	if err != nil {
+		fmt.Fprintln(errOut, err)
+		return 2
+	}
+
+	if closer, ok := store.(gokv.Closer); ok {
+		defer closer.Close()
+	}
+
+	if err := cmd(&env{store: store, codec: c, human: *human, in: in, out: out}, f.Args()[1:]); err != nil {
+		fmt.Fprintln(errOut, err)
+		return 1
+	}
+
+	return 0
+}
+
+func newCodec(name string) (codec.Codec, error) {
+	switch name {
+	case "json":
+		return codec.JSON, nil
+	case "gob":
+		return codec.Gob, nil
+	default:
+		return nil, fmt.Errorf("unknown codec %q", name)
+	}
+}
+
+func newStore(backend, driver, dsn string) (gokv.Store, error) {
+	switch backend {
+	case "memory":
+		return memory.New(), nil
+	case "sql":
+		client := sqlc.NewClient(sqlc.Config{DriverName: driver, DataSourceName: dsn})
+		return closingStore{Store: client.AsStore(), Closer: client}, nil
+	default:
+		return nil, fmt.Errorf("unknown backend %q", backend)
+	}
+}
+
+// closingStore is a gokv.Store which closes its underlying client.
+type closingStore struct {
+	gokv.Store
+	gokv.Closer
+}
+
+// print prints v as JSON, or as the plain text when human is set.
+func (e *env) print(v interface{}, text string) error {
+	if e.human {
+		_, err := fmt.Fprintln(e.out, text)
+		return err
+	}
+
+	data, err := codec.JSON.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintln(e.out, string(data))
+
+	return err
+}
+
+func cmdGet(e *env, args []string) error {
+	if len(args) != 1 {
+		return errors.New("usage: gokv get <key>")
+	}
+
+	v, err := e.store.Get(args[0])
+	if err != nil {
+		return err
+	}
+
+	return e.print(map[string]string{"key": args[0], "value": v}, v)
+}
+
+func cmdSet(e *env, args []string) error {
+	if len(args) != 2 {
+		return errors.New("usage: gokv set <key> <value>")
+	}
+
+	if err := e.store.Set(args[0], args[1]); err != nil {
+		return err
+	}
+
+	return e.print(map[string]string{"key": args[0], "value": args[1]}, "OK")
+}
+
+func cmdDel(e *env, args []string) error {
+	if len(args) != 1 {
+		return errors.New("usage: gokv del <key>")
+	}
+
+	if err := e.store.Del(args[0]); err != nil {
+		return err
+	}
+
+	return e.print(map[string]string{"key": args[0]}, "OK")
+}
+
+func cmdList(e *env, _ []string) error {
+	all, err := e.store.All()
+	if err != nil {
+		return err
+	}
+
+	keys := make([]string, 0, len(all))
+	for k := range all {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	return e.print(keys, strings.Join(keys, "\n"))
+}
+
+func cmdDump(e *env, _ []string) error {
+	all, err := e.store.All()
+	if err != nil {
+		return err
+	}
+
+	data, err := e.codec.Marshal(all)
+	if err != nil {
+		return err
+	}
+
+	_, err = e.out.Write(data)
+
+	return err
+}
+
+func cmdLoad(e *env, _ []string) error {
+	data, err := io.ReadAll(e.in)
+	if err != nil {
+		return err
+	}
+
+	var kvs map[string]string
+	if err := e.codec.Unmarshal(data, &kvs); err != nil {
+		return err
+	}
+
+	for k, v := range kvs {
+		if err := e.store.Set(k, v); err != nil {
+			return err
+		}
+	}
+
+	return e.print(map[string]int{"loaded": len(kvs)}, fmt.Sprintf("%d loaded", len(kvs)))
+}
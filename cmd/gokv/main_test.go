@@ -0,0 +1,80 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"github.com/bingoohuang/gokv"
+	"github.com/bingoohuang/gokv/pkg/codec"
+	"github.com/bingoohuang/gokv/pkg/memory"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func TestCommands(t *testing.T) {
+	var out bytes.Buffer
+	e := &env{store: memory.New(), codec: codec.JSON, out: &out}
+
+	assert.Nil(t, cmdSet(e, []string{"k1", "v1"}))
+	assert.Nil(t, cmdSet(e, []string{"k2", "v2"}))
+	assert.Equal(t, `{"key":"k1","value":"v1"}`+"\n"+`{"key":"k2","value":"v2"}`+"\n", out.String())
+
+	out.Reset()
+	assert.Nil(t, cmdGet(e, []string{"k1"}))
+	assert.Equal(t, `{"key":"k1","value":"v1"}`+"\n", out.String())
+
+	out.Reset()
+	assert.Nil(t, cmdList(e, nil))
+	assert.Equal(t, `["k1","k2"]`+"\n", out.String())
+
+	out.Reset()
+	assert.Nil(t, cmdDump(e, nil))
+	dump := out.String()
+	assert.JSONEq(t, `{"k1":"v1","k2":"v2"}`, dump)
+
+	out.Reset()
+	assert.Nil(t, cmdDel(e, []string{"k1"}))
+	assert.Equal(t, `{"key":"k1"}`+"\n", out.String())
+	assert.True(t, errors.Is(cmdGet(e, []string{"k1"}), gokv.ErrKeyNotFound))
+
+	loaded := &env{store: memory.New(), codec: codec.JSON, in: strings.NewReader(dump), out: &out}
+	out.Reset()
+	assert.Nil(t, cmdLoad(loaded, nil))
+	assert.Equal(t, `{"loaded":2}`+"\n", out.String())
+	all, _ := loaded.store.All()
+	assert.Equal(t, map[string]string{"k1": "v1", "k2": "v2"}, all)
+
+	assert.NotNil(t, cmdGet(e, nil))
+}
+
+func TestHumanMode(t *testing.T) {
+	var out bytes.Buffer
+	e := &env{store: memory.New(), codec: codec.Gob, human: true, out: &out}
+
+	assert.Nil(t, cmdSet(e, []string{"k1", "v1"}))
+	assert.Nil(t, cmdSet(e, []string{"k2", "v2"}))
+	assert.Nil(t, cmdGet(e, []string{"k1"}))
+	assert.Nil(t, cmdList(e, nil))
+	assert.Equal(t, "OK\nOK\nv1\nk1\nk2\n", out.String())
+
+	out.Reset()
+	assert.Nil(t, cmdDump(e, nil))
+
+	loaded := &env{store: memory.New(), codec: codec.Gob, human: true, in: &out, out: &bytes.Buffer{}}
+	assert.Nil(t, cmdLoad(loaded, nil))
+	v, err := loaded.store.Get("k2")
+	assert.Nil(t, err)
+	assert.Equal(t, "v2", v)
+}
+
+func TestRun(t *testing.T) {
+	var out, errOut bytes.Buffer
+	assert.Equal(t, 0, run([]string{"-backend", "memory", "list"}, nil, &out, &errOut))
+	assert.Equal(t, "[]\n", out.String())
+
+	assert.Equal(t, 1, run([]string{"-backend", "memory", "get", "missing"}, nil, &out, &errOut))
+	assert.Contains(t, errOut.String(), gokv.ErrKeyNotFound.Error())
+
+	assert.Equal(t, 2, run([]string{"-backend", "memory", "unknown"}, nil, &out, &errOut))
+	assert.Equal(t, 2, run([]string{"-backend", "redis", "list"}, nil, &out, &errOut))
+}
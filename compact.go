@@ -0,0 +1,20 @@
+package gokv
+
+import "context"
+
+// Compactable is implemented by the stores which reclaim the space of the deleted and overwritten values
+// by compaction, e.g. the embedded databases of files.
+type Compactable interface {
+	// Compact compacts the store, it may block the writes while compacting.
+	Compact(ctx context.Context) error
+}
+
+// Compact compacts s, ErrNotSupported is returned when s is not Compactable.
+func Compact(ctx context.Context, s Store) error {
+	c, ok := s.(Compactable)
+	if !ok {
+		return ErrNotSupported
+	}
+
+	return c.Compact(ctx)
+}
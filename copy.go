@@ -0,0 +1,76 @@
+package gokv
+
+import (
+	"context"
+	"go.uber.org/multierr"
+	"sync"
+)
+
+// CopyOptions are the options of Copy.
+type CopyOptions struct {
+	// Concurrency is the max number of the concurrent writes to the destination, default to 1.
+	Concurrency int
+	// OnProgress is called after each key value is copied, with the number of the copied ones and the total.
+	OnProgress func(copied, total int)
+}
+
+// Copy copies all the key values of src to dst, e.g. to migrate between backends.
+// The writes are issued by at most opts.Concurrency workers, and the errors of them are aggregated.
+// No more writes are issued once ctx is done.
+func Copy(ctx context.Context, dst, src Store, opts CopyOptions) (er error) {
+	kvs, err := src.All()
+	if err != nil {
+		return err
+	}
+
+	concurrency := opts.Concurrency
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+
+	set := dst.Set
+	if cs, ok := dst.(ContextStore); ok {
+		set = func(k, v string) error { return cs.SetContext(ctx, k, v) }
+	}
+
+	var (
+		lock   sync.Mutex
+		copied int
+		wg     sync.WaitGroup
+		sem    = make(chan struct{}, concurrency)
+	)
+
+	total := len(kvs)
+	for k, v := range kvs {
+		select {
+		case <-ctx.Done():
+			wg.Wait()
+			return multierr.Append(er, ctx.Err())
+		case sem <- struct{}{}:
+		}
+
+		wg.Add(1)
+		go func(k, v string) {
+			defer func() { <-sem; wg.Done() }()
+
+			err := set(k, v)
+
+			lock.Lock()
+			defer lock.Unlock()
+
+			if err != nil {
+				er = multierr.Append(er, err)
+				return
+			}
+
+			copied++
+			if opts.OnProgress != nil {
+				opts.OnProgress(copied, total)
+			}
+		}(k, v)
+	}
+
+	wg.Wait()
+
+	return er
+}
@@ -0,0 +1,74 @@
+package gokv_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/bingoohuang/gokv"
+	"github.com/bingoohuang/gokv/pkg/memory"
+	"github.com/stretchr/testify/assert"
+	"sync/atomic"
+	"testing"
+)
+
+// concurrencyStore records the max number of the concurrent writes.
+type concurrencyStore struct {
+	*memory.Store
+	inflight, max int32
+	err           error
+}
+
+func (s *concurrencyStore) Set(k, v string) error {
+	n := atomic.AddInt32(&s.inflight, 1)
+	defer atomic.AddInt32(&s.inflight, -1)
+
+	for {
+		max := atomic.LoadInt32(&s.max)
+		if n <= max || atomic.CompareAndSwapInt32(&s.max, max, n) {
+			break
+		}
+	}
+
+	if s.err != nil {
+		return s.err
+	}
+
+	return s.Store.Set(k, v)
+}
+
+func TestCopy(t *testing.T) {
+	src := memory.New()
+	for i := 0; i < 10000; i++ {
+		assert.Nil(t, src.Set(fmt.Sprintf("k%d", i), fmt.Sprintf("v%d", i)))
+	}
+
+	dst := &concurrencyStore{Store: memory.New()}
+	var progress, lastCopied, lastTotal int
+	err := gokv.Copy(context.Background(), dst, src, gokv.CopyOptions{
+		Concurrency: 8,
+		OnProgress: func(copied, total int) {
+			progress++
+			lastCopied, lastTotal = copied, total
+		},
+	})
+	assert.Nil(t, err)
+
+	kvs, err := dst.All()
+	assert.Nil(t, err)
+	assert.Len(t, kvs, 10000)
+	assert.Equal(t, "v9999", kvs["k9999"])
+	assert.LessOrEqual(t, dst.max, int32(8))
+	assert.Equal(t, 10000, progress)
+	assert.Equal(t, 10000, lastCopied)
+	assert.Equal(t, 10000, lastTotal)
+
+	errFull := errors.New("disk full")
+	err = gokv.Copy(context.Background(), &concurrencyStore{Store: memory.New(), err: errFull}, src,
+		gokv.CopyOptions{Concurrency: 8})
+	assert.True(t, errors.Is(err, errFull))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err = gokv.Copy(ctx, memory.New(), src, gokv.CopyOptions{Concurrency: 8})
+	assert.True(t, errors.Is(err, context.Canceled))
+}
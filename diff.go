@@ -0,0 +1,49 @@
+package gokv
+
+import "sort"
+
+// DiffOptions are the options of Diff.
+type DiffOptions struct {
+	// Normalize normalizes the values before comparing, e.g. to ignore the formatting of JSON values,
+	// default to comparing the values as they are.
+	Normalize func(v string) string
+}
+
+// Diff compares the key values of the stores a and b loaded by All, e.g. to verify a migration,
+// and reports the sorted keys only in a, only in b, and the keys whose values differ.
+func Diff(a, b Store, opts DiffOptions) (onlyInA, onlyInB, differingValues []string, err error) {
+	kvsA, err := a.All()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	kvsB, err := b.All()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	normalize := opts.Normalize
+	if normalize == nil {
+		normalize = func(v string) string { return v }
+	}
+
+	for k, va := range kvsA {
+		if vb, ok := kvsB[k]; !ok {
+			onlyInA = append(onlyInA, k)
+		} else if normalize(va) != normalize(vb) {
+			differingValues = append(differingValues, k)
+		}
+	}
+
+	for k := range kvsB {
+		if _, ok := kvsA[k]; !ok {
+			onlyInB = append(onlyInB, k)
+		}
+	}
+
+	sort.Strings(onlyInA)
+	sort.Strings(onlyInB)
+	sort.Strings(differingValues)
+
+	return onlyInA, onlyInB, differingValues, nil
+}
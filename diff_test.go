@@ -0,0 +1,35 @@
+package gokv_test
+
+import (
+	"github.com/bingoohuang/gokv"
+	"github.com/bingoohuang/gokv/pkg/memory"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	a, b := memory.New(), memory.New()
+	for k, v := range map[string]string{"same": "v", "onlyA": "v", "differ": "a", "case": "Value"} {
+		assert.Nil(t, a.Set(k, v))
+	}
+	for k, v := range map[string]string{"same": "v", "onlyB1": "v", "onlyB2": "v", "differ": "b", "case": "value"} {
+		assert.Nil(t, b.Set(k, v))
+	}
+
+	onlyInA, onlyInB, differing, err := gokv.Diff(a, b, gokv.DiffOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"onlyA"}, onlyInA)
+	assert.Equal(t, []string{"onlyB1", "onlyB2"}, onlyInB)
+	assert.Equal(t, []string{"case", "differ"}, differing)
+
+	_, _, differing, err = gokv.Diff(a, b, gokv.DiffOptions{Normalize: strings.ToLower})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"differ"}, differing)
+
+	onlyInA, onlyInB, differing, err = gokv.Diff(a, a, gokv.DiffOptions{})
+	assert.Nil(t, err)
+	assert.Empty(t, onlyInA)
+	assert.Empty(t, onlyInB)
+	assert.Empty(t, differing)
+}
@@ -0,0 +1,23 @@
+package gokv
+
+// MapError maps the native error of a backend to the common error, e.g. ErrKeyNotFound or ErrConflict,
+// so that errors.Is(err, common) holds, while the native error is still available by errors.Unwrap and errors.As.
+func MapError(common, native error) error {
+	if native == nil {
+		return common
+	}
+
+	return &mappedError{common: common, native: native}
+}
+
+type mappedError struct {
+	common, native error
+}
+
+func (e *mappedError) Error() string { return e.common.Error() + ": " + e.native.Error() }
+
+// Is tells whether the target is the common error.
+func (e *mappedError) Is(target error) bool { return target == e.common }
+
+// Unwrap returns the native error.
+func (e *mappedError) Unwrap() error { return e.native }
@@ -0,0 +1,25 @@
+package gokv_test
+
+import (
+	"errors"
+	"github.com/bingoohuang/gokv"
+	"github.com/stretchr/testify/assert"
+	"io/fs"
+	"testing"
+)
+
+func TestMapError(t *testing.T) {
+	native := &fs.PathError{Op: "open", Path: "k1", Err: fs.ErrNotExist}
+	err := gokv.MapError(gokv.ErrKeyNotFound, native)
+
+	assert.True(t, errors.Is(err, gokv.ErrKeyNotFound))
+	assert.True(t, errors.Is(err, fs.ErrNotExist), "the native error is preserved")
+	assert.False(t, errors.Is(err, gokv.ErrConflict))
+	assert.Equal(t, native, errors.Unwrap(err))
+
+	var pathErr *fs.PathError
+	assert.True(t, errors.As(err, &pathErr))
+	assert.Equal(t, "key not found: open k1: file does not exist", err.Error())
+
+	assert.Equal(t, gokv.ErrConflict, gokv.MapError(gokv.ErrConflict, nil))
+}
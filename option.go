@@ -0,0 +1,47 @@
+package gokv
+
+import "time"
+
+// Option defines the options of a key-value pair.
+type Option struct {
+	// TTL is the time-to-live of the cached value, after which the value is reloaded from the backend.
+	TTL time.Duration
+	// NoCache tells the store to persist the value without keeping it in the in-memory cache,
+	// so that subsequent reads always hit the backend.
+	NoCache bool
+	// Tags are the string labels of the key-value pair, e.g. its owner or its category.
+	Tags map[string]string `json:",omitempty"`
+}
+
+// OptionFn is the function to apply an option.
+type OptionFn func(*Option)
+
+// NewOption creates an Option by applying the option functions.
+func NewOption(fns ...OptionFn) Option {
+	var o Option
+	for _, fn := range fns {
+		fn(&o)
+	}
+
+	return o
+}
+
+// NoCache keeps the value out of the in-memory cache.
+func NoCache() OptionFn { return func(o *Option) { o.NoCache = true } }
+
+// TTL sets the time-to-live of the cached value.
+func TTL(ttl time.Duration) OptionFn { return func(o *Option) { o.TTL = ttl } }
+
+// Tag sets the tag of the key-value pair.
+func Tag(name, value string) OptionFn {
+	return func(o *Option) {
+		if o.Tags == nil {
+			o.Tags = make(map[string]string)
+		}
+		o.Tags[name] = value
+	}
+}
+
+// GeneratorFn generates the value for a key which is missing from the store.
+// The generated value will be stored with the option functions returned.
+type GeneratorFn func(k string) (v string, fns []OptionFn, err error)
@@ -0,0 +1,44 @@
+package gokv
+
+import "sort"
+
+// KV is a key value pair.
+type KV struct {
+	Key   string
+	Value string
+}
+
+// OrderedStore is implemented by the stores which list the key values in the order of the keys.
+// The ordering is not free, the stores sort all the key values, in memory or by the database,
+// so prefer All for the large stores when the order does not matter.
+type OrderedStore interface {
+	// AllOrdered lists the key values in the lexicographic order of the keys.
+	AllOrdered() ([]KV, error)
+}
+
+// AllOrdered lists the key values of s in the lexicographic order of the keys,
+// by sorting All of s when s is not an OrderedStore.
+func AllOrdered(s Store) ([]KV, error) {
+	if o, ok := s.(OrderedStore); ok {
+		return o.AllOrdered()
+	}
+
+	m, err := s.All()
+	if err != nil {
+		return nil, err
+	}
+
+	return SortedKVs(m), nil
+}
+
+// SortedKVs returns the key values of m in the lexicographic order of the keys.
+func SortedKVs(m map[string]string) []KV {
+	kvs := make([]KV, 0, len(m))
+	for k, v := range m {
+		kvs = append(kvs, KV{Key: k, Value: v})
+	}
+
+	sort.Slice(kvs, func(i, j int) bool { return kvs[i].Key < kvs[j].Key })
+
+	return kvs
+}
@@ -0,0 +1,29 @@
+package gokv_test
+
+import (
+	"github.com/bingoohuang/gokv"
+	"github.com/bingoohuang/gokv/pkg/memory"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+// unorderedStore hides the OrderedStore of the memory store.
+type unorderedStore struct{ gokv.Store }
+
+func TestAllOrdered(t *testing.T) {
+	s := memory.New()
+	for _, k := range []string{"b", "a10", "a2", "A", "a"} {
+		assert.Nil(t, s.Set(k, "v"+k))
+	}
+
+	expected := []gokv.KV{{"A", "vA"}, {"a", "va"}, {"a10", "va10"}, {"a2", "va2"}, {"b", "vb"}}
+
+	var _ gokv.OrderedStore = s
+	kvs, err := s.AllOrdered()
+	assert.Nil(t, err)
+	assert.Equal(t, expected, kvs)
+
+	kvs, err = gokv.AllOrdered(unorderedStore{Store: s})
+	assert.Nil(t, err)
+	assert.Equal(t, expected, kvs, "All is sorted for the unordered stores")
+}
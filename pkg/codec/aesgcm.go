@@ -0,0 +1,66 @@
+package codec
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"io"
+)
+
+// ErrCiphertext is the error of a ciphertext which is too short or fails the authentication,
+// e.g. it is tampered or encrypted by another key.
+var ErrCiphertext = errors.New("invalid ciphertext or wrong key")
+
+// AESGCM is a Codec wrapper which encrypts the output of the inner codec by AES-GCM
+// with a random nonce per value, which is prepended to the ciphertext.
+type AESGCM struct {
+	Inner Codec
+	aead  cipher.AEAD
+}
+
+// NewAESGCM creates an AESGCM codec wrapping inner, the key should be 16, 24 or 32 bytes
+// to select AES-128, AES-192 or AES-256.
+func NewAESGCM(inner Codec, key []byte) (*AESGCM, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	return &AESGCM{Inner: inner, aead: aead}, nil
+}
+
+// Marshal encodes a Go value with the inner codec and then encrypts it.
+func (c *AESGCM) Marshal(v interface{}) ([]byte, error) {
+	data, err := c.Inner.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, c.aead.NonceSize(), c.aead.NonceSize()+len(data)+c.aead.Overhead())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+
+	return c.aead.Seal(nonce, nonce, data, nil), nil
+}
+
+// Unmarshal decrypts the data and then decodes it with the inner codec.
+func (c *AESGCM) Unmarshal(data []byte, v interface{}) error {
+	if len(data) < c.aead.NonceSize() {
+		return ErrCiphertext
+	}
+
+	nonce, ciphertext := data[:c.aead.NonceSize()], data[c.aead.NonceSize():]
+	plain, err := c.aead.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return ErrCiphertext
+	}
+
+	return c.Inner.Unmarshal(plain, v)
+}
@@ -0,0 +1,36 @@
+package codec
+
+import "encoding/base64"
+
+// Base64 is a Codec wrapper which encodes the output of the inner codec with standard base64,
+// so that binary codecs like gob can be stored in text columns.
+type Base64 struct {
+	Inner Codec
+}
+
+// NewBase64 creates a Base64 codec wrapping inner.
+func NewBase64(inner Codec) *Base64 { return &Base64{Inner: inner} }
+
+// Marshal encodes a Go value with the inner codec and then base64.
+func (c *Base64) Marshal(v interface{}) ([]byte, error) {
+	data, err := c.Inner.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]byte, base64.StdEncoding.EncodedLen(len(data)))
+	base64.StdEncoding.Encode(out, data)
+
+	return out, nil
+}
+
+// Unmarshal decodes the base64 data and then decodes it with the inner codec.
+func (c *Base64) Unmarshal(data []byte, v interface{}) error {
+	raw := make([]byte, base64.StdEncoding.DecodedLen(len(data)))
+	n, err := base64.StdEncoding.Decode(raw, data)
+	if err != nil {
+		return err
+	}
+
+	return c.Inner.Unmarshal(raw[:n], v)
+}
@@ -0,0 +1,45 @@
+package codec
+
+import (
+	"errors"
+	"go.uber.org/multierr"
+)
+
+// ErrEmptyChain is the error of marshaling or unmarshaling by a ChainCodec without any codec.
+var ErrEmptyChain = errors.New("empty codec chain")
+
+// ChainCodec marshals by the first codec, and unmarshals by the codecs in order until one succeeds,
+// e.g. for migrating the stored values from one encoding to another.
+// The codecs should fail without partially decoding into the value.
+type ChainCodec []Codec
+
+// Chain creates a ChainCodec of the codecs, the first one is used to marshal.
+func Chain(codecs ...Codec) ChainCodec { return codecs }
+
+// Marshal encodes a Go value by the first codec, or returns ErrEmptyChain without any codec.
+func (c ChainCodec) Marshal(v interface{}) ([]byte, error) {
+	if len(c) == 0 {
+		return nil, WrapError("marshal", v, ErrEmptyChain)
+	}
+
+	return c[0].Marshal(v)
+}
+
+// Unmarshal decodes the data by the first codec which succeeds, or returns the errors of all the codecs,
+// which is ErrEmptyChain without any codec.
+func (c ChainCodec) Unmarshal(data []byte, v interface{}) (err error) {
+	if len(c) == 0 {
+		return WrapError("unmarshal", v, ErrEmptyChain)
+	}
+
+	for _, codec := range c {
+		e := codec.Unmarshal(data, v)
+		if e == nil {
+			return nil
+		}
+
+		err = multierr.Append(err, e)
+	}
+
+	return err
+}
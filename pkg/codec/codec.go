@@ -0,0 +1,50 @@
+// Package codec contains the codecs to marshal and unmarshal the values stored in gokv.
+package codec
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+)
+
+// Codec encodes/decodes Go values to/from slices of bytes.
+type Codec interface {
+	// Marshal encodes a Go value to a slice of bytes.
+	Marshal(v interface{}) ([]byte, error)
+	// Unmarshal decodes a slice of bytes into a Go value.
+	Unmarshal(data []byte, v interface{}) error
+}
+
+var (
+	// JSON is a Codec that encodes/decodes Go values to/from JSON.
+	JSON = JSONCodec{}
+	// Gob is a Codec that encodes/decodes Go values to/from gob.
+	Gob = GobCodec{}
+)
+
+// JSONCodec encodes/decodes Go values to/from JSON.
+type JSONCodec struct{}
+
+// Marshal encodes a Go value to JSON.
+func (c JSONCodec) Marshal(v interface{}) ([]byte, error) { return json.Marshal(v) }
+
+// Unmarshal decodes a JSON value into a Go value.
+func (c JSONCodec) Unmarshal(data []byte, v interface{}) error { return json.Unmarshal(data, v) }
+
+// GobCodec encodes/decodes Go values to/from gob.
+type GobCodec struct{}
+
+// Marshal encodes a Go value to gob.
+func (c GobCodec) Marshal(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(v); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// Unmarshal decodes a gob value into a Go value.
+func (c GobCodec) Unmarshal(data []byte, v interface{}) error {
+	return gob.NewDecoder(bytes.NewReader(data)).Decode(v)
+}
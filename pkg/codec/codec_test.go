@@ -0,0 +1,125 @@
+package codec_test
+
+import (
+	"errors"
+	"github.com/bingoohuang/gokv/pkg/codec"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+type user struct {
+	Name string `json:"name"`
+	Age  int    `json:"age"`
+}
+
+func TestCodecs(t *testing.T) {
+	for _, c := range []codec.Codec{codec.JSON, codec.Gob, codec.NewBase64(codec.Gob)} {
+		data, err := c.Marshal(user{Name: "bingoo", Age: 18})
+		assert.Nil(t, err)
+
+		var u user
+		assert.Nil(t, c.Unmarshal(data, &u))
+		assert.Equal(t, user{Name: "bingoo", Age: 18}, u)
+	}
+}
+
+const userSchema = `{
+  "type": "object",
+  "properties": {
+    "name": {"type": "string", "minLength": 1},
+    "age":  {"type": "integer", "minimum": 0}
+  },
+  "required": ["name", "age"]
+}`
+
+func TestValidating(t *testing.T) {
+	c, err := codec.NewValidating(codec.JSON, userSchema)
+	assert.Nil(t, err)
+
+	data, err := c.Marshal(user{Name: "bingoo", Age: 18})
+	assert.Nil(t, err)
+	assert.JSONEq(t, `{"name":"bingoo","age":18}`, string(data))
+
+	_, err = c.Marshal(user{Name: "", Age: -1})
+	assert.True(t, errors.Is(err, codec.ErrSchemaValidation))
+	assert.Contains(t, err.Error(), "name")
+	assert.Contains(t, err.Error(), "age")
+
+	var u user
+	assert.Nil(t, c.Unmarshal([]byte(`{"name":""}`), &u))
+
+	c.ValidateUnmarshal = true
+	err = c.Unmarshal([]byte(`{"name":""}`), &u)
+	assert.True(t, errors.Is(err, codec.ErrSchemaValidation))
+	assert.Contains(t, err.Error(), "age")
+
+	_, err = codec.NewValidating(codec.JSON, `{"type": 1}`)
+	assert.NotNil(t, err)
+}
+
+func TestWrapError(t *testing.T) {
+	assert.Nil(t, codec.WrapError("marshal", user{}, nil))
+
+	_, err := codec.JSON.Marshal(func() {})
+	err = codec.WrapError("marshal", func() {}, err)
+	assert.Contains(t, err.Error(), "codec marshal func()")
+
+	var u user
+	cause := codec.JSON.Unmarshal([]byte(`{"age":"old"}`), &u)
+	err = codec.WrapError("unmarshal", &u, cause)
+	assert.Contains(t, err.Error(), "codec unmarshal *codec_test.user")
+	assert.True(t, errors.Is(err, cause))
+}
+
+func TestChain(t *testing.T) {
+	c := codec.Chain(codec.JSON, codec.Gob)
+
+	data, err := c.Marshal(user{Name: "bingoo", Age: 18})
+	assert.Nil(t, err)
+	assert.Equal(t, `{"name":"bingoo","age":18}`, string(data))
+
+	gobData, err := codec.Gob.Marshal(user{Name: "huang", Age: 20})
+	assert.Nil(t, err)
+
+	for data, expected := range map[string]user{
+		string(data):    {Name: "bingoo", Age: 18},
+		string(gobData): {Name: "huang", Age: 20},
+	} {
+		var u user
+		assert.Nil(t, c.Unmarshal([]byte(data), &u))
+		assert.Equal(t, expected, u)
+	}
+
+	var u user
+	assert.NotNil(t, c.Unmarshal([]byte("neither"), &u))
+
+	_, err = codec.Chain().Marshal(user{})
+	assert.True(t, errors.Is(err, codec.ErrEmptyChain))
+	assert.True(t, errors.Is(codec.Chain().Unmarshal(data, &u), codec.ErrEmptyChain))
+}
+
+func TestAESGCM(t *testing.T) {
+	key := []byte("0123456789abcdef0123456789abcdef")
+	c, err := codec.NewAESGCM(codec.JSON, key)
+	assert.Nil(t, err)
+
+	data1, err := c.Marshal(user{Name: "bingoo", Age: 18})
+	assert.Nil(t, err)
+	assert.NotContains(t, string(data1), "bingoo")
+
+	data2, err := c.Marshal(user{Name: "bingoo", Age: 18})
+	assert.Nil(t, err)
+	assert.NotEqual(t, data1, data2, "the nonces should differ")
+
+	var u user
+	assert.Nil(t, c.Unmarshal(data1, &u))
+	assert.Equal(t, user{Name: "bingoo", Age: 18}, u)
+
+	other, err := codec.NewAESGCM(codec.JSON, []byte("fedcba9876543210fedcba9876543210"))
+	assert.Nil(t, err)
+	assert.Equal(t, codec.ErrCiphertext, other.Unmarshal(data1, &u))
+	assert.Equal(t, codec.ErrCiphertext, c.Unmarshal([]byte("short"), &u))
+
+	_, err = codec.NewAESGCM(codec.JSON, []byte("bad key size"))
+	assert.NotNil(t, err)
+}
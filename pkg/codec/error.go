@@ -0,0 +1,26 @@
+package codec
+
+import "fmt"
+
+// Error annotates the error of a codec with the operation and the Go type involved.
+type Error struct {
+	// Op is the operation, "marshal" or "unmarshal".
+	Op string
+	// Type is the name of the Go type marshaled or unmarshaled into.
+	Type string
+	Err  error
+}
+
+func (e *Error) Error() string { return fmt.Sprintf("codec %s %s: %v", e.Op, e.Type, e.Err) }
+
+// Unwrap returns the error of the codec.
+func (e *Error) Unwrap() error { return e.Err }
+
+// WrapError wraps err of the op on the value v as an *Error, it returns nil when err is nil.
+func WrapError(op string, v interface{}, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	return &Error{Op: op, Type: fmt.Sprintf("%T", v), Err: err}
+}
@@ -0,0 +1,74 @@
+package codec
+
+import (
+	"errors"
+	"fmt"
+	"github.com/xeipuuv/gojsonschema"
+	"strings"
+)
+
+// ErrSchemaValidation is the error when a payload does not conform to the JSON Schema.
+var ErrSchemaValidation = errors.New("schema validation failed")
+
+// Validating is a Codec wrapper which validates the JSON payloads produced by the inner codec
+// against a JSON Schema, so that non-conforming values are rejected before they are stored.
+type Validating struct {
+	Inner Codec
+	// ValidateUnmarshal validates the payload before unmarshalling too.
+	ValidateUnmarshal bool
+
+	schema *gojsonschema.Schema
+}
+
+// NewValidating creates a Validating codec wrapping inner with the JSON Schema schemaJSON.
+func NewValidating(inner Codec, schemaJSON string) (*Validating, error) {
+	schema, err := gojsonschema.NewSchema(gojsonschema.NewStringLoader(schemaJSON))
+	if err != nil {
+		return nil, fmt.Errorf("compile schema: %w", err)
+	}
+
+	return &Validating{Inner: inner, schema: schema}, nil
+}
+
+// Marshal encodes a Go value with the inner codec and validates the result.
+func (c *Validating) Marshal(v interface{}) ([]byte, error) {
+	data, err := c.Inner.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := c.validate(data); err != nil {
+		return nil, err
+	}
+
+	return data, nil
+}
+
+// Unmarshal decodes the data with the inner codec, validating it first if ValidateUnmarshal is set.
+func (c *Validating) Unmarshal(data []byte, v interface{}) error {
+	if c.ValidateUnmarshal {
+		if err := c.validate(data); err != nil {
+			return err
+		}
+	}
+
+	return c.Inner.Unmarshal(data, v)
+}
+
+func (c *Validating) validate(data []byte) error {
+	result, err := c.schema.Validate(gojsonschema.NewBytesLoader(data))
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrSchemaValidation, err)
+	}
+
+	if result.Valid() {
+		return nil
+	}
+
+	fields := make([]string, 0, len(result.Errors()))
+	for _, e := range result.Errors() {
+		fields = append(fields, fmt.Sprintf("%s: %s", e.Field(), e.Description()))
+	}
+
+	return fmt.Errorf("%w: %s", ErrSchemaValidation, strings.Join(fields, "; "))
+}
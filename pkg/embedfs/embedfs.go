@@ -0,0 +1,83 @@
+// Package embedfs provides a read-only gokv.Store over an fs.FS, e.g. an embed.FS,
+// whose keys are the slash-separated paths of the files.
+package embedfs
+
+import (
+	"errors"
+	"github.com/bingoohuang/gokv"
+	"io/fs"
+)
+
+// Store is a read-only gokv.Store over the files of an fs.FS.
+type Store struct {
+	fsys fs.FS
+}
+
+// New creates a Store over fsys, use fs.Sub to root the keys at a sub directory.
+func New(fsys fs.FS) *Store { return &Store{fsys: fsys} }
+
+// All reads all the files in the store.
+func (s *Store) All() (map[string]string, error) {
+	m := make(map[string]string)
+	err := s.walk(func(path string) error {
+		data, err := fs.ReadFile(s.fsys, path)
+		if err != nil {
+			return err
+		}
+
+		m[path] = string(data)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+// Keys list the paths of the files in the store.
+func (s *Store) Keys() (keys []string, err error) {
+	err = s.walk(func(path string) error {
+		keys = append(keys, path)
+		return nil
+	})
+
+	return keys, err
+}
+
+func (s *Store) walk(fn func(path string) error) error {
+	return fs.WalkDir(s.fsys, ".", func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+
+		return fn(path)
+	})
+}
+
+// Get reads the file of the key, gokv.ErrKeyNotFound is returned when the file does not exist
+// or the key is a directory.
+func (s *Store) Get(k string) (string, error) {
+	if info, err := fs.Stat(s.fsys, k); err == nil && info.IsDir() {
+		return "", gokv.ErrKeyNotFound
+	}
+
+	data, err := fs.ReadFile(s.fsys, k)
+	if errors.Is(err, fs.ErrNotExist) || errors.Is(err, fs.ErrInvalid) {
+		return "", gokv.MapError(gokv.ErrKeyNotFound, err)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	return string(data), nil
+}
+
+// Set returns gokv.ErrReadOnly.
+func (s *Store) Set(string, string) error { return gokv.ErrReadOnly }
+
+// Del returns gokv.ErrReadOnly.
+func (s *Store) Del(string) error { return gokv.ErrReadOnly }
+
+// Close does nothing for the Store.
+func (s *Store) Close() error { return nil }
@@ -0,0 +1,52 @@
+package embedfs_test
+
+import (
+	"embed"
+	"errors"
+	"github.com/bingoohuang/gokv"
+	"github.com/bingoohuang/gokv/pkg/embedfs"
+	"github.com/stretchr/testify/assert"
+	"io/fs"
+	"testing"
+)
+
+//go:embed testdata
+var testdata embed.FS
+
+func TestStore(t *testing.T) {
+	sub, err := fs.Sub(testdata, "testdata")
+	assert.Nil(t, err)
+
+	var s gokv.Store = embedfs.New(sub)
+
+	for k, expected := range map[string]string{
+		"mode":             "debug",
+		"conf/server.json": `{"port":8080}`,
+		"conf/log.yaml":    "level: info",
+	} {
+		v, err := s.Get(k)
+		assert.Nil(t, err)
+		assert.Equal(t, expected, v)
+	}
+
+	for _, k := range []string{"missing", "conf", "../mode", ""} {
+		_, err := s.Get(k)
+		assert.True(t, errors.Is(err, gokv.ErrKeyNotFound), k)
+	}
+
+	_, err = s.Get("missing")
+	var pathErr *fs.PathError
+	assert.True(t, errors.As(err, &pathErr), "the native error is preserved")
+
+	keys, err := embedfs.New(sub).Keys()
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"conf/log.yaml", "conf/server.json", "mode"}, keys)
+
+	kvs, err := s.All()
+	assert.Nil(t, err)
+	assert.Len(t, kvs, 3)
+	assert.Equal(t, "debug", kvs["mode"])
+
+	assert.True(t, errors.Is(s.Set("mode", "release"), gokv.ErrReadOnly))
+	assert.True(t, errors.Is(s.Del("mode"), gokv.ErrReadOnly))
+}
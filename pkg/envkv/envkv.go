@@ -0,0 +1,86 @@
+// Package envkv provides a read-only gokv.Store over the environment variables of the process.
+package envkv
+
+import (
+	"github.com/bingoohuang/gokv"
+	"os"
+	"strings"
+)
+
+// Options are the options of the Store.
+type Options struct {
+	// Prefix is the prefix of the names of the environment variables, e.g. APP_,
+	// which is prepended to the keys, and stripped from the names for the keys of All and Keys.
+	Prefix string
+	// UpperCase upper-cases the keys to the names of the environment variables, e.g. db_url to DB_URL,
+	// and lower-cases the names for the keys of All and Keys.
+	UpperCase bool
+}
+
+// Store is a read-only gokv.Store over the environment variables.
+type Store struct {
+	Options
+}
+
+// New creates a Store over the environment variables with the options.
+func New(options Options) *Store { return &Store{Options: options} }
+
+// name returns the name of the environment variable of the key.
+func (s *Store) name(k string) string {
+	if s.UpperCase {
+		k = strings.ToUpper(k)
+	}
+
+	return s.Prefix + k
+}
+
+// All returns the environment variables with the Prefix, keyed by their names without the Prefix.
+func (s *Store) All() (map[string]string, error) {
+	m := make(map[string]string)
+	s.each(func(k, v string) { m[k] = v })
+
+	return m, nil
+}
+
+// Keys list the names of the environment variables with the Prefix, without the Prefix.
+func (s *Store) Keys() (keys []string, err error) {
+	s.each(func(k, _ string) { keys = append(keys, k) })
+
+	return keys, nil
+}
+
+func (s *Store) each(fn func(k, v string)) {
+	for _, env := range os.Environ() {
+		name, v, ok := strings.Cut(env, "=")
+		if !ok || !strings.HasPrefix(name, s.Prefix) || name == s.Prefix {
+			continue
+		}
+
+		k := strings.TrimPrefix(name, s.Prefix)
+		if s.UpperCase {
+			k = strings.ToLower(k)
+		}
+
+		fn(k, v)
+	}
+}
+
+// Get returns the value of the environment variable of the key,
+// gokv.ErrKeyNotFound is returned when it is not set.
+func (s *Store) Get(k string) (string, error) {
+	v, ok := os.LookupEnv(s.name(k))
+	if !ok {
+		return "", gokv.ErrKeyNotFound
+	}
+
+	return v, nil
+}
+
+// Set returns gokv.ErrReadOnly.
+func (s *Store) Set(string, string) error { return gokv.ErrReadOnly }
+
+// Del returns gokv.ErrReadOnly.
+func (s *Store) Del(string) error { return gokv.ErrReadOnly }
+
+// Close does nothing for the Store.
+func (s *Store) Close() error { return nil }
@@ -0,0 +1,45 @@
+package envkv_test
+
+import (
+	"errors"
+	"github.com/bingoohuang/gokv"
+	"github.com/bingoohuang/gokv/pkg/envkv"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestStore(t *testing.T) {
+	t.Setenv("GOKV_TEST_DB_URL", "mysql://localhost")
+	t.Setenv("GOKV_TEST_MODE", "")
+	t.Setenv("OTHER_MODE", "release")
+
+	var s gokv.Store = envkv.New(envkv.Options{})
+
+	v, err := s.Get("GOKV_TEST_DB_URL")
+	assert.Nil(t, err)
+	assert.Equal(t, "mysql://localhost", v)
+
+	v, err = s.Get("GOKV_TEST_MODE")
+	assert.Nil(t, err, "an empty variable is set")
+	assert.Equal(t, "", v)
+
+	_, err = s.Get("GOKV_TEST_MISSING")
+	assert.True(t, errors.Is(err, gokv.ErrKeyNotFound))
+
+	prefixed := envkv.New(envkv.Options{Prefix: "GOKV_TEST_", UpperCase: true})
+
+	v, err = prefixed.Get("db_url")
+	assert.Nil(t, err)
+	assert.Equal(t, "mysql://localhost", v)
+
+	kvs, err := prefixed.All()
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"db_url": "mysql://localhost", "mode": ""}, kvs)
+
+	keys, err := prefixed.Keys()
+	assert.Nil(t, err)
+	assert.ElementsMatch(t, []string{"db_url", "mode"}, keys)
+
+	assert.True(t, errors.Is(s.Set("GOKV_TEST_MODE", "debug"), gokv.ErrReadOnly))
+	assert.True(t, errors.Is(s.Del("GOKV_TEST_MODE"), gokv.ErrReadOnly))
+}
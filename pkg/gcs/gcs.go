@@ -0,0 +1,243 @@
+// Package gcs provides a gokv.Store over the objects of a Google Cloud Storage bucket
+// by the official client cloud.google.com/go/storage.
+package gcs
+
+import (
+	"cloud.google.com/go/storage"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/bingoohuang/gokv"
+	"github.com/bingoohuang/gokv/pkg/codec"
+	"go.uber.org/multierr"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/iterator"
+	"google.golang.org/api/option"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// ErrObjectNotExist is the error of the objects missing from the bucket,
+// which is reported as gokv.ErrKeyNotFound by Get.
+var ErrObjectNotExist = storage.ErrObjectNotExist
+
+// DefaultScanBatchSize is the default max number of the objects listed per page, the max of the JSON API.
+const DefaultScanBatchSize = 1000
+
+// Options are the options of the Store.
+type Options struct {
+	// Bucket is the name of the bucket, required.
+	Bucket string
+	// Prefix is prepended to the keys for the object names, e.g. "kv/".
+	Prefix string
+	// Codec encodes the values into the objects, default to codec.JSON.
+	Codec codec.Codec
+	// ClientOptions are the options of the storage client, which is authorized by the application default
+	// credentials by default, e.g. option.WithEndpoint("http://localhost:4443/storage/v1/") and
+	// option.WithoutAuthentication() for a fake-gcs-server.
+	ClientOptions []option.ClientOption
+	// ScanBatchSize is the max number of the objects listed per page by All, default to DefaultScanBatchSize,
+	// the smaller pages take more requests but less memory per response.
+	ScanBatchSize int
+}
+
+// Store is a gokv.Store over the objects under the prefix of a bucket.
+type Store struct {
+	Options
+
+	client *storage.Client
+	bucket *storage.BucketHandle
+}
+
+var (
+	_ gokv.Store        = (*Store)(nil)
+	_ gokv.ContextStore = (*Store)(nil)
+	_ gokv.StreamStore  = (*Store)(nil)
+	_ gokv.Closer       = (*Store)(nil)
+)
+
+// New creates a Store of the bucket in the options, Close must be called to release the storage client.
+func New(options Options) (*Store, error) {
+	if options.Bucket == "" {
+		return nil, errors.New("gcs: bucket is required")
+	}
+	if options.Codec == nil {
+		options.Codec = codec.JSON
+	}
+	if options.ScanBatchSize <= 0 {
+		options.ScanBatchSize = DefaultScanBatchSize
+	}
+
+	client, err := storage.NewClient(context.Background(), options.ClientOptions...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Store{Options: options, client: client, bucket: client.Bucket(options.Bucket)}, nil
+}
+
+// All returns the key values of the objects under the prefix.
+func (s *Store) All() (map[string]string, error) { return s.AllContext(context.Background()) }
+
+// Set stores the value of the key as an object.
+func (s *Store) Set(k, v string) error { return s.SetContext(context.Background(), k, v) }
+
+// Get retrieves the value of the key from its object,
+// gokv.ErrKeyNotFound is returned when the object does not exist.
+func (s *Store) Get(k string) (string, error) { return s.GetContext(context.Background(), k) }
+
+// Del deletes the object of the key, deleting a missing object does NOT lead to an error.
+func (s *Store) Del(k string) error { return s.DelContext(context.Background(), k) }
+
+// Close closes the storage client.
+func (s *Store) Close() error { return s.client.Close() }
+
+// AllContext returns the key values of the objects under the prefix.
+func (s *Store) AllContext(ctx context.Context) (map[string]string, error) {
+	names, err := s.list(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	m := make(map[string]string, len(names))
+	for _, name := range names {
+		k := strings.TrimPrefix(name, s.Prefix)
+		v, err := s.GetContext(ctx, k)
+		if errors.Is(err, gokv.ErrKeyNotFound) {
+			continue // deleted after listed
+		} else if err != nil {
+			return nil, err
+		}
+
+		m[k] = v
+	}
+
+	return m, nil
+}
+
+// SetContext stores the value of the key as an object.
+func (s *Store) SetContext(ctx context.Context, k, v string) error {
+	data, err := s.Codec.Marshal(v)
+	if err != nil {
+		return codec.WrapError("marshal", v, err)
+	}
+
+	return s.write(ctx, k, func(w io.Writer) error {
+		_, err := w.Write(data)
+		return err
+	})
+}
+
+// GetContext retrieves the value of the key from its object,
+// gokv.ErrKeyNotFound is returned when the object does not exist.
+func (s *Store) GetContext(ctx context.Context, k string) (v string, er error) {
+	r, err := s.bucket.Object(s.Prefix + k).NewReader(ctx)
+	if err != nil {
+		return "", s.mapError(k, err)
+	}
+
+	defer func() { er = multierr.Append(er, r.Close()) }()
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return "", err
+	}
+
+	if err := s.Codec.Unmarshal(data, &v); err != nil {
+		return "", codec.WrapError("unmarshal", &v, err)
+	}
+
+	return v, nil
+}
+
+// DelContext deletes the object of the key, deleting a missing object does NOT lead to an error.
+func (s *Store) DelContext(ctx context.Context, k string) error {
+	if err := s.bucket.Object(s.Prefix + k).Delete(ctx); err != nil && !errors.Is(err, ErrObjectNotExist) {
+		return s.mapError(k, err)
+	}
+
+	return nil
+}
+
+// GetStream opens the object of the key for reading as is, without decoding by the Codec,
+// found is false when the object does not exist. The caller must close the reader.
+func (s *Store) GetStream(ctx context.Context, k string) (r io.ReadCloser, found bool, err error) {
+	r, err = s.bucket.Object(s.Prefix + k).NewReader(ctx)
+	if errors.Is(err, ErrObjectNotExist) {
+		return nil, false, nil
+	} else if err != nil {
+		return nil, false, s.mapError(k, err)
+	}
+
+	return r, true, nil
+}
+
+// SetStream uploads the object of the key read from r until EOF as is, without encoding by the Codec,
+// so the streamed values should be read back by GetStream rather than Get.
+func (s *Store) SetStream(ctx context.Context, k string, r io.Reader) error {
+	return s.write(ctx, k, func(w io.Writer) error {
+		_, err := io.Copy(w, r)
+		return err
+	})
+}
+
+// write uploads the object of the key written by f, the object is not created when f fails.
+func (s *Store) write(ctx context.Context, k string, f func(w io.Writer) error) error {
+	// the upload is aborted by canceling its ctx
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	w := s.bucket.Object(s.Prefix + k).NewWriter(ctx)
+	if err := f(w); err != nil {
+		cancel()
+		return multierr.Append(err, w.Close())
+	}
+
+	return s.mapError(k, w.Close())
+}
+
+// list lists the names of the objects under the prefix by the pages of ScanBatchSize.
+func (s *Store) list(ctx context.Context) (names []string, err error) {
+	query := &storage.Query{Prefix: s.Prefix}
+	if err := query.SetAttrSelection([]string{"Name"}); err != nil {
+		return nil, err
+	}
+
+	it := s.bucket.Objects(ctx, query)
+	it.PageInfo().MaxSize = s.ScanBatchSize
+
+	for {
+		attrs, err := it.Next()
+		if err == iterator.Done {
+			return names, nil
+		} else if errors.Is(err, storage.ErrBucketNotExist) {
+			return nil, fmt.Errorf("gcs: bucket %s doesn't exist, error:%w", s.Bucket, err)
+		} else if err != nil {
+			return nil, err
+		}
+
+		names = append(names, attrs.Name)
+	}
+}
+
+// mapError annotates the error of the key, ErrObjectNotExist is mapped to gokv.ErrKeyNotFound,
+// and the 409 and 412 of the preconditions are mapped to gokv.ErrConflict.
+func (s *Store) mapError(k string, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	err = fmt.Errorf("key:%s, error:%w", k, err)
+	if errors.Is(err, ErrObjectNotExist) {
+		return gokv.MapError(gokv.ErrKeyNotFound, err)
+	}
+
+	var apiErr *googleapi.Error
+	if errors.As(err, &apiErr) &&
+		(apiErr.Code == http.StatusConflict || apiErr.Code == http.StatusPreconditionFailed) {
+		return gokv.MapError(gokv.ErrConflict, err)
+	}
+
+	return err
+}
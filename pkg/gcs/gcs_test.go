@@ -0,0 +1,196 @@
+package gcs_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"github.com/bingoohuang/gokv"
+	"github.com/bingoohuang/gokv/pkg/gcs"
+	"github.com/bingoohuang/gokv/pkg/storetest"
+	"github.com/fsouza/fake-gcs-server/fakestorage"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/api/option"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// requestLog records the request log of the fake-gcs-server.
+type requestLog struct {
+	sync.Mutex
+	bytes.Buffer
+}
+
+func (l *requestLog) Write(p []byte) (int, error) {
+	l.Lock()
+	defer l.Unlock()
+
+	return l.Buffer.Write(p)
+}
+
+// maxResults returns the maxResults of the list requests logged.
+func (l *requestLog) maxResults() (maxResults []string) {
+	l.Lock()
+	defer l.Unlock()
+
+	for _, m := range regexp.MustCompile(`[?&]maxResults=(\d+)`).FindAllStringSubmatch(l.String(), -1) {
+		maxResults = append(maxResults, m[1])
+	}
+
+	return maxResults
+}
+
+// startFakeGCS starts a fake-gcs-server with the objects of the bucket, and creates the options to connect to it.
+func startFakeGCS(t *testing.T, bucket string, objects map[string]string) (*fakestorage.Server, *requestLog,
+	gcs.Options) {
+	log := &requestLog{}
+	// the objects are read by the XML API from the public host
+	server, err := fakestorage.NewServerWithOptions(fakestorage.Options{Scheme: "http", PublicHost: "127.0.0.1",
+		Writer: log})
+	assert.Nil(t, err)
+	t.Cleanup(server.Stop)
+
+	server.CreateBucketWithOpts(fakestorage.CreateBucketOpts{Name: bucket})
+	for name, content := range objects {
+		server.CreateObject(fakestorage.Object{
+			ObjectAttrs: fakestorage.ObjectAttrs{BucketName: bucket, Name: name},
+			Content:     []byte(content),
+		})
+	}
+
+	return server, log, gcs.Options{
+		Bucket: bucket,
+		ClientOptions: []option.ClientOption{
+			option.WithEndpoint(server.URL() + "/storage/v1/"),
+			option.WithoutAuthentication(),
+		},
+	}
+}
+
+// content returns the content of the object of the fake-gcs-server.
+func content(t *testing.T, server *fakestorage.Server, bucket, name string) []byte {
+	object, err := server.GetObject(bucket, name)
+	assert.Nil(t, err)
+
+	return object.Content
+}
+
+func TestStore(t *testing.T) {
+	server, _, options := startFakeGCS(t, "bucket", map[string]string{"other/k": `"v"`})
+
+	options.Prefix = "kv/"
+	s, err := gcs.New(options)
+	assert.Nil(t, err)
+	defer s.Close()
+
+	storetest.TestStore(t, s)
+
+	assert.Nil(t, s.Set("k1", "v1"))
+	assert.Nil(t, s.Set("a/b c", "v2"))
+	assert.Equal(t, []byte(`"v1"`), content(t, server, "bucket", "kv/k1"), "the values are encoded by the codec")
+
+	v, err := s.Get("a/b c")
+	assert.Nil(t, err)
+	assert.Equal(t, "v2", v)
+
+	all, err := s.All()
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"k1": "v1", "a/b c": "v2"}, all)
+
+	assert.Nil(t, s.Del("k1"))
+	_, err = s.Get("k1")
+	assert.True(t, errors.Is(err, gokv.ErrKeyNotFound))
+	assert.True(t, errors.Is(err, gcs.ErrObjectNotExist), "the native error is preserved")
+
+	missing, err := gcs.New(gcs.Options{Bucket: "missing", ClientOptions: options.ClientOptions})
+	assert.Nil(t, err)
+	defer missing.Close()
+
+	_, err = missing.All()
+	assert.NotNil(t, err)
+}
+
+func TestScanBatchSize(t *testing.T) {
+	_, log, options := startFakeGCS(t, "bucket", map[string]string{"k1": `"v1"`, "k2": `"v2"`})
+
+	s, err := gcs.New(options)
+	assert.Nil(t, err)
+	defer s.Close()
+
+	_, err = s.All()
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"1000"}, log.maxResults())
+
+	log.Reset()
+	options.ScanBatchSize = 1
+	paged, err := gcs.New(options)
+	assert.Nil(t, err)
+	defer paged.Close()
+
+	all, err := paged.All()
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"k1": "v1", "k2": "v2"}, all)
+	// the fake-gcs-server lists all the objects in a page, while the paging is done by the iterator of the client
+	assert.Equal(t, []string{"1"}, log.maxResults(), "the pages are listed by the configured batch size")
+}
+
+func TestStream(t *testing.T) {
+	server, _, options := startFakeGCS(t, "bucket", nil)
+
+	options.Prefix = "kv/"
+	s, err := gcs.New(options)
+	assert.Nil(t, err)
+	defer s.Close()
+
+	large := strings.Repeat("0123456789", 100000)
+	ctx := context.Background()
+	assert.Nil(t, gokv.SetStream(ctx, s, "big", strings.NewReader(large)))
+	assert.Equal(t, []byte(large), content(t, server, "bucket", "kv/big"), "the streamed value is stored as is")
+
+	r, found, err := gokv.GetStream(ctx, s, "big")
+	assert.Nil(t, err)
+	assert.True(t, found)
+	data, err := io.ReadAll(r)
+	assert.Nil(t, err)
+	assert.Nil(t, r.Close())
+	assert.Equal(t, large, string(data))
+
+	r, found, err = s.GetStream(ctx, "missing")
+	assert.Nil(t, err)
+	assert.False(t, found)
+	assert.Nil(t, r)
+
+	// the object is not created when the stream fails
+	errRead := errors.New("read failed")
+	err = s.SetStream(ctx, "broken", io.MultiReader(strings.NewReader("partial"), &failingReader{err: errRead}))
+	assert.True(t, errors.Is(err, errRead))
+	_, found, err = s.GetStream(ctx, "broken")
+	assert.Nil(t, err)
+	assert.False(t, found)
+}
+
+type failingReader struct{ err error }
+
+func (r *failingReader) Read([]byte) (int, error) { return 0, r.err }
+
+func TestConflict(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "conditionNotMet", http.StatusPreconditionFailed)
+	}))
+	defer server.Close()
+
+	s, err := gcs.New(gcs.Options{Bucket: "bucket", ClientOptions: []option.ClientOption{
+		option.WithEndpoint(server.URL + "/storage/v1/"),
+		option.WithoutAuthentication(),
+	}})
+	assert.Nil(t, err)
+	defer s.Close()
+
+	err = s.Set("k1", "v1")
+	assert.True(t, errors.Is(err, gokv.ErrConflict))
+	assert.Contains(t, err.Error(), "412")
+}
@@ -0,0 +1,215 @@
+// Package jsonfile provides a gokv.Store in memory, which is persisted to a single JSON object file.
+package jsonfile
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/bingoohuang/gokv"
+	"go.uber.org/multierr"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Options is the options of the Store.
+type Options struct {
+	// FlushInterval flushes the changes to the file in every FlushInterval and on Close,
+	// default to flushing on every Set and Del.
+	FlushInterval time.Duration
+}
+
+// Store is a gokv.Store in memory persisted to a JSON file.
+type Store struct {
+	path string
+	Options
+
+	lock  sync.Mutex
+	m     map[string]string
+	dirty bool
+
+	stop      chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// Open loads the store from the JSON object file of path, which is created on the first flush if it does not exist.
+func Open(path string, options Options) (*Store, error) {
+	s := &Store{path: path, Options: options, m: make(map[string]string)}
+
+	data, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &s.m); err != nil {
+			return nil, err
+		}
+	}
+
+	if options.FlushInterval > 0 {
+		s.stop = make(chan struct{})
+		s.done = make(chan struct{})
+
+		go s.tickerFlush()
+	}
+
+	return s, nil
+}
+
+func (s *Store) tickerFlush() {
+	defer close(s.done)
+
+	ticker := time.NewTicker(s.FlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+			s.lock.Lock()
+			err := s.flushDirty()
+			s.lock.Unlock()
+
+			if err != nil {
+				log.Printf("W! flush error %v", err)
+			}
+		}
+	}
+}
+
+// All returns a copy of the key values in the store.
+func (s *Store) All() (map[string]string, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	m := make(map[string]string, len(s.m))
+	for k, v := range s.m {
+		m[k] = v
+	}
+
+	return m, nil
+}
+
+// Keys list the keys in the store.
+func (s *Store) Keys() ([]string, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	keys := make([]string, 0, len(s.m))
+	for k := range s.m {
+		keys = append(keys, k)
+	}
+
+	return keys, nil
+}
+
+// Set stores the given value for the given key.
+func (s *Store) Set(k, v string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.m[k] = v
+
+	return s.changed()
+}
+
+// Get retrieves the value for the given key, gokv.ErrKeyNotFound is returned when the key does not exist.
+func (s *Store) Get(k string) (string, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	v, ok := s.m[k]
+	if !ok {
+		return "", gokv.ErrKeyNotFound
+	}
+
+	return v, nil
+}
+
+// Del deletes the stored value for the given key.
+func (s *Store) Del(k string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if _, ok := s.m[k]; !ok {
+		return nil
+	}
+
+	delete(s.m, k)
+
+	return s.changed()
+}
+
+// Close stops the interval flushing, and flushes the pending changes.
+func (s *Store) Close() error {
+	s.closeOnce.Do(func() {
+		if s.stop != nil {
+			close(s.stop)
+			<-s.done
+		}
+	})
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.flushDirty()
+}
+
+// changed flushes the change immediately, or marks it dirty for the interval flushing.
+func (s *Store) changed() error {
+	s.dirty = true
+	if s.FlushInterval > 0 {
+		return nil
+	}
+
+	return s.flushDirty()
+}
+
+// flushDirty writes the map to the file if it is changed.
+func (s *Store) flushDirty() error {
+	if !s.dirty {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(s.m, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := writeFileAtomic(s.path, data); err != nil {
+		return err
+	}
+
+	s.dirty = false
+
+	return nil
+}
+
+// writeFileAtomic writes the data to a temporary file and renames it to the path,
+// so that the file is either the old or the new one after a crash.
+func writeFileAtomic(path string, data []byte) (err error) {
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if err != nil {
+			_ = os.Remove(f.Name())
+		}
+	}()
+
+	if _, err = f.Write(data); err == nil {
+		err = f.Sync()
+	}
+	if err = multierr.Append(err, f.Close()); err != nil {
+		return err
+	}
+
+	return os.Rename(f.Name(), path)
+}
@@ -0,0 +1,90 @@
+package jsonfile_test
+
+import (
+	"fmt"
+	"github.com/bingoohuang/gokv/pkg/jsonfile"
+	"github.com/bingoohuang/gokv/pkg/storetest"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestStore(t *testing.T) {
+	s, err := jsonfile.Open(filepath.Join(t.TempDir(), "kv.json"), jsonfile.Options{})
+	assert.Nil(t, err)
+	defer s.Close()
+
+	storetest.TestStore(t, s)
+}
+
+func TestPersistence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "kv.json")
+
+	s, err := jsonfile.Open(path, jsonfile.Options{})
+	assert.Nil(t, err)
+	assert.Nil(t, s.Set("k1", "v1"))
+	assert.Nil(t, s.Set("k2", "v2"))
+	assert.Nil(t, s.Del("k2"))
+
+	// flushed on every write without Close
+	reopened, err := jsonfile.Open(path, jsonfile.Options{})
+	assert.Nil(t, err)
+
+	all, err := reopened.All()
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"k1": "v1"}, all)
+
+	// no temporary files are left
+	entries, err := os.ReadDir(filepath.Dir(path))
+	assert.Nil(t, err)
+	assert.Len(t, entries, 1)
+
+	// the interval flushing writes on Close
+	interval, err := jsonfile.Open(path, jsonfile.Options{FlushInterval: time.Hour})
+	assert.Nil(t, err)
+	assert.Nil(t, interval.Set("k3", "v3"))
+
+	reopened, err = jsonfile.Open(path, jsonfile.Options{})
+	assert.Nil(t, err)
+	_, err = reopened.Get("k3")
+	assert.NotNil(t, err)
+
+	assert.Nil(t, interval.Close())
+
+	reopened, err = jsonfile.Open(path, jsonfile.Options{})
+	assert.Nil(t, err)
+	v, err := reopened.Get("k3")
+	assert.Nil(t, err)
+	assert.Equal(t, "v3", v)
+}
+
+func TestConcurrentWriters(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "kv.json")
+
+	s, err := jsonfile.Open(path, jsonfile.Options{FlushInterval: time.Millisecond})
+	assert.Nil(t, err)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			for j := 0; j < 100; j++ {
+				assert.Nil(t, s.Set(fmt.Sprintf("k%d-%d", i, j), "v"))
+			}
+		}(i)
+	}
+	wg.Wait()
+	assert.Nil(t, s.Close())
+
+	reopened, err := jsonfile.Open(path, jsonfile.Options{})
+	assert.Nil(t, err)
+
+	keys, err := reopened.Keys()
+	assert.Nil(t, err)
+	assert.Len(t, keys, 800)
+}
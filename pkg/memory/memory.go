@@ -0,0 +1,97 @@
+// Package memory provides a gokv.Store in memory.
+package memory
+
+import (
+	"github.com/bingoohuang/gokv"
+	"sync"
+)
+
+// Store is a gokv.Store in memory.
+type Store struct {
+	lock sync.RWMutex
+	m    map[string]string
+}
+
+// New creates a new Store.
+func New() *Store { return &Store{m: make(map[string]string)} }
+
+// All returns a copy of the key values in the store.
+func (s *Store) All() (map[string]string, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	m := make(map[string]string, len(s.m))
+	for k, v := range s.m {
+		m[k] = v
+	}
+
+	return m, nil
+}
+
+// AllOrdered returns a copy of the key values in the store sorted by the keys.
+func (s *Store) AllOrdered() ([]gokv.KV, error) {
+	m, _ := s.All()
+	return gokv.SortedKVs(m), nil
+}
+
+// Each calls fn with each of the key values in the store, until fn returns an error, which is returned.
+// fn is called on a snapshot of the store, so it may modify the store.
+func (s *Store) Each(fn func(k, v string) error) error {
+	m, _ := s.All()
+	for k, v := range m {
+		if err := fn(k, v); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Keys list the keys in the store.
+func (s *Store) Keys() ([]string, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	keys := make([]string, 0, len(s.m))
+	for k := range s.m {
+		keys = append(keys, k)
+	}
+
+	return keys, nil
+}
+
+// Set stores the given value for the given key.
+func (s *Store) Set(k, v string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.m[k] = v
+
+	return nil
+}
+
+// Get retrieves the value for the given key, gokv.ErrKeyNotFound is returned when the key does not exist.
+func (s *Store) Get(k string) (string, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	v, ok := s.m[k]
+	if !ok {
+		return "", gokv.ErrKeyNotFound
+	}
+
+	return v, nil
+}
+
+// Del deletes the stored value for the given key.
+func (s *Store) Del(k string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	delete(s.m, k)
+
+	return nil
+}
+
+// Close does nothing for the Store in memory.
+func (s *Store) Close() error { return nil }
@@ -0,0 +1,36 @@
+package memory_test
+
+import (
+	"errors"
+	"fmt"
+	"github.com/bingoohuang/gokv/pkg/memory"
+	"github.com/bingoohuang/gokv/pkg/storetest"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestStore(t *testing.T) {
+	storetest.TestStore(t, memory.New())
+}
+
+func TestEach(t *testing.T) {
+	s := memory.New()
+	for i := 0; i < 10; i++ {
+		assert.Nil(t, s.Set(fmt.Sprintf("k%d", i), "v"))
+	}
+
+	errStop := errors.New("stop")
+	visited := 0
+	err := s.Each(func(k, v string) error {
+		if visited++; visited == 3 {
+			return errStop
+		}
+		return nil
+	})
+	assert.Equal(t, errStop, err)
+	assert.Equal(t, 3, visited)
+
+	visited = 0
+	assert.Nil(t, s.Each(func(k, v string) error { visited++; return nil }))
+	assert.Equal(t, 10, visited)
+}
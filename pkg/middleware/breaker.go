@@ -0,0 +1,199 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"github.com/bingoohuang/gokv"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is the error returned immediately while the circuit is open.
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+// BreakerState is the state of a circuit breaker.
+type BreakerState int
+
+const (
+	// BreakerClosed passes the operations to the inner store.
+	BreakerClosed BreakerState = iota
+	// BreakerOpen fails the operations with ErrCircuitOpen.
+	BreakerOpen
+	// BreakerHalfOpen passes a single probe operation to the inner store after the cooldown.
+	BreakerHalfOpen
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerClosed:
+		return "closed"
+	case BreakerOpen:
+		return "open"
+	default:
+		return "half-open"
+	}
+}
+
+// BreakerSettings are the settings of a circuit breaker.
+type BreakerSettings struct {
+	// Threshold is the number of the consecutive errors to open the circuit, default to 5.
+	Threshold int
+	// Cooldown is the duration of the open state before probing, default to 10s.
+	Cooldown time.Duration
+	// Now returns the current time, default to time.Now.
+	Now func() time.Time
+}
+
+// CircuitBreakerStore fails fast with ErrCircuitOpen when the inner store is known to be unhealthy.
+type CircuitBreakerStore struct {
+	Inner gokv.Store
+	BreakerSettings
+
+	lock     sync.Mutex
+	state    BreakerState
+	failures int
+	openedAt time.Time
+	// probing tells whether the probe of the half-open state is running.
+	probing bool
+}
+
+// CircuitBreaker creates a store which opens the circuit after settings.Threshold consecutive errors
+// of inner, fails the operations with ErrCircuitOpen while open, and probes inner by a single operation
+// in the half-open state after settings.Cooldown, which closes the circuit when it succeeds.
+// gokv.ErrKeyNotFound is counted as a success, while the errors of the done contexts are neither,
+// e.g. the probe canceled by its caller keeps the circuit half-open for the next probe.
+func CircuitBreaker(inner gokv.Store, settings BreakerSettings) *CircuitBreakerStore {
+	if settings.Threshold <= 0 {
+		settings.Threshold = 5
+	}
+	if settings.Cooldown <= 0 {
+		settings.Cooldown = 10 * time.Second
+	}
+	if settings.Now == nil {
+		settings.Now = time.Now
+	}
+
+	return &CircuitBreakerStore{Inner: inner, BreakerSettings: settings}
+}
+
+// State returns the current state of the circuit.
+func (s *CircuitBreakerStore) State() BreakerState {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.state == BreakerOpen && s.Now().Sub(s.openedAt) >= s.Cooldown {
+		return BreakerHalfOpen
+	}
+
+	return s.state
+}
+
+// allow tells whether an operation is allowed, it moves the open circuit to half-open after the cooldown,
+// and only one operation at a time is allowed as the probe in the half-open state.
+func (s *CircuitBreakerStore) allow() (probe bool, err error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	switch s.state {
+	case BreakerClosed:
+		return false, nil
+	case BreakerOpen:
+		if s.Now().Sub(s.openedAt) < s.Cooldown {
+			return false, ErrCircuitOpen
+		}
+		s.state = BreakerHalfOpen
+	}
+
+	if s.probing {
+		return false, ErrCircuitOpen
+	}
+
+	s.probing = true
+	return true, nil
+}
+
+func (s *CircuitBreakerStore) done(ctx context.Context, probe bool, err error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if probe {
+		s.probing = false
+	}
+
+	if err != nil && (ctx.Err() != nil || errors.Is(err, context.Canceled) ||
+		errors.Is(err, context.DeadlineExceeded)) {
+		return // neutral, which tells nothing about the health of inner
+	}
+
+	if err == nil || errors.Is(err, gokv.ErrKeyNotFound) {
+		if s.state == BreakerHalfOpen {
+			s.state = BreakerClosed
+		}
+		s.failures = 0
+		return
+	}
+
+	s.failures++
+	if s.state == BreakerHalfOpen || s.failures >= s.Threshold {
+		s.state = BreakerOpen
+		s.openedAt = s.Now()
+	}
+}
+
+func (s *CircuitBreakerStore) call(ctx context.Context, f func() error) error {
+	probe, err := s.allow()
+	if err != nil {
+		return err
+	}
+
+	err = f()
+	s.done(ctx, probe, err)
+
+	return err
+}
+
+// All returns the key values of the inner store.
+func (s *CircuitBreakerStore) All() (map[string]string, error) {
+	return s.AllContext(context.Background())
+}
+
+// Set stores the value to the inner store.
+func (s *CircuitBreakerStore) Set(k, v string) error { return s.SetContext(context.Background(), k, v) }
+
+// Get retrieves the value from the inner store.
+func (s *CircuitBreakerStore) Get(k string) (string, error) {
+	return s.GetContext(context.Background(), k)
+}
+
+// Del deletes the value from the inner store.
+func (s *CircuitBreakerStore) Del(k string) error { return s.DelContext(context.Background(), k) }
+
+// AllContext returns the key values of the inner store.
+func (s *CircuitBreakerStore) AllContext(ctx context.Context) (kvs map[string]string, err error) {
+	err = s.call(ctx, func() (err error) {
+		kvs, err = withContext(s.Inner).AllContext(ctx)
+		return err
+	})
+
+	return kvs, err
+}
+
+// SetContext stores the value to the inner store.
+func (s *CircuitBreakerStore) SetContext(ctx context.Context, k, v string) error {
+	return s.call(ctx, func() error { return withContext(s.Inner).SetContext(ctx, k, v) })
+}
+
+// GetContext retrieves the value from the inner store.
+func (s *CircuitBreakerStore) GetContext(ctx context.Context, k string) (v string, err error) {
+	err = s.call(ctx, func() (err error) {
+		v, err = withContext(s.Inner).GetContext(ctx, k)
+		return err
+	})
+
+	return v, err
+}
+
+// DelContext deletes the value from the inner store.
+func (s *CircuitBreakerStore) DelContext(ctx context.Context, k string) error {
+	return s.call(ctx, func() error { return withContext(s.Inner).DelContext(ctx, k) })
+}
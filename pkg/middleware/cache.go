@@ -0,0 +1,204 @@
+package middleware
+
+import (
+	"context"
+	"github.com/bingoohuang/gokv"
+	"log"
+	"sync"
+	"time"
+)
+
+// GetManyStore is implemented by the stores which can retrieve multiple keys at once.
+type GetManyStore interface {
+	// GetMany retrieves the values of the keys, the missing keys are absent from the result.
+	GetMany(keys []string) (map[string]string, error)
+}
+
+// CacheStore is a read-through cache in memory over the inner store.
+type CacheStore struct {
+	Inner gokv.Store
+
+	lock  sync.RWMutex
+	cache map[string]string
+	// gens are bumped by the writes of the keys, so that a read-through fill racing with a write
+	// never puts the stale value back into the cache.
+	gens map[string]uint64
+
+	stop      chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// Cache creates a read-through cache over inner, which is refreshed from inner.All() in every refreshInterval
+// when it is positive. Close must be called to stop the refreshing.
+func Cache(inner gokv.Store, refreshInterval time.Duration) *CacheStore {
+	s := &CacheStore{Inner: inner, cache: make(map[string]string), gens: make(map[string]uint64)}
+
+	if refreshInterval > 0 {
+		s.stop = make(chan struct{})
+		s.done = make(chan struct{})
+
+		go s.tickerRefresh(refreshInterval)
+	}
+
+	return s
+}
+
+func (s *CacheStore) tickerRefresh(interval time.Duration) {
+	defer close(s.done)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+			if _, err := s.All(); err != nil {
+				log.Printf("W! refresh error %v", err)
+			}
+		}
+	}
+}
+
+// Preload fills the cache with the keys from the inner store, or all the keys when keys is empty.
+// The keys are retrieved by GetMany if the inner store supports it, otherwise by All.
+// The keys missing from the inner store are removed from the cache.
+func (s *CacheStore) Preload(keys []string) error {
+	if len(keys) == 0 {
+		_, err := s.All()
+		return err
+	}
+
+	var kvs map[string]string
+	var err error
+	if gm, ok := s.Inner.(GetManyStore); ok {
+		kvs, err = gm.GetMany(keys)
+	} else {
+		kvs, err = s.Inner.All()
+	}
+	if err != nil {
+		return err
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for _, k := range keys {
+		if v, ok := kvs[k]; ok {
+			s.cache[k] = v
+		} else {
+			delete(s.cache, k)
+		}
+	}
+
+	return nil
+}
+
+// All returns the key values of the inner store, and replaces the cache with them.
+func (s *CacheStore) All() (map[string]string, error) { return s.AllContext(context.Background()) }
+
+// Get retrieves the value from the cache, or from the inner store on a miss.
+func (s *CacheStore) Get(k string) (string, error) { return s.GetContext(context.Background(), k) }
+
+// Set stores the value to the inner store and the cache.
+func (s *CacheStore) Set(k, v string) error { return s.SetContext(context.Background(), k, v) }
+
+// Del deletes the value from the inner store and the cache.
+func (s *CacheStore) Del(k string) error { return s.DelContext(context.Background(), k) }
+
+// AllContext returns the key values of the inner store, and replaces the cache with them.
+func (s *CacheStore) AllContext(ctx context.Context) (map[string]string, error) {
+	kvs, err := withContext(s.Inner).AllContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	cache := make(map[string]string, len(kvs))
+	for k, v := range kvs {
+		cache[k] = v
+	}
+
+	s.lock.Lock()
+	s.cache = cache
+	s.lock.Unlock()
+
+	return kvs, nil
+}
+
+// GetContext retrieves the value from the cache, or from the inner store on a miss.
+func (s *CacheStore) GetContext(ctx context.Context, k string) (string, error) {
+	s.lock.RLock()
+	v, ok := s.cache[k]
+	gen := s.gens[k]
+	s.lock.RUnlock()
+
+	if ok {
+		return v, nil
+	}
+
+	v, err := withContext(s.Inner).GetContext(ctx, k)
+	if err != nil {
+		return "", err
+	}
+
+	s.lock.Lock()
+	if s.gens[k] == gen {
+		s.cache[k] = v
+	}
+	s.lock.Unlock()
+
+	return v, nil
+}
+
+// SetContext stores the value to the inner store and the cache.
+func (s *CacheStore) SetContext(ctx context.Context, k, v string) error {
+	if err := withContext(s.Inner).SetContext(ctx, k, v); err != nil {
+		return err
+	}
+
+	s.lock.Lock()
+	s.gens[k]++
+	s.cache[k] = v
+	s.lock.Unlock()
+
+	return nil
+}
+
+// DelContext deletes the value from the inner store and the cache.
+// The key is evicted again after the inner store deletes it, in case a Get filled it in between.
+func (s *CacheStore) DelContext(ctx context.Context, k string) error {
+	s.evict(k)
+
+	if err := withContext(s.Inner).DelContext(ctx, k); err != nil {
+		return err
+	}
+
+	s.evict(k)
+
+	return nil
+}
+
+func (s *CacheStore) evict(k string) {
+	s.lock.Lock()
+	s.gens[k]++
+	delete(s.cache, k)
+	s.lock.Unlock()
+}
+
+// Close stops the refreshing, and closes the inner store if it is a gokv.Closer.
+func (s *CacheStore) Close() error {
+	s.closeOnce.Do(func() {
+		if s.stop != nil {
+			close(s.stop)
+			<-s.done
+		}
+	})
+
+	if c, ok := s.Inner.(gokv.Closer); ok {
+		return c.Close()
+	}
+
+	return nil
+}
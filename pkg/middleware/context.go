@@ -0,0 +1,52 @@
+package middleware
+
+import (
+	"context"
+	"github.com/bingoohuang/gokv"
+)
+
+// withContext returns s as a gokv.ContextStore. When s does not support context,
+// the context is only checked before each operation.
+func withContext(s gokv.Store) gokv.ContextStore {
+	if cs, ok := s.(gokv.ContextStore); ok {
+		return cs
+	}
+
+	return contextStore{s: s}
+}
+
+type contextStore struct {
+	s gokv.Store
+}
+
+func (c contextStore) AllContext(ctx context.Context) (map[string]string, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	return c.s.All()
+}
+
+func (c contextStore) SetContext(ctx context.Context, k, v string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return c.s.Set(k, v)
+}
+
+func (c contextStore) GetContext(ctx context.Context, k string) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+
+	return c.s.Get(k)
+}
+
+func (c contextStore) DelContext(ctx context.Context, k string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return c.s.Del(k)
+}
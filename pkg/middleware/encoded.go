@@ -0,0 +1,70 @@
+package middleware
+
+import (
+	"context"
+	"github.com/bingoohuang/gokv"
+	"github.com/bingoohuang/gokv/pkg/codec"
+)
+
+// EncodedStore stores the values encoded by the codec in the inner string store.
+// The string operations of the inner store are still available.
+type EncodedStore struct {
+	gokv.Store
+	Codec codec.Codec
+}
+
+// Encoded creates a store which encodes the values by c around inner.
+func Encoded(inner gokv.Store, c codec.Codec) *EncodedStore {
+	return &EncodedStore{Store: inner, Codec: c}
+}
+
+// AllContext returns the key values of the inner store.
+func (s *EncodedStore) AllContext(ctx context.Context) (map[string]string, error) {
+	return withContext(s.Store).AllContext(ctx)
+}
+
+// SetContext stores the value to the inner store.
+func (s *EncodedStore) SetContext(ctx context.Context, k, v string) error {
+	return withContext(s.Store).SetContext(ctx, k, v)
+}
+
+// GetContext retrieves the value from the inner store.
+func (s *EncodedStore) GetContext(ctx context.Context, k string) (string, error) {
+	return withContext(s.Store).GetContext(ctx, k)
+}
+
+// DelContext deletes the value from the inner store.
+func (s *EncodedStore) DelContext(ctx context.Context, k string) error {
+	return withContext(s.Store).DelContext(ctx, k)
+}
+
+// SetValue marshals v and stores it for the key.
+func (s *EncodedStore) SetValue(k string, v interface{}) error {
+	return s.SetValueContext(context.Background(), k, v)
+}
+
+// GetValue retrieves the value of the key and unmarshals it into dest, which must be a pointer.
+// It returns gokv.ErrKeyNotFound when the key is missing, as the inner store does.
+func (s *EncodedStore) GetValue(k string, dest interface{}) error {
+	return s.GetValueContext(context.Background(), k, dest)
+}
+
+// SetValueContext is SetValue bound to the context.
+func (s *EncodedStore) SetValueContext(ctx context.Context, k string, v interface{}) error {
+	data, err := s.Codec.Marshal(v)
+	if err != nil {
+		return codec.WrapError("marshal", v, err)
+	}
+
+	return s.SetContext(ctx, k, string(data))
+}
+
+// GetValueContext is GetValue bound to the context.
+func (s *EncodedStore) GetValueContext(ctx context.Context, k string, dest interface{}) error {
+	v, err := s.GetContext(ctx, k)
+	if err != nil {
+		return err
+	}
+
+	return codec.WrapError("unmarshal", dest, s.Codec.Unmarshal([]byte(v), dest))
+}
@@ -0,0 +1,97 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"github.com/bingoohuang/gokv"
+	"go.uber.org/multierr"
+	"log"
+)
+
+// FallbackStore reads from a fast primary store, and falls back to an authoritative secondary store on misses.
+type FallbackStore struct {
+	Primary   gokv.Store
+	Secondary gokv.Store
+	// Backfill stores the values found in the secondary to the primary.
+	Backfill bool
+	// PrimaryWritesOnly writes Set and Del to the primary only, otherwise they go to both the stores.
+	PrimaryWritesOnly bool
+}
+
+// Fallback creates a store whose Get tries primary, and then secondary on gokv.ErrKeyNotFound,
+// backfilling primary with the found value when backfill is true. Set and Del go to the secondary
+// and then the primary, while All lists the key values of the authoritative secondary.
+func Fallback(primary, secondary gokv.Store, backfill bool) *FallbackStore {
+	return &FallbackStore{Primary: primary, Secondary: secondary, Backfill: backfill}
+}
+
+// All returns the key values of the secondary.
+func (s *FallbackStore) All() (map[string]string, error) { return s.AllContext(context.Background()) }
+
+// Get retrieves the value from the primary, or from the secondary when it is missing from the primary.
+func (s *FallbackStore) Get(k string) (string, error) { return s.GetContext(context.Background(), k) }
+
+// Set stores the value to the secondary and the primary, or the primary only with PrimaryWritesOnly.
+func (s *FallbackStore) Set(k, v string) error { return s.SetContext(context.Background(), k, v) }
+
+// Del deletes the value from the secondary and the primary, or the primary only with PrimaryWritesOnly.
+func (s *FallbackStore) Del(k string) error { return s.DelContext(context.Background(), k) }
+
+// AllContext returns the key values of the secondary.
+func (s *FallbackStore) AllContext(ctx context.Context) (map[string]string, error) {
+	return withContext(s.Secondary).AllContext(ctx)
+}
+
+// GetContext retrieves the value from the primary, or from the secondary when it is missing from the primary.
+// A failed backfill is logged without failing the Get.
+func (s *FallbackStore) GetContext(ctx context.Context, k string) (string, error) {
+	v, err := withContext(s.Primary).GetContext(ctx, k)
+	if !errors.Is(err, gokv.ErrKeyNotFound) {
+		return v, err
+	}
+
+	if v, err = withContext(s.Secondary).GetContext(ctx, k); err != nil {
+		return "", err
+	}
+
+	if s.Backfill {
+		if err := withContext(s.Primary).SetContext(ctx, k, v); err != nil {
+			log.Printf("W! backfill %s error %v", k, err)
+		}
+	}
+
+	return v, nil
+}
+
+// SetContext stores the value to the secondary and the primary, or the primary only with PrimaryWritesOnly.
+func (s *FallbackStore) SetContext(ctx context.Context, k, v string) error {
+	if !s.PrimaryWritesOnly {
+		if err := withContext(s.Secondary).SetContext(ctx, k, v); err != nil {
+			return err
+		}
+	}
+
+	return withContext(s.Primary).SetContext(ctx, k, v)
+}
+
+// DelContext deletes the value from the secondary and the primary, or the primary only with PrimaryWritesOnly.
+func (s *FallbackStore) DelContext(ctx context.Context, k string) error {
+	if !s.PrimaryWritesOnly {
+		if err := withContext(s.Secondary).DelContext(ctx, k); err != nil {
+			return err
+		}
+	}
+
+	return withContext(s.Primary).DelContext(ctx, k)
+}
+
+// Close closes the stores which are gokv.Closer.
+func (s *FallbackStore) Close() (err error) {
+	for _, store := range []gokv.Store{s.Primary, s.Secondary} {
+		if c, ok := store.(gokv.Closer); ok {
+			err = multierr.Append(err, c.Close())
+		}
+	}
+
+	return err
+}
@@ -0,0 +1,74 @@
+package middleware
+
+import (
+	"context"
+	"github.com/bingoohuang/gokv"
+	"strings"
+)
+
+var (
+	// LowercaseKey is a key transform which folds the keys to lower case.
+	LowercaseKey = strings.ToLower
+	// TrimKey is a key transform which trims the leading and trailing white spaces of the keys.
+	TrimKey = strings.TrimSpace
+)
+
+// ComposeKeys composes the key transforms which are applied in order.
+func ComposeKeys(fns ...func(string) string) func(string) string {
+	return func(k string) string {
+		for _, fn := range fns {
+			k = fn(k)
+		}
+
+		return k
+	}
+}
+
+// KeyTransformStore transforms the keys before they hit the inner store.
+type KeyTransformStore struct {
+	Inner gokv.Store
+	Fn    func(string) string
+}
+
+// KeyTransform creates a store which transforms the keys of Get, Set and Del by fn around inner,
+// e.g. ComposeKeys(TrimKey, LowercaseKey), so that the keys differing only in the case or the spaces
+// resolve to the same entry. The transform is one-way, All returns the transformed keys as stored.
+func KeyTransform(inner gokv.Store, fn func(string) string) *KeyTransformStore {
+	return &KeyTransformStore{Inner: inner, Fn: fn}
+}
+
+// All returns the key values of the inner store, whose keys are the transformed ones.
+func (s *KeyTransformStore) All() (map[string]string, error) {
+	return s.AllContext(context.Background())
+}
+
+// Set stores the value for the transformed key.
+func (s *KeyTransformStore) Set(k, v string) error { return s.SetContext(context.Background(), k, v) }
+
+// Get retrieves the value of the transformed key.
+func (s *KeyTransformStore) Get(k string) (string, error) {
+	return s.GetContext(context.Background(), k)
+}
+
+// Del deletes the value of the transformed key.
+func (s *KeyTransformStore) Del(k string) error { return s.DelContext(context.Background(), k) }
+
+// AllContext returns the key values of the inner store, whose keys are the transformed ones.
+func (s *KeyTransformStore) AllContext(ctx context.Context) (map[string]string, error) {
+	return withContext(s.Inner).AllContext(ctx)
+}
+
+// SetContext stores the value for the transformed key.
+func (s *KeyTransformStore) SetContext(ctx context.Context, k, v string) error {
+	return withContext(s.Inner).SetContext(ctx, s.Fn(k), v)
+}
+
+// GetContext retrieves the value of the transformed key.
+func (s *KeyTransformStore) GetContext(ctx context.Context, k string) (string, error) {
+	return withContext(s.Inner).GetContext(ctx, s.Fn(k))
+}
+
+// DelContext deletes the value of the transformed key.
+func (s *KeyTransformStore) DelContext(ctx context.Context, k string) error {
+	return withContext(s.Inner).DelContext(ctx, s.Fn(k))
+}
@@ -0,0 +1,802 @@
+package middleware_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/bingoohuang/gokv"
+	"github.com/bingoohuang/gokv/pkg/codec"
+	"github.com/bingoohuang/gokv/pkg/memory"
+	"github.com/bingoohuang/gokv/pkg/middleware"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+var errDown = errors.New("store is down")
+
+// mapStore is a gokv.Store over a map which counts the calls and fails when down.
+type mapStore struct {
+	sync.Mutex
+	m     map[string]string
+	down  bool
+	calls int
+}
+
+func newMapStore(kvs map[string]string) *mapStore {
+	m := make(map[string]string)
+	for k, v := range kvs {
+		m[k] = v
+	}
+
+	return &mapStore{m: m}
+}
+
+func (s *mapStore) call() error {
+	s.Lock()
+	defer s.Unlock()
+
+	s.calls++
+	if s.down {
+		return errDown
+	}
+
+	return nil
+}
+
+func (s *mapStore) All() (map[string]string, error) {
+	if err := s.call(); err != nil {
+		return nil, err
+	}
+
+	s.Lock()
+	defer s.Unlock()
+
+	m := make(map[string]string)
+	for k, v := range s.m {
+		m[k] = v
+	}
+
+	return m, nil
+}
+
+func (s *mapStore) Set(k, v string) error {
+	if err := s.call(); err != nil {
+		return err
+	}
+
+	s.Lock()
+	defer s.Unlock()
+
+	s.m[k] = v
+
+	return nil
+}
+
+func (s *mapStore) Get(k string) (string, error) {
+	if err := s.call(); err != nil {
+		return "", err
+	}
+
+	s.Lock()
+	defer s.Unlock()
+
+	v, ok := s.m[k]
+	if !ok {
+		return "", gokv.ErrKeyNotFound
+	}
+
+	return v, nil
+}
+
+func (s *mapStore) Del(k string) error {
+	if err := s.call(); err != nil {
+		return err
+	}
+
+	s.Lock()
+	defer s.Unlock()
+
+	delete(s.m, k)
+
+	return nil
+}
+
+func TestReadReplica(t *testing.T) {
+	primary := newMapStore(map[string]string{"k": "primary"})
+	r1 := newMapStore(map[string]string{"k": "r1"})
+	r2 := newMapStore(map[string]string{"k": "r2"})
+	r1.down = true
+
+	s := middleware.ReadReplica(primary, r1, r2)
+	s.Policy = middleware.Sequential{}
+
+	v, err := s.Get("k")
+	assert.Nil(t, err)
+	assert.Equal(t, "r2", v)
+	assert.Equal(t, 1, r1.calls)
+	assert.Equal(t, 0, primary.calls)
+
+	all, err := s.All()
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"k": "r2"}, all)
+
+	_, err = s.Get("missing")
+	assert.True(t, errors.Is(err, gokv.ErrKeyNotFound))
+
+	r2.down = true
+	v, err = s.Get("k")
+	assert.Nil(t, err)
+	assert.Equal(t, "primary", v)
+
+	primary.down = true
+	_, err = s.Get("k")
+	assert.True(t, errors.Is(err, errDown))
+	primary.down = false
+
+	assert.Nil(t, s.Set("k2", "v2"))
+	assert.Equal(t, "v2", primary.m["k2"])
+	assert.NotContains(t, r1.m, "k2")
+	assert.Nil(t, s.Del("k"))
+	assert.NotContains(t, primary.m, "k")
+	assert.Contains(t, r1.m, "k")
+}
+
+func TestReadReplicaRoundRobin(t *testing.T) {
+	r1 := newMapStore(map[string]string{"k": "r1"})
+	r2 := newMapStore(map[string]string{"k": "r2"})
+	s := middleware.ReadReplica(newMapStore(nil), r1, r2)
+
+	var values []string
+	for i := 0; i < 4; i++ {
+		v, err := s.Get("k")
+		assert.Nil(t, err)
+		values = append(values, v)
+	}
+
+	assert.Equal(t, []string{"r1", "r2", "r1", "r2"}, values)
+
+	// the nil Policy of a struct literal is RoundRobin too
+	literal := &middleware.ReadReplicaStore{Primary: newMapStore(nil), Replicas: []gokv.Store{r1, r2}}
+	values = nil
+	for i := 0; i < 2; i++ {
+		v, err := literal.Get("k")
+		assert.Nil(t, err)
+		values = append(values, v)
+	}
+
+	assert.Equal(t, []string{"r1", "r2"}, values)
+}
+
+// ctxStore is a mapStore supporting gokv.ContextStore which records the last context.
+type ctxStore struct {
+	*mapStore
+	lastCtx context.Context
+}
+
+func (s *ctxStore) AllContext(ctx context.Context) (map[string]string, error) {
+	s.lastCtx = ctx
+	return s.All()
+}
+
+func (s *ctxStore) SetContext(ctx context.Context, k, v string) error {
+	s.lastCtx = ctx
+	return s.Set(k, v)
+}
+
+func (s *ctxStore) GetContext(ctx context.Context, k string) (string, error) {
+	s.lastCtx = ctx
+	return s.Get(k)
+}
+
+func (s *ctxStore) DelContext(ctx context.Context, k string) error {
+	s.lastCtx = ctx
+	return s.Del(k)
+}
+
+type ctxKey struct{}
+
+func TestContextPropagation(t *testing.T) {
+	primary := &ctxStore{mapStore: newMapStore(map[string]string{"k": "v"})}
+	ctx := context.WithValue(context.Background(), ctxKey{}, "traced")
+
+	var s gokv.ContextStore = middleware.Retry(middleware.ReadReplica(primary), 3, time.Millisecond)
+	v, err := s.GetContext(ctx, "k")
+	assert.Nil(t, err)
+	assert.Equal(t, "v", v)
+	assert.Equal(t, "traced", primary.lastCtx.Value(ctxKey{}))
+
+	assert.Nil(t, s.SetContext(ctx, "k2", "v2"))
+	assert.Equal(t, "traced", primary.lastCtx.Value(ctxKey{}))
+
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = middleware.Retry(newMapStore(nil), 3, time.Millisecond).GetContext(canceled, "k")
+	assert.True(t, errors.Is(err, context.Canceled))
+}
+
+// assertContextPropagated asserts that the operations of s pass the context through to inner.
+func assertContextPropagated(t *testing.T, s gokv.ContextStore, inner *ctxStore) {
+	ctx := context.WithValue(context.Background(), ctxKey{}, "traced")
+
+	assert.Nil(t, s.SetContext(ctx, "k", "v"))
+	assert.Equal(t, "traced", inner.lastCtx.Value(ctxKey{}))
+
+	inner.lastCtx = nil
+	_, err := s.AllContext(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, "traced", inner.lastCtx.Value(ctxKey{}))
+
+	inner.lastCtx = nil
+	assert.Nil(t, s.DelContext(ctx, "k"))
+	assert.Equal(t, "traced", inner.lastCtx.Value(ctxKey{}))
+
+	inner.lastCtx = nil
+	_, err = s.GetContext(ctx, "k")
+	assert.True(t, errors.Is(err, gokv.ErrKeyNotFound))
+	assert.Equal(t, "traced", inner.lastCtx.Value(ctxKey{}))
+}
+
+func TestRetryAbortsOnCancel(t *testing.T) {
+	inner := newMapStore(nil)
+	inner.down = true
+
+	_, err := middleware.Retry(inner, 3, time.Millisecond).Get("k")
+	assert.True(t, errors.Is(err, errDown))
+	assert.Equal(t, 3, inner.calls)
+
+	inner.calls = 0
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	err = middleware.Retry(inner, 1000, 20*time.Millisecond).SetContext(ctx, "k", "v")
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+	assert.True(t, time.Since(start) < time.Second)
+	assert.LessOrEqual(t, inner.calls, 4)
+}
+
+type profile struct {
+	Name string
+	Tags []string
+}
+
+func TestEncoded(t *testing.T) {
+	for _, c := range []codec.Codec{codec.JSON, codec.Gob} {
+		s := middleware.Encoded(memory.New(), c)
+
+		in := profile{Name: "bingoo", Tags: []string{"a", "b"}}
+		assert.Nil(t, s.SetValue("p", in))
+
+		var out profile
+		assert.Nil(t, s.GetValue("p", &out))
+		assert.Equal(t, in, out)
+
+		err := s.GetValue("missing", &out)
+		assert.True(t, errors.Is(err, gokv.ErrKeyNotFound))
+	}
+
+	s := middleware.Encoded(memory.New(), codec.JSON)
+	assert.Nil(t, s.SetValue("p", profile{Name: "bingoo"}))
+	v, err := s.Get("p")
+	assert.Nil(t, err)
+	assert.Equal(t, `{"Name":"bingoo","Tags":null}`, v)
+}
+
+func TestEncodedContext(t *testing.T) {
+	inner := &ctxStore{mapStore: newMapStore(nil)}
+	s := middleware.Encoded(inner, codec.JSON)
+	assertContextPropagated(t, s, inner)
+
+	ctx := context.WithValue(context.Background(), ctxKey{}, "traced")
+	assert.Nil(t, s.SetValueContext(ctx, "p", profile{Name: "bingoo"}))
+	var out profile
+	assert.Nil(t, s.GetValueContext(ctx, "p", &out))
+	assert.Equal(t, "bingoo", out.Name)
+	assert.Equal(t, "traced", inner.lastCtx.Value(ctxKey{}))
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	now := time.Now()
+	inner := newMapStore(map[string]string{"k": "v"})
+	s := middleware.CircuitBreaker(inner, middleware.BreakerSettings{
+		Threshold: 3,
+		Cooldown:  time.Minute,
+		Now:       func() time.Time { return now },
+	})
+
+	_, err := s.Get("missing")
+	assert.True(t, errors.Is(err, gokv.ErrKeyNotFound))
+	assert.Equal(t, middleware.BreakerClosed, s.State())
+
+	inner.down = true
+	for i := 0; i < 3; i++ {
+		_, err := s.Get("k")
+		assert.True(t, errors.Is(err, errDown))
+	}
+	assert.Equal(t, middleware.BreakerOpen, s.State())
+
+	_, err = s.Get("k")
+	assert.True(t, errors.Is(err, middleware.ErrCircuitOpen))
+	assert.Equal(t, 4, inner.calls)
+
+	// a failed probe opens the circuit again
+	now = now.Add(time.Minute)
+	assert.Equal(t, middleware.BreakerHalfOpen, s.State())
+	assert.True(t, errors.Is(s.Set("k", "v2"), errDown))
+	assert.Equal(t, middleware.BreakerOpen, s.State())
+	assert.True(t, errors.Is(s.Set("k", "v2"), middleware.ErrCircuitOpen))
+
+	// the probe failed by its canceled ctx keeps the circuit half-open for the next probe
+	now = now.Add(time.Minute)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = s.GetContext(ctx, "k")
+	assert.True(t, errors.Is(err, context.Canceled))
+	assert.Equal(t, middleware.BreakerHalfOpen, s.State())
+
+	// a successful probe closes the circuit
+	inner.down = false
+	v, err := s.Get("k")
+	assert.Nil(t, err)
+	assert.Equal(t, "v", v)
+	assert.Equal(t, middleware.BreakerClosed, s.State())
+	assert.Nil(t, s.Set("k", "v2"))
+}
+
+// unsafeStore is a gokv.Store over a map without locking, which fails when written concurrently.
+type unsafeStore struct {
+	m       map[string]string
+	writing int32
+	overlap int32
+}
+
+func (s *unsafeStore) All() (map[string]string, error) { return s.m, nil }
+
+func (s *unsafeStore) Get(k string) (string, error) {
+	v, ok := s.m[k]
+	if !ok {
+		return "", gokv.ErrKeyNotFound
+	}
+
+	return v, nil
+}
+
+func (s *unsafeStore) Set(k, v string) error {
+	return s.write(func() { s.m[k] = v })
+}
+
+func (s *unsafeStore) Del(k string) error {
+	return s.write(func() { delete(s.m, k) })
+}
+
+func (s *unsafeStore) write(f func()) error {
+	if atomic.AddInt32(&s.writing, 1) > 1 {
+		atomic.AddInt32(&s.overlap, 1)
+	}
+	defer atomic.AddInt32(&s.writing, -1)
+
+	f()
+	time.Sleep(time.Microsecond)
+
+	return nil
+}
+
+func TestSerializeWrites(t *testing.T) {
+	inner := &unsafeStore{m: make(map[string]string)}
+	s := middleware.SerializeWrites(inner)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			k := fmt.Sprintf("k%d", i)
+			for j := 0; j < 20; j++ {
+				assert.Nil(t, s.Set(k, fmt.Sprintf("v%d", j)))
+			}
+			if i%2 == 0 {
+				assert.Nil(t, s.Del(k))
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	assert.Equal(t, int32(0), atomic.LoadInt32(&inner.overlap))
+	kvs, err := s.All()
+	assert.Nil(t, err)
+	assert.Len(t, kvs, 25)
+	v, err := s.Get("k1")
+	assert.Nil(t, err)
+	assert.Equal(t, "v19", v)
+
+	assert.Nil(t, s.Close())
+	assert.True(t, errors.Is(s.Set("k1", "v"), middleware.ErrWriterClosed))
+}
+
+func TestSerializeContext(t *testing.T) {
+	inner := &ctxStore{mapStore: newMapStore(nil)}
+	s := middleware.SerializeWrites(inner)
+	defer s.Close()
+
+	assertContextPropagated(t, s, inner)
+}
+
+// getManyStore is a mapStore supporting GetMany.
+type getManyStore struct {
+	*mapStore
+	getMany int
+}
+
+func (s *getManyStore) GetMany(keys []string) (map[string]string, error) {
+	s.getMany++
+
+	kvs := make(map[string]string)
+	for _, k := range keys {
+		if v, err := s.mapStore.Get(k); err == nil {
+			kvs[k] = v
+		}
+	}
+
+	return kvs, nil
+}
+
+func TestCachePreload(t *testing.T) {
+	inner := newMapStore(map[string]string{"k1": "v1", "k2": "v2", "k3": "v3"})
+	s := middleware.Cache(inner, 0)
+	defer s.Close()
+
+	assert.Nil(t, s.Preload(nil))
+	inner.calls = 0
+	for _, k := range []string{"k1", "k2", "k3"} {
+		v, err := s.Get(k)
+		assert.Nil(t, err)
+		assert.Equal(t, "v"+k[1:], v)
+	}
+	assert.Equal(t, 0, inner.calls)
+
+	_, err := s.Get("k9")
+	assert.True(t, errors.Is(err, gokv.ErrKeyNotFound))
+	assert.Equal(t, 1, inner.calls)
+
+	gm := &getManyStore{mapStore: newMapStore(map[string]string{"k1": "v1", "k2": "v2"})}
+	s2 := middleware.Cache(gm, 0)
+	defer s2.Close()
+
+	assert.Nil(t, s2.Preload([]string{"k1", "k9"}))
+	assert.Equal(t, 1, gm.getMany)
+	gm.calls = 0
+	v, err := s2.Get("k1")
+	assert.Nil(t, err)
+	assert.Equal(t, "v1", v)
+	assert.Equal(t, 0, gm.calls)
+}
+
+func TestCacheRefresh(t *testing.T) {
+	inner := newMapStore(map[string]string{"k": "v"})
+	s := middleware.Cache(inner, 10*time.Millisecond)
+
+	v, err := s.Get("k")
+	assert.Nil(t, err)
+	assert.Equal(t, "v", v)
+
+	inner.Lock()
+	inner.m["k"] = "v2"
+	inner.Unlock()
+
+	assert.Eventually(t, func() bool {
+		v, _ := s.Get("k")
+		return v == "v2"
+	}, time.Second, 5*time.Millisecond)
+
+	assert.Nil(t, s.Close())
+	assert.Nil(t, s.Close())
+}
+
+// gatedStore is a mapStore whose Get holds the value read until the gate is opened.
+type gatedStore struct {
+	*mapStore
+	reading chan struct{}
+	gate    chan struct{}
+}
+
+func (s *gatedStore) Get(k string) (string, error) {
+	v, err := s.mapStore.Get(k)
+	s.reading <- struct{}{}
+	<-s.gate
+
+	return v, err
+}
+
+func TestCacheConcurrentGetDel(t *testing.T) {
+	for _, write := range []string{"del", "set"} {
+		inner := &gatedStore{
+			mapStore: newMapStore(map[string]string{"k": "old"}),
+			reading:  make(chan struct{}),
+			gate:     make(chan struct{}),
+		}
+		s := middleware.Cache(inner, 0)
+
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			v, err := s.Get("k")
+			assert.Nil(t, err)
+			assert.Equal(t, "old", v)
+		}()
+
+		<-inner.reading
+		if write == "del" {
+			assert.Nil(t, s.Del("k"))
+		} else {
+			assert.Nil(t, s.Set("k", "new"))
+		}
+		close(inner.gate)
+		<-done
+
+		// the stale value read before the write is not filled into the cache
+		inner.reading = make(chan struct{}, 1)
+		v, err := s.Get("k")
+		if write == "del" {
+			assert.True(t, errors.Is(err, gokv.ErrKeyNotFound), write)
+		} else {
+			assert.Nil(t, err)
+			assert.Equal(t, "new", v, write)
+		}
+	}
+}
+
+func TestCacheContext(t *testing.T) {
+	inner := &ctxStore{mapStore: newMapStore(nil)}
+	assertContextPropagated(t, middleware.Cache(inner, 0), inner)
+}
+
+func TestKeyTransform(t *testing.T) {
+	inner := memory.New()
+	s := middleware.KeyTransform(inner, middleware.ComposeKeys(middleware.TrimKey, middleware.LowercaseKey))
+
+	assert.Nil(t, s.Set("Foo ", "bar"))
+
+	v, err := s.Get("foo")
+	assert.Nil(t, err)
+	assert.Equal(t, "bar", v)
+
+	v, err = s.Get(" FOO")
+	assert.Nil(t, err)
+	assert.Equal(t, "bar", v)
+
+	all, err := s.All()
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"foo": "bar"}, all)
+
+	assert.Nil(t, s.Del("fOo"))
+
+	_, err = inner.Get("foo")
+	assert.True(t, errors.Is(err, gokv.ErrKeyNotFound))
+}
+
+func TestKeyTransformContext(t *testing.T) {
+	inner := &ctxStore{mapStore: newMapStore(nil)}
+	assertContextPropagated(t, middleware.KeyTransform(inner, middleware.LowercaseKey), inner)
+}
+
+func TestWAL(t *testing.T) {
+	var log bytes.Buffer
+	s := middleware.WithWAL(memory.New(), &log)
+
+	assert.Nil(t, s.Set("k1", "v1"))
+	assert.Nil(t, s.Set("k2", "v2"))
+	assert.Nil(t, s.Set("k1", "v1-updated"))
+	assert.Nil(t, s.Set("", ""))
+	assert.Nil(t, s.Del("k2"))
+	assert.Nil(t, s.Set("k3", "值3"))
+
+	want, err := s.All()
+	assert.Nil(t, err)
+
+	replayed := memory.New()
+	assert.Nil(t, middleware.ReplayWAL(bytes.NewReader(log.Bytes()), replayed))
+
+	got, err := replayed.All()
+	assert.Nil(t, err)
+	assert.Equal(t, want, got)
+
+	// the truncated last record is reported, after the records before it are applied
+	replayed = memory.New()
+	err = middleware.ReplayWAL(bytes.NewReader(log.Bytes()[:log.Len()-1]), replayed)
+	assert.Equal(t, io.ErrUnexpectedEOF, err)
+
+	v, err := replayed.Get("k1")
+	assert.Nil(t, err)
+	assert.Equal(t, "v1-updated", v)
+
+	err = middleware.ReplayWAL(bytes.NewReader([]byte{0, 0, 0, 2, 'X', 0}), memory.New())
+	assert.True(t, errors.Is(err, middleware.ErrCorruptWAL))
+
+	// the corrupt length is not allocated
+	err = middleware.ReplayWAL(bytes.NewReader([]byte{0xff, 0xff, 0xff, 0xff, 'S'}), memory.New())
+	assert.True(t, errors.Is(err, middleware.ErrCorruptWAL))
+
+	// the mutations failed by the inner store are skipped by the replay
+	log.Reset()
+	inner := newMapStore(nil)
+	s = middleware.WithWAL(inner, &log)
+	assert.Nil(t, s.Set("k1", "v1"))
+	inner.down = true
+	assert.Equal(t, errDown, s.Set("k1", "v2"))
+	assert.Equal(t, errDown, s.Del("k1"))
+	inner.down = false
+	assert.Nil(t, s.Set("k2", "v2"))
+
+	replayed = memory.New()
+	assert.Nil(t, middleware.ReplayWAL(bytes.NewReader(log.Bytes()), replayed))
+	got, err = replayed.All()
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"k1": "v1", "k2": "v2"}, got)
+}
+
+func TestWALContext(t *testing.T) {
+	inner := &ctxStore{mapStore: newMapStore(nil)}
+	assertContextPropagated(t, middleware.WithWAL(inner, &bytes.Buffer{}), inner)
+}
+
+func TestSharded(t *testing.T) {
+	shards := []gokv.Store{memory.New(), memory.New(), memory.New()}
+	s := middleware.Sharded(shards, nil)
+
+	want := make(map[string]string)
+	for i := 0; i < 30; i++ {
+		k, v := fmt.Sprintf("k%d", i), fmt.Sprintf("v%d", i)
+		want[k] = v
+		assert.Nil(t, s.Set(k, v))
+	}
+
+	for _, shard := range shards {
+		kvs, err := shard.All()
+		assert.Nil(t, err)
+		assert.NotEmpty(t, kvs, "the keys are distributed to every shard")
+		assert.Less(t, len(kvs), len(want))
+
+		for k := range kvs {
+			assert.True(t, s.Shard(k) == shard)
+		}
+	}
+
+	all, err := s.All()
+	assert.Nil(t, err)
+	assert.Equal(t, want, all)
+
+	v, err := s.Get("k7")
+	assert.Nil(t, err)
+	assert.Equal(t, "v7", v)
+
+	assert.Nil(t, s.Del("k7"))
+	_, err = s.Get("k7")
+	assert.True(t, errors.Is(err, gokv.ErrKeyNotFound))
+
+	negative := middleware.Sharded(shards, func(string) int { return -4 })
+	assert.True(t, negative.Shard("k") == shards[2])
+
+	assert.Nil(t, s.Close())
+}
+
+func TestShardedContext(t *testing.T) {
+	inner := &ctxStore{mapStore: newMapStore(nil)}
+	assertContextPropagated(t, middleware.Sharded([]gokv.Store{inner}, nil), inner)
+}
+
+func TestOnChange(t *testing.T) {
+	inner := newMapStore(nil)
+
+	var events []string
+	s := middleware.OnChange(inner, func(op, k, v string) { events = append(events, op+" "+k+" "+v) })
+
+	assert.Nil(t, s.Set("k1", "v1"))
+	assert.Nil(t, s.Del("k1"))
+	_, _ = s.Get("k1")
+
+	inner.down = true
+	assert.Equal(t, errDown, s.Set("k2", "v2"))
+	assert.Equal(t, []string{"set k1 v1", "del k1 "}, events, "only the successful writes are called back")
+
+	// the async callback never blocks the writes
+	release := make(chan struct{})
+	var lock sync.Mutex
+	events = nil
+	async := middleware.OnChangeAsync(newMapStore(nil), func(op, k, v string) {
+		<-release
+		lock.Lock()
+		events = append(events, op+" "+k)
+		lock.Unlock()
+	}, 2)
+
+	for i := 0; i < 5; i++ {
+		assert.Nil(t, async.Set(fmt.Sprintf("k%d", i), "v"))
+	}
+
+	close(release)
+	assert.Nil(t, async.Close())
+	assert.GreaterOrEqual(t, len(events), 2, "the queued events are called back before close")
+	assert.LessOrEqual(t, len(events), 3, "the events beyond the queue are dropped")
+	assert.Equal(t, uint64(5-len(events)), async.Dropped(), "the dropped events are counted")
+	assert.Equal(t, "set k0", events[0])
+	assert.Equal(t, middleware.ErrOnChangeClosed, async.Set("k", "v"), "no event is dropped silently after close")
+	assert.Equal(t, middleware.ErrOnChangeClosed, async.Del("k"))
+
+	// the default buffer queues the events of a burst, instead of dropping all but the one being called back
+	called := 0
+	defaults := middleware.OnChangeAsync(newMapStore(nil), func(op, k, v string) { called++ }, 0)
+	for i := 0; i < 100; i++ {
+		assert.Nil(t, defaults.Set(fmt.Sprintf("k%d", i), "v"))
+	}
+
+	assert.Nil(t, defaults.Close())
+	assert.Equal(t, 100, called)
+	assert.Equal(t, uint64(0), defaults.Dropped())
+}
+
+func TestOnChangeContext(t *testing.T) {
+	inner := &ctxStore{mapStore: newMapStore(nil)}
+	assertContextPropagated(t, middleware.OnChange(inner, func(op, k, v string) {}), inner)
+}
+
+func TestFallback(t *testing.T) {
+	primary := newMapStore(map[string]string{"k1": "primary"})
+	secondary := newMapStore(map[string]string{"k1": "secondary", "k2": "v2"})
+
+	s := middleware.Fallback(primary, secondary, true)
+
+	v, err := s.Get("k1")
+	assert.Nil(t, err)
+	assert.Equal(t, "primary", v)
+	assert.Equal(t, 0, secondary.calls)
+
+	v, err = s.Get("k2")
+	assert.Nil(t, err)
+	assert.Equal(t, "v2", v)
+	assert.Equal(t, "v2", primary.m["k2"], "the value is backfilled into the primary")
+
+	_, err = s.Get("missing")
+	assert.True(t, errors.Is(err, gokv.ErrKeyNotFound))
+
+	primary.down = true
+	_, err = s.Get("k2")
+	assert.Equal(t, errDown, err, "the errors of the primary do not fall back")
+	primary.down = false
+
+	assert.Nil(t, s.Set("k3", "v3"))
+	assert.Equal(t, "v3", primary.m["k3"])
+	assert.Equal(t, "v3", secondary.m["k3"])
+	assert.Nil(t, s.Del("k3"))
+	assert.NotContains(t, primary.m, "k3")
+	assert.NotContains(t, secondary.m, "k3")
+
+	s.PrimaryWritesOnly = true
+	assert.Nil(t, s.Set("k4", "v4"))
+	assert.Equal(t, "v4", primary.m["k4"])
+	assert.NotContains(t, secondary.m, "k4")
+
+	empty := newMapStore(nil)
+	_, err = middleware.Fallback(empty, secondary, false).Get("k2")
+	assert.Nil(t, err)
+	assert.Empty(t, empty.m, "no backfill")
+}
+
+func TestFallbackContext(t *testing.T) {
+	primary := &ctxStore{mapStore: newMapStore(nil)}
+	secondary := &ctxStore{mapStore: newMapStore(nil)}
+	s := middleware.Fallback(primary, secondary, true)
+	assertContextPropagated(t, s, secondary)
+
+	ctx := context.WithValue(context.Background(), ctxKey{}, "traced")
+	assert.Nil(t, secondary.Set("k", "v"))
+	v, err := s.GetContext(ctx, "k")
+	assert.Nil(t, err)
+	assert.Equal(t, "v", v)
+	assert.Equal(t, "traced", primary.lastCtx.Value(ctxKey{}), "the backfill is bound to the context")
+}
@@ -0,0 +1,187 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"github.com/bingoohuang/gokv"
+	"log"
+	"sync"
+	"sync/atomic"
+)
+
+// The ops of the change events.
+const (
+	ChangeSet = "set"
+	ChangeDel = "del"
+)
+
+// DefaultChangeBuffer is the buffer of the queue of OnChangeAsync when the buffer given is not positive.
+const DefaultChangeBuffer = 1024
+
+// ErrOnChangeClosed is the error of the writes after the OnChangeStore is closed.
+var ErrOnChangeClosed = errors.New("on change store is closed")
+
+// changeEvent is a change queued for the callback of the async OnChangeStore.
+type changeEvent struct {
+	op, k, v string
+}
+
+// OnChangeStore calls back on every successful Set and Del of the inner store.
+type OnChangeStore struct {
+	Inner gokv.Store
+	Fn    func(op, k, v string)
+
+	dropped uint64
+
+	// lock is held for reading by the writes, so that Close waits for their events to be queued.
+	lock   sync.RWMutex
+	closed bool
+
+	events    chan changeEvent
+	quit      chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// OnChange creates a store which calls fn synchronously after each successful Set and Del of inner,
+// with the op ChangeSet or ChangeDel, the key, and the value which is empty for ChangeDel,
+// e.g. to broadcast the cache invalidations. The write returns after fn returns.
+func OnChange(inner gokv.Store, fn func(op, k, v string)) *OnChangeStore {
+	return &OnChangeStore{Inner: inner, Fn: fn}
+}
+
+// OnChangeAsync is like OnChange, but fn is called in order by a goroutine from a queue of buffer events,
+// so that a slow fn never blocks the writes, the buffer defaults to DefaultChangeBuffer when it is not positive.
+// The events are dropped with a warning when the queue is full, and counted by Dropped.
+// Close must be called to stop the goroutine after the queued events are called back.
+func OnChangeAsync(inner gokv.Store, fn func(op, k, v string), buffer int) *OnChangeStore {
+	if buffer <= 0 {
+		buffer = DefaultChangeBuffer
+	}
+
+	s := &OnChangeStore{
+		Inner:  inner,
+		Fn:     fn,
+		events: make(chan changeEvent, buffer),
+		quit:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+
+	go s.work()
+
+	return s
+}
+
+func (s *OnChangeStore) work() {
+	defer close(s.done)
+
+	for {
+		select {
+		case e := <-s.events:
+			s.Fn(e.op, e.k, e.v)
+		case <-s.quit:
+			for {
+				select {
+				case e := <-s.events:
+					s.Fn(e.op, e.k, e.v)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+func (s *OnChangeStore) changed(op, k, v string) {
+	if s.events == nil {
+		s.Fn(op, k, v)
+		return
+	}
+
+	select {
+	case s.events <- changeEvent{op: op, k: k, v: v}:
+	default:
+		dropped := atomic.AddUint64(&s.dropped, 1)
+		log.Printf("W! change event %s %s dropped for the full queue, %d dropped", op, k, dropped)
+	}
+}
+
+// Dropped returns the number of the events dropped for the full queue of OnChangeAsync.
+func (s *OnChangeStore) Dropped() uint64 { return atomic.LoadUint64(&s.dropped) }
+
+// All returns the key values of the inner store.
+func (s *OnChangeStore) All() (map[string]string, error) { return s.AllContext(context.Background()) }
+
+// Get retrieves the value from the inner store.
+func (s *OnChangeStore) Get(k string) (string, error) { return s.GetContext(context.Background(), k) }
+
+// Set stores the value to the inner store, and calls back with ChangeSet when it succeeds.
+func (s *OnChangeStore) Set(k, v string) error { return s.SetContext(context.Background(), k, v) }
+
+// Del deletes the value from the inner store, and calls back with ChangeDel when it succeeds.
+func (s *OnChangeStore) Del(k string) error { return s.DelContext(context.Background(), k) }
+
+// AllContext returns the key values of the inner store.
+func (s *OnChangeStore) AllContext(ctx context.Context) (map[string]string, error) {
+	return withContext(s.Inner).AllContext(ctx)
+}
+
+// GetContext retrieves the value from the inner store.
+func (s *OnChangeStore) GetContext(ctx context.Context, k string) (string, error) {
+	return withContext(s.Inner).GetContext(ctx, k)
+}
+
+// SetContext stores the value to the inner store, and calls back with ChangeSet when it succeeds.
+func (s *OnChangeStore) SetContext(ctx context.Context, k, v string) error {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	if s.closed {
+		return ErrOnChangeClosed
+	}
+
+	if err := withContext(s.Inner).SetContext(ctx, k, v); err != nil {
+		return err
+	}
+
+	s.changed(ChangeSet, k, v)
+	return nil
+}
+
+// DelContext deletes the value from the inner store, and calls back with ChangeDel when it succeeds.
+func (s *OnChangeStore) DelContext(ctx context.Context, k string) error {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	if s.closed {
+		return ErrOnChangeClosed
+	}
+
+	if err := withContext(s.Inner).DelContext(ctx, k); err != nil {
+		return err
+	}
+
+	s.changed(ChangeDel, k, "")
+	return nil
+}
+
+// Close stops the goroutine of the async callback after the queued events,
+// and closes the inner store if it is a gokv.Closer. The writes after Close return ErrOnChangeClosed.
+func (s *OnChangeStore) Close() error {
+	s.closeOnce.Do(func() {
+		s.lock.Lock()
+		s.closed = true
+		s.lock.Unlock()
+
+		if s.quit != nil {
+			close(s.quit)
+			<-s.done
+		}
+	})
+
+	if c, ok := s.Inner.(gokv.Closer); ok {
+		return c.Close()
+	}
+
+	return nil
+}
@@ -0,0 +1,137 @@
+// Package middleware contains the decorators of gokv.Store.
+package middleware
+
+import (
+	"context"
+	"errors"
+	"github.com/bingoohuang/gokv"
+	"go.uber.org/multierr"
+	"sync/atomic"
+)
+
+// ReplicaPolicy decides the order in which the replicas are tried for a read.
+type ReplicaPolicy interface {
+	// Order returns the indexes of n replicas in the order to try.
+	Order(n int) []int
+}
+
+// RoundRobin starts each read from the next replica, failing over to the following ones.
+type RoundRobin struct {
+	next uint32
+}
+
+// Order returns the indexes of n replicas starting from the next one.
+func (p *RoundRobin) Order(n int) []int {
+	start := int(atomic.AddUint32(&p.next, 1)-1) % n
+	order := make([]int, n)
+	for i := range order {
+		order[i] = (start + i) % n
+	}
+
+	return order
+}
+
+// Sequential always tries the replicas in the order they are given.
+type Sequential struct{}
+
+// Order returns the indexes of n replicas in order.
+func (Sequential) Order(n int) []int {
+	order := make([]int, n)
+	for i := range order {
+		order[i] = i
+	}
+
+	return order
+}
+
+// ReadReplicaStore reads from the replicas and writes to the primary.
+type ReadReplicaStore struct {
+	Primary  gokv.Store
+	Replicas []gokv.Store
+	// Policy decides the order to try the replicas, default to RoundRobin, also when it is left nil.
+	Policy ReplicaPolicy
+
+	roundRobin RoundRobin
+}
+
+// ReadReplica creates a store whose Get/All pick a replica by the policy and fail over to the next one,
+// and finally to the primary, on error, while Set/Del always go to the primary.
+// A gokv.ErrKeyNotFound from a replica is returned as is without failing over.
+func ReadReplica(primary gokv.Store, replicas ...gokv.Store) *ReadReplicaStore {
+	return &ReadReplicaStore{Primary: primary, Replicas: replicas, Policy: &RoundRobin{}}
+}
+
+// stores returns the stores in the order to try for a read.
+func (s *ReadReplicaStore) stores() []gokv.Store {
+	stores := make([]gokv.Store, 0, len(s.Replicas)+1)
+	if len(s.Replicas) > 0 {
+		var policy ReplicaPolicy = &s.roundRobin
+		if s.Policy != nil {
+			policy = s.Policy
+		}
+
+		for _, i := range policy.Order(len(s.Replicas)) {
+			stores = append(stores, s.Replicas[i])
+		}
+	}
+
+	return append(stores, s.Primary)
+}
+
+// All returns the key values from the first available store.
+func (s *ReadReplicaStore) All() (map[string]string, error) {
+	return s.AllContext(context.Background())
+}
+
+// Set stores the value to the primary.
+func (s *ReadReplicaStore) Set(k, v string) error { return s.SetContext(context.Background(), k, v) }
+
+// Get retrieves the value from the first available store.
+func (s *ReadReplicaStore) Get(k string) (string, error) {
+	return s.GetContext(context.Background(), k)
+}
+
+// Del deletes the value from the primary.
+func (s *ReadReplicaStore) Del(k string) error { return s.DelContext(context.Background(), k) }
+
+// AllContext returns the key values from the first available store.
+func (s *ReadReplicaStore) AllContext(ctx context.Context) (kvs map[string]string, er error) {
+	for _, store := range s.stores() {
+		kvs, err := withContext(store).AllContext(ctx)
+		if err == nil {
+			return kvs, nil
+		}
+
+		if er = multierr.Append(er, err); ctx.Err() != nil {
+			return nil, er
+		}
+	}
+
+	return nil, er
+}
+
+// GetContext retrieves the value from the first available store.
+func (s *ReadReplicaStore) GetContext(ctx context.Context, k string) (v string, er error) {
+	for _, store := range s.stores() {
+		v, err := withContext(store).GetContext(ctx, k)
+		if err == nil || errors.Is(err, gokv.ErrKeyNotFound) {
+			return v, err
+		}
+
+		if er = multierr.Append(er, err); ctx.Err() != nil {
+			return "", er
+		}
+	}
+
+	return "", er
+}
+
+// SetContext stores the value to the primary.
+func (s *ReadReplicaStore) SetContext(ctx context.Context, k, v string) error {
+	return withContext(s.Primary).SetContext(ctx, k, v)
+}
+
+// DelContext deletes the value from the primary.
+func (s *ReadReplicaStore) DelContext(ctx context.Context, k string) error {
+	return withContext(s.Primary).DelContext(ctx, k)
+}
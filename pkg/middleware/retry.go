@@ -0,0 +1,82 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"github.com/bingoohuang/gokv"
+	"time"
+)
+
+// RetryStore retries the failed operations of the inner store.
+type RetryStore struct {
+	Inner gokv.Store
+	// Attempts is the max number of the attempts of an operation.
+	Attempts int
+	// Interval is the interval between the attempts.
+	Interval time.Duration
+}
+
+// Retry creates a store which retries the failed operations of inner up to attempts times, waiting interval
+// between the attempts. gokv.ErrKeyNotFound is not retried, and the remaining attempts are aborted once
+// the context is done.
+func Retry(inner gokv.Store, attempts int, interval time.Duration) *RetryStore {
+	return &RetryStore{Inner: inner, Attempts: attempts, Interval: interval}
+}
+
+func (s *RetryStore) retry(ctx context.Context, f func() error) (err error) {
+	for i := 0; ; i++ {
+		if err = f(); err == nil || errors.Is(err, gokv.ErrKeyNotFound) || i+1 >= s.Attempts {
+			return err
+		}
+
+		t := time.NewTimer(s.Interval)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return ctx.Err()
+		case <-t.C:
+		}
+	}
+}
+
+// All returns the key values of the inner store.
+func (s *RetryStore) All() (map[string]string, error) { return s.AllContext(context.Background()) }
+
+// Set stores the value to the inner store.
+func (s *RetryStore) Set(k, v string) error { return s.SetContext(context.Background(), k, v) }
+
+// Get retrieves the value from the inner store.
+func (s *RetryStore) Get(k string) (string, error) { return s.GetContext(context.Background(), k) }
+
+// Del deletes the value from the inner store.
+func (s *RetryStore) Del(k string) error { return s.DelContext(context.Background(), k) }
+
+// AllContext returns the key values of the inner store.
+func (s *RetryStore) AllContext(ctx context.Context) (kvs map[string]string, err error) {
+	err = s.retry(ctx, func() (err error) {
+		kvs, err = withContext(s.Inner).AllContext(ctx)
+		return err
+	})
+
+	return kvs, err
+}
+
+// SetContext stores the value to the inner store.
+func (s *RetryStore) SetContext(ctx context.Context, k, v string) error {
+	return s.retry(ctx, func() error { return withContext(s.Inner).SetContext(ctx, k, v) })
+}
+
+// GetContext retrieves the value from the inner store.
+func (s *RetryStore) GetContext(ctx context.Context, k string) (v string, err error) {
+	err = s.retry(ctx, func() (err error) {
+		v, err = withContext(s.Inner).GetContext(ctx, k)
+		return err
+	})
+
+	return v, err
+}
+
+// DelContext deletes the value from the inner store.
+func (s *RetryStore) DelContext(ctx context.Context, k string) error {
+	return s.retry(ctx, func() error { return withContext(s.Inner).DelContext(ctx, k) })
+}
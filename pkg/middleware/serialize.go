@@ -0,0 +1,115 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"github.com/bingoohuang/gokv"
+	"sync"
+)
+
+// ErrWriterClosed is the error of the writes after the SerializedStore is closed.
+var ErrWriterClosed = errors.New("serialized writer is closed")
+
+// SerializedStore funnels the writes to the inner store through a single goroutine,
+// while the reads are passed to the inner store concurrently.
+type SerializedStore struct {
+	Inner gokv.Store
+
+	writes    chan writeRequest
+	quit      chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// SerializeWrites creates a store which serializes Set and Del of inner by a worker goroutine,
+// for the backends which are not safe for concurrent writes. Close must be called to stop the worker.
+func SerializeWrites(inner gokv.Store) *SerializedStore {
+	s := &SerializedStore{
+		Inner:  inner,
+		writes: make(chan writeRequest),
+		quit:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+
+	go s.work()
+
+	return s
+}
+
+func (s *SerializedStore) work() {
+	defer close(s.done)
+
+	for {
+		select {
+		case <-s.quit:
+			return
+		case r := <-s.writes:
+			r.result <- r.write()
+		}
+	}
+}
+
+type writeRequest struct {
+	write  func() error
+	result chan error
+}
+
+// write runs f by the worker, and gives up when ctx is done before the worker takes it.
+func (s *SerializedStore) write(ctx context.Context, f func() error) error {
+	r := writeRequest{write: f, result: make(chan error, 1)}
+
+	select {
+	case <-s.quit:
+		return ErrWriterClosed
+	case <-ctx.Done():
+		return ctx.Err()
+	case s.writes <- r:
+		return <-r.result
+	}
+}
+
+// All returns the key values of the inner store.
+func (s *SerializedStore) All() (map[string]string, error) { return s.AllContext(context.Background()) }
+
+// Get retrieves the value from the inner store.
+func (s *SerializedStore) Get(k string) (string, error) { return s.GetContext(context.Background(), k) }
+
+// Set stores the value to the inner store by the worker.
+func (s *SerializedStore) Set(k, v string) error { return s.SetContext(context.Background(), k, v) }
+
+// Del deletes the value from the inner store by the worker.
+func (s *SerializedStore) Del(k string) error { return s.DelContext(context.Background(), k) }
+
+// AllContext returns the key values of the inner store.
+func (s *SerializedStore) AllContext(ctx context.Context) (map[string]string, error) {
+	return withContext(s.Inner).AllContext(ctx)
+}
+
+// GetContext retrieves the value from the inner store.
+func (s *SerializedStore) GetContext(ctx context.Context, k string) (string, error) {
+	return withContext(s.Inner).GetContext(ctx, k)
+}
+
+// SetContext stores the value to the inner store by the worker.
+func (s *SerializedStore) SetContext(ctx context.Context, k, v string) error {
+	return s.write(ctx, func() error { return withContext(s.Inner).SetContext(ctx, k, v) })
+}
+
+// DelContext deletes the value from the inner store by the worker.
+func (s *SerializedStore) DelContext(ctx context.Context, k string) error {
+	return s.write(ctx, func() error { return withContext(s.Inner).DelContext(ctx, k) })
+}
+
+// Close stops the worker after the write in progress, and closes the inner store if it is a gokv.Closer.
+func (s *SerializedStore) Close() error {
+	s.closeOnce.Do(func() {
+		close(s.quit)
+		<-s.done
+	})
+
+	if c, ok := s.Inner.(gokv.Closer); ok {
+		return c.Close()
+	}
+
+	return nil
+}
@@ -0,0 +1,112 @@
+package middleware
+
+import (
+	"context"
+	"github.com/bingoohuang/gokv"
+	"go.uber.org/multierr"
+	"hash/fnv"
+	"sync"
+)
+
+// FNVHash is the default hash of the keys of Sharded by 32-bit FNV-1a.
+func FNVHash(k string) int {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(k))
+
+	return int(h.Sum32())
+}
+
+// ShardedStore routes each key to one of the shards by the hash of the key.
+type ShardedStore struct {
+	Shards []gokv.Store
+	HashFn func(key string) int
+}
+
+// Sharded creates a store which routes Set, Get and Del of a key to the shard of hashFn(key) modulo
+// the number of the shards, hashFn defaults to FNVHash, and All merges the key values of all the shards.
+// The shard of a key changes when the shards are added or removed, which requires a migration of the keys,
+// e.g. reading All of the old shards and setting them into the new ones.
+func Sharded(shards []gokv.Store, hashFn func(key string) int) *ShardedStore {
+	if hashFn == nil {
+		hashFn = FNVHash
+	}
+
+	return &ShardedStore{Shards: shards, HashFn: hashFn}
+}
+
+// Shard returns the shard of the key.
+func (s *ShardedStore) Shard(k string) gokv.Store {
+	i := s.HashFn(k) % len(s.Shards)
+	if i < 0 {
+		i += len(s.Shards)
+	}
+
+	return s.Shards[i]
+}
+
+// All returns the key values of all the shards, which are queried concurrently.
+func (s *ShardedStore) All() (map[string]string, error) { return s.AllContext(context.Background()) }
+
+// Set stores the value to the shard of the key.
+func (s *ShardedStore) Set(k, v string) error { return s.SetContext(context.Background(), k, v) }
+
+// Get retrieves the value from the shard of the key.
+func (s *ShardedStore) Get(k string) (string, error) { return s.GetContext(context.Background(), k) }
+
+// Del deletes the value from the shard of the key.
+func (s *ShardedStore) Del(k string) error { return s.DelContext(context.Background(), k) }
+
+// AllContext returns the key values of all the shards, which are queried concurrently.
+func (s *ShardedStore) AllContext(ctx context.Context) (map[string]string, error) {
+	results := make([]map[string]string, len(s.Shards))
+	errs := make([]error, len(s.Shards))
+
+	var wg sync.WaitGroup
+	for i, shard := range s.Shards {
+		wg.Add(1)
+		go func(i int, shard gokv.Store) {
+			defer wg.Done()
+			results[i], errs[i] = withContext(shard).AllContext(ctx)
+		}(i, shard)
+	}
+	wg.Wait()
+
+	if err := multierr.Combine(errs...); err != nil {
+		return nil, err
+	}
+
+	all := make(map[string]string)
+	for _, kvs := range results {
+		for k, v := range kvs {
+			all[k] = v
+		}
+	}
+
+	return all, nil
+}
+
+// SetContext stores the value to the shard of the key.
+func (s *ShardedStore) SetContext(ctx context.Context, k, v string) error {
+	return withContext(s.Shard(k)).SetContext(ctx, k, v)
+}
+
+// GetContext retrieves the value from the shard of the key.
+func (s *ShardedStore) GetContext(ctx context.Context, k string) (string, error) {
+	return withContext(s.Shard(k)).GetContext(ctx, k)
+}
+
+// DelContext deletes the value from the shard of the key.
+func (s *ShardedStore) DelContext(ctx context.Context, k string) error {
+	return withContext(s.Shard(k)).DelContext(ctx, k)
+}
+
+// Close closes the shards which are gokv.Closer.
+func (s *ShardedStore) Close() (err error) {
+	for _, shard := range s.Shards {
+		if c, ok := shard.(gokv.Closer); ok {
+			err = multierr.Append(err, c.Close())
+		}
+	}
+
+	return err
+}
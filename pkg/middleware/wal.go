@@ -0,0 +1,184 @@
+package middleware
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"github.com/bingoohuang/gokv"
+	"go.uber.org/multierr"
+	"io"
+	"sync"
+)
+
+// ErrCorruptWAL is the error of replaying a malformed record of the write-ahead log.
+var ErrCorruptWAL = errors.New("corrupt write-ahead log")
+
+const (
+	walSet byte = 'S'
+	walDel byte = 'D'
+	// walAbort marks the record before it as not applied, since the inner store failed it.
+	walAbort byte = 'A'
+)
+
+// MaxWALRecordSize is the max length of the body of a record, the larger mutations are refused by WALStore,
+// and the larger lengths are corrupt for ReplayWAL, instead of being allocated.
+const MaxWALRecordSize = 64 << 20
+
+// WALStore appends the mutations to a write-ahead log before applying them to the inner store.
+type WALStore struct {
+	Inner gokv.Store
+
+	// lock keeps the records in the order of the mutations applied.
+	lock sync.Mutex
+	w    io.Writer
+}
+
+// WithWAL creates a store which appends a record to w for each Set and Del before applying it to inner,
+// the mutation is not applied when the record fails to be written, and an abort record is appended after it
+// when inner fails the mutation, so that it is skipped by ReplayWAL.
+// Each record is a 4 bytes big-endian length of the body, which is the operation byte,
+// the uvarint length of the key, the key and the value.
+func WithWAL(inner gokv.Store, w io.Writer) *WALStore {
+	return &WALStore{Inner: inner, w: w}
+}
+
+// All returns the key values of the inner store.
+func (s *WALStore) All() (map[string]string, error) { return s.AllContext(context.Background()) }
+
+// Get retrieves the value from the inner store.
+func (s *WALStore) Get(k string) (string, error) { return s.GetContext(context.Background(), k) }
+
+// Set logs and stores the value to the inner store.
+func (s *WALStore) Set(k, v string) error { return s.SetContext(context.Background(), k, v) }
+
+// Del logs and deletes the value from the inner store.
+func (s *WALStore) Del(k string) error { return s.DelContext(context.Background(), k) }
+
+// AllContext returns the key values of the inner store.
+func (s *WALStore) AllContext(ctx context.Context) (map[string]string, error) {
+	return withContext(s.Inner).AllContext(ctx)
+}
+
+// GetContext retrieves the value from the inner store.
+func (s *WALStore) GetContext(ctx context.Context, k string) (string, error) {
+	return withContext(s.Inner).GetContext(ctx, k)
+}
+
+// SetContext logs and stores the value to the inner store.
+func (s *WALStore) SetContext(ctx context.Context, k, v string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if err := s.append(walSet, k, v); err != nil {
+		return err
+	}
+
+	return s.abortOnError(withContext(s.Inner).SetContext(ctx, k, v))
+}
+
+// DelContext logs and deletes the value from the inner store.
+func (s *WALStore) DelContext(ctx context.Context, k string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if err := s.append(walDel, k, ""); err != nil {
+		return err
+	}
+
+	return s.abortOnError(withContext(s.Inner).DelContext(ctx, k))
+}
+
+// abortOnError appends an abort record for the last record when err is not nil.
+func (s *WALStore) abortOnError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	return multierr.Append(err, s.append(walAbort, "", ""))
+}
+
+func (s *WALStore) append(op byte, k, v string) error {
+	if size := 1 + binary.MaxVarintLen64 + len(k) + len(v); size > MaxWALRecordSize {
+		return fmt.Errorf("record of key %s is %d bytes larger than MaxWALRecordSize", k, size)
+	}
+
+	record := make([]byte, 5+binary.MaxVarintLen64, 5+binary.MaxVarintLen64+len(k)+len(v))
+	record[4] = op
+	record = record[:5+binary.PutUvarint(record[5:], uint64(len(k)))]
+	record = append(append(record, k...), v...)
+	binary.BigEndian.PutUint32(record, uint32(len(record)-4))
+
+	// the record is written at once, so that the records are not interleaved by a partial write
+	_, err := s.w.Write(record)
+	return err
+}
+
+// ReplayWAL applies the records of the write-ahead log read from r to dst in order,
+// skipping the records aborted by the failures of the inner store.
+// io.ErrUnexpectedEOF is returned when the last record is truncated, e.g. by a crash while appending it,
+// and the records before it are applied.
+func ReplayWAL(r io.Reader, dst gokv.Store) error {
+	// pending is applied when the next record is known not to abort it
+	var pending []byte
+	applyPending := func() (err error) {
+		if pending != nil {
+			err, pending = replay(pending, dst), nil
+		}
+		return err
+	}
+
+	var header [4]byte
+	for {
+		if _, err := io.ReadFull(r, header[:]); err == io.EOF {
+			return applyPending()
+		} else if err != nil {
+			return multierr.Append(applyPending(), err)
+		}
+
+		size := binary.BigEndian.Uint32(header[:])
+		if size > MaxWALRecordSize {
+			return multierr.Append(applyPending(), fmt.Errorf("record length %d, error:%w", size, ErrCorruptWAL))
+		}
+
+		body := make([]byte, size)
+		if _, err := io.ReadFull(r, body); err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return multierr.Append(applyPending(), err)
+		}
+
+		if len(body) > 0 && body[0] == walAbort {
+			pending = nil
+			continue
+		}
+
+		if err := applyPending(); err != nil {
+			return err
+		}
+		pending = body
+	}
+}
+func replay(body []byte, dst gokv.Store) error {
+	if len(body) == 0 {
+		return ErrCorruptWAL
+	}
+
+	keyLen, n := binary.Uvarint(body[1:])
+	if n <= 0 || keyLen > uint64(len(body)-1-n) {
+		return ErrCorruptWAL
+	}
+
+	kv := body[1+n:]
+	k, v := string(kv[:keyLen]), string(kv[keyLen:])
+
+	switch body[0] {
+	case walSet:
+		return dst.Set(k, v)
+	case walDel:
+		return dst.Del(k)
+	default:
+		return fmt.Errorf("unknown operation %q, error:%w", body[0], ErrCorruptWAL)
+	}
+}
@@ -0,0 +1,17 @@
+package securebolt
+
+import "go.etcd.io/bbolt"
+
+// FailOpen fails the openings of the database file at path with err until the returned restore is called.
+func FailOpen(path string, err error) (restore func()) {
+	open := openDB
+	openDB = func(p string) (*bbolt.DB, error) {
+		if p == path {
+			return nil, err
+		}
+
+		return open(p)
+	}
+
+	return func() { openDB = open }
+}
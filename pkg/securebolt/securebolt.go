@@ -0,0 +1,374 @@
+// Package securebolt provides a gokv.Store over a bbolt database file whose values are encrypted by AES-GCM.
+package securebolt
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"github.com/bingoohuang/gokv"
+	"github.com/bingoohuang/gokv/pkg/codec"
+	"go.etcd.io/bbolt"
+	"go.uber.org/multierr"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+// ErrWrongKey is the error of opening the store with another master key than the one it is created with.
+var ErrWrongKey = errors.New("wrong master key")
+
+// ErrStoreClosed is the error of the operations after Compact failed to reopen the database file.
+var ErrStoreClosed = errors.New("securebolt store is closed")
+
+var (
+	metaBucket = []byte("meta")
+	kvBucket   = []byte("kv")
+	saltKey    = []byte("salt")
+	checkKey   = []byte("check")
+)
+
+// checkValue is encrypted in the meta bucket to verify the master key on opening.
+const checkValue = "securebolt"
+
+// Store is a gokv.Store over an encrypted bbolt database.
+// The keys are stored in plaintext, while each value is encrypted with its own random nonce
+// by the data encryption key, which is derived from the master key and a random salt of the database.
+type Store struct {
+	Options
+
+	path  string
+	codec codec.Codec
+
+	// lock guards db, which is reopened by Compact, and is nil with closedErr when the reopening fails.
+	lock      sync.RWMutex
+	db        *bbolt.DB
+	closedErr error
+
+	stop      chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// Logger logs the errors of the background compaction, e.g. *log.Logger.
+type Logger interface {
+	Printf(format string, v ...interface{})
+}
+
+// Options are the options of the store.
+type Options struct {
+	// CompactInterval compacts the database file in every CompactInterval when it is positive.
+	CompactInterval time.Duration
+	// Logger logs the errors of the background compaction, default to the standard logger of the log package.
+	Logger Logger
+}
+
+var _ gokv.Compactable = (*Store)(nil)
+
+// Open opens or creates the store of the bbolt database file at path, encrypted by the masterKey.
+// ErrWrongKey is returned when the store is created with another master key.
+func Open(path string, masterKey []byte) (*Store, error) {
+	return OpenOptions(path, masterKey, Options{})
+}
+
+// OpenOptions opens the store as Open with the options, Close must be called to stop the background compaction.
+func OpenOptions(path string, masterKey []byte, options Options) (s *Store, er error) {
+	db, err := openDB(path)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		if er != nil {
+			er = multierr.Append(er, db.Close())
+		}
+	}()
+
+	if options.Logger == nil {
+		options.Logger = log.Default()
+	}
+
+	s = &Store{Options: options, path: path, db: db}
+	err = db.Update(func(tx *bbolt.Tx) error {
+		if _, err := tx.CreateBucketIfNotExists(kvBucket); err != nil {
+			return err
+		}
+
+		meta, err := tx.CreateBucketIfNotExists(metaBucket)
+		if err != nil {
+			return err
+		}
+
+		salt := meta.Get(saltKey)
+		if salt == nil {
+			salt = make([]byte, 16)
+			if _, err := rand.Read(salt); err != nil {
+				return err
+			}
+			if err := meta.Put(saltKey, salt); err != nil {
+				return err
+			}
+		}
+
+		if s.codec, err = codec.NewAESGCM(codec.JSON, deriveKey(masterKey, salt)); err != nil {
+			return err
+		}
+
+		if check := meta.Get(checkKey); check != nil {
+			var v string
+			if err := s.codec.Unmarshal(check, &v); err != nil || v != checkValue {
+				return ErrWrongKey
+			}
+
+			return nil
+		}
+
+		check, err := s.codec.Marshal(checkValue)
+		if err != nil {
+			return err
+		}
+
+		return meta.Put(checkKey, check)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if s.CompactInterval > 0 {
+		s.stop = make(chan struct{})
+		s.done = make(chan struct{})
+
+		go s.tickerCompact()
+	}
+
+	return s, nil
+}
+
+// openDB opens the bbolt database file, which is replaced by the tests to fail the reopening of Compact.
+var openDB = func(path string) (*bbolt.DB, error) {
+	return bbolt.Open(path, 0600, &bbolt.Options{Timeout: time.Second})
+}
+
+func (s *Store) tickerCompact() {
+	defer close(s.done)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go func() {
+		select {
+		case <-s.stop:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	ticker := time.NewTicker(s.CompactInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+			if err := s.Compact(ctx); err != nil {
+				s.Logger.Printf("W! compact error %v", err)
+			}
+		}
+	}
+}
+
+// Compact rewrites the database into a new file to reclaim the free pages of the deleted and overwritten values,
+// and replaces the database file with it. The operations are blocked while compacting.
+// The operations fail with ErrStoreClosed when the database file fails to be reopened after it is closed,
+// and the store should be opened again.
+func (s *Store) Compact(ctx context.Context) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.db == nil {
+		return s.closedErr
+	}
+
+	tmp := s.path + ".compact"
+	dst, err := openDB(tmp)
+	if err != nil {
+		return err
+	}
+
+	if err := copyBuckets(ctx, dst, s.db); err != nil {
+		return multierr.Combine(err, dst.Close(), os.Remove(tmp))
+	}
+	if err := dst.Close(); err != nil {
+		return multierr.Append(err, os.Remove(tmp))
+	}
+
+	if err := s.db.Close(); err != nil {
+		return multierr.Append(err, os.Remove(tmp))
+	}
+
+	// the original file is reopened when the compacted one fails to replace it
+	renameErr := os.Rename(tmp, s.path)
+	if renameErr != nil {
+		renameErr = multierr.Append(renameErr, os.Remove(tmp))
+	}
+
+	if s.db, err = openDB(s.path); err != nil {
+		s.db, s.closedErr = nil, fmt.Errorf("reopen %s: %v, error:%w", s.path, err, ErrStoreClosed)
+	}
+
+	return multierr.Append(renameErr, err)
+}
+
+// copyBuckets copies the top level buckets of src into dst, which have no nested buckets.
+func copyBuckets(ctx context.Context, dst, src *bbolt.DB) error {
+	return src.View(func(stx *bbolt.Tx) error {
+		return dst.Update(func(dtx *bbolt.Tx) error {
+			return stx.ForEach(func(name []byte, sb *bbolt.Bucket) error {
+				b, err := dtx.CreateBucket(name)
+				if err != nil {
+					return err
+				}
+
+				// the keys are put in order, so the pages are filled up
+				b.FillPercent = 1
+
+				return sb.ForEach(func(k, v []byte) error {
+					if err := ctx.Err(); err != nil {
+						return err
+					}
+
+					return b.Put(k, v)
+				})
+			})
+		})
+	})
+}
+
+// deriveKey derives the 256 bits data encryption key from the master key and the salt by HMAC-SHA256.
+func deriveKey(masterKey, salt []byte) []byte {
+	mac := hmac.New(sha256.New, masterKey)
+	mac.Write(salt)
+
+	return mac.Sum(nil)
+}
+
+// All decrypts all the key values in the store.
+func (s *Store) All() (map[string]string, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	if s.db == nil {
+		return nil, s.closedErr
+	}
+
+	m := make(map[string]string)
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		return tx.Bucket(kvBucket).ForEach(func(k, data []byte) error {
+			var v string
+			if err := s.codec.Unmarshal(data, &v); err != nil {
+				return err
+			}
+
+			m[string(k)] = v
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+// Keys list the keys in the store.
+func (s *Store) Keys() (keys []string, err error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	if s.db == nil {
+		return nil, s.closedErr
+	}
+
+	err = s.db.View(func(tx *bbolt.Tx) error {
+		return tx.Bucket(kvBucket).ForEach(func(k, _ []byte) error {
+			keys = append(keys, string(k))
+			return nil
+		})
+	})
+
+	return keys, err
+}
+
+// Set encrypts and stores the value for the given key.
+func (s *Store) Set(k, v string) error {
+	data, err := s.codec.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	if s.db == nil {
+		return s.closedErr
+	}
+
+	return s.db.Update(func(tx *bbolt.Tx) error { return tx.Bucket(kvBucket).Put([]byte(k), data) })
+}
+
+// Get retrieves and decrypts the value for the given key,
+// gokv.ErrKeyNotFound is returned when the key does not exist.
+func (s *Store) Get(k string) (v string, err error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	if s.db == nil {
+		return "", s.closedErr
+	}
+
+	err = s.db.View(func(tx *bbolt.Tx) error {
+		data := tx.Bucket(kvBucket).Get([]byte(k))
+		if data == nil {
+			return gokv.ErrKeyNotFound
+		}
+
+		return s.codec.Unmarshal(data, &v)
+	})
+
+	return v, err
+}
+
+// Del deletes the stored value for the given key.
+func (s *Store) Del(k string) error {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	if s.db == nil {
+		return s.closedErr
+	}
+
+	return s.db.Update(func(tx *bbolt.Tx) error { return tx.Bucket(kvBucket).Delete([]byte(k)) })
+}
+
+// Close stops the background compaction, and closes the database file.
+func (s *Store) Close() error {
+	s.closeOnce.Do(func() {
+		if s.stop != nil {
+			close(s.stop)
+			<-s.done
+		}
+	})
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.db == nil {
+		return nil
+	}
+
+	return s.db.Close()
+}
@@ -0,0 +1,156 @@
+package securebolt_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/bingoohuang/gokv"
+	"github.com/bingoohuang/gokv/pkg/securebolt"
+	"github.com/bingoohuang/gokv/pkg/storetest"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+var masterKey = []byte("the master key")
+
+func TestStore(t *testing.T) {
+	s, err := securebolt.Open(filepath.Join(t.TempDir(), "kv.db"), masterKey)
+	assert.Nil(t, err)
+	defer s.Close()
+
+	storetest.TestStore(t, s)
+}
+
+func TestEncryption(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "kv.db")
+
+	s, err := securebolt.Open(path, masterKey)
+	assert.Nil(t, err)
+	assert.Nil(t, s.Set("password", "top-secret-value"))
+	assert.Nil(t, s.Close())
+
+	data, err := os.ReadFile(path)
+	assert.Nil(t, err)
+	assert.True(t, bytes.Contains(data, []byte("password")), "the keys are in plaintext")
+	assert.False(t, bytes.Contains(data, []byte("top-secret-value")), "the values should be ciphertext")
+
+	s, err = securebolt.Open(path, masterKey)
+	assert.Nil(t, err)
+
+	v, err := s.Get("password")
+	assert.Nil(t, err)
+	assert.Equal(t, "top-secret-value", v)
+
+	all, err := s.All()
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"password": "top-secret-value"}, all)
+	assert.Nil(t, s.Close())
+
+	_, err = securebolt.Open(path, []byte("another key"))
+	assert.Equal(t, securebolt.ErrWrongKey, err)
+
+	// the file is not locked after the failed opening
+	s, err = securebolt.Open(path, masterKey)
+	assert.Nil(t, err)
+	assert.Nil(t, s.Close())
+}
+
+func TestCompact(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "kv.db")
+
+	s, err := securebolt.Open(path, masterKey)
+	assert.Nil(t, err)
+	defer s.Close()
+
+	value := strings.Repeat("v", 10000)
+	for i := 0; i < 100; i++ {
+		assert.Nil(t, s.Set(fmt.Sprintf("k%d", i), value))
+	}
+	for i := 1; i < 100; i++ {
+		assert.Nil(t, s.Del(fmt.Sprintf("k%d", i)))
+	}
+
+	before := fileSize(t, path)
+	assert.Nil(t, gokv.Compact(context.Background(), s))
+	assert.Less(t, fileSize(t, path), before)
+
+	all, err := s.All()
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"k0": value}, all)
+
+	assert.Nil(t, s.Set("k1", "v1"))
+	assert.Nil(t, s.Close())
+
+	s, err = securebolt.Open(path, masterKey)
+	assert.Nil(t, err)
+
+	v, err := s.Get("k1")
+	assert.Nil(t, err)
+	assert.Equal(t, "v1", v)
+}
+
+func TestCompactReopenFailed(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "kv.db")
+
+	s, err := securebolt.Open(path, masterKey)
+	assert.Nil(t, err)
+	assert.Nil(t, s.Set("k", "v"))
+
+	errOpen := errors.New("open failed")
+	restore := securebolt.FailOpen(path, errOpen)
+	err = gokv.Compact(context.Background(), s)
+	restore()
+	assert.True(t, errors.Is(err, errOpen))
+
+	// the operations fail instead of panicking on the database not reopened
+	_, err = s.Get("k")
+	assert.True(t, errors.Is(err, securebolt.ErrStoreClosed))
+	assert.True(t, errors.Is(s.Set("k", "v2"), securebolt.ErrStoreClosed))
+	assert.True(t, errors.Is(s.Del("k"), securebolt.ErrStoreClosed))
+	_, err = s.All()
+	assert.True(t, errors.Is(err, securebolt.ErrStoreClosed))
+	_, err = s.Keys()
+	assert.True(t, errors.Is(err, securebolt.ErrStoreClosed))
+	assert.True(t, errors.Is(gokv.Compact(context.Background(), s), securebolt.ErrStoreClosed))
+	assert.Nil(t, s.Close())
+
+	// the compacted file is kept
+	s, err = securebolt.Open(path, masterKey)
+	assert.Nil(t, err)
+	defer s.Close()
+
+	v, err := s.Get("k")
+	assert.Nil(t, err)
+	assert.Equal(t, "v", v)
+}
+
+func TestCompactInterval(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "kv.db")
+
+	s, err := securebolt.OpenOptions(path, masterKey, securebolt.Options{CompactInterval: 10 * time.Millisecond})
+	assert.Nil(t, err)
+	defer s.Close()
+
+	value := strings.Repeat("v", 10000)
+	for i := 0; i < 100; i++ {
+		assert.Nil(t, s.Set(fmt.Sprintf("k%d", i), value))
+	}
+	for i := 0; i < 100; i++ {
+		assert.Nil(t, s.Del(fmt.Sprintf("k%d", i)))
+	}
+
+	before := fileSize(t, path)
+	assert.Eventually(t, func() bool { return fileSize(t, path) < before }, time.Second, 10*time.Millisecond)
+}
+
+func fileSize(t *testing.T, path string) int64 {
+	fi, err := os.Stat(path)
+	assert.Nil(t, err)
+
+	return fi.Size()
+}
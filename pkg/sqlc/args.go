@@ -0,0 +1,57 @@
+package sqlc
+
+import (
+	"fmt"
+	"strings"
+)
+
+// setArgs returns the names of the fields bound as the arguments of SetSQL.
+func (c *Client) setArgs() []string {
+	if names, ok := c.ArgBindings["SetSQL"]; ok {
+		return names
+	}
+
+	return c.SetArgs
+}
+
+// renderArgs renders the SQL template of the name, whose arguments are the fields of data named by ArgBindings
+// when they are set. The SQL without template actions is used as it is.
+func (c *Client) renderArgs(name, sqlTemplate string, data map[string]interface{}) (
+	query string, args []interface{}, err error) {
+	names := c.ArgBindings[name]
+	if len(names) == 0 {
+		return c.render(sqlTemplate, data)
+	}
+
+	query = sqlTemplate
+	if strings.Contains(sqlTemplate, "{{") {
+		if query, args, err = c.render(sqlTemplate, data); err != nil {
+			return "", nil, err
+		}
+	}
+
+	if args, err = bindArgs(name, names, args, data); err != nil {
+		return "", nil, err
+	}
+
+	return query, args, nil
+}
+
+// bindArgs returns the fields of data named by names as the bound arguments of the SQL of the name.
+func bindArgs(name string, names []string, templateArgs []interface{}, data map[string]interface{}) (
+	[]interface{}, error) {
+	if len(templateArgs) > 0 {
+		return nil, fmt.Errorf("%s should not bind the arguments by {{arg}} with ArgBindings or SetArgs", name)
+	}
+
+	args := make([]interface{}, len(names))
+	for i, field := range names {
+		v, ok := data[field]
+		if !ok {
+			return nil, fmt.Errorf("unknown field %q bound to %s", field, name)
+		}
+		args[i] = v
+	}
+
+	return args, nil
+}
@@ -0,0 +1,47 @@
+package sqlc_test
+
+import (
+	"github.com/bingoohuang/gokv/pkg/sqlc"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestArgBindings(t *testing.T) {
+	client := sqlc.NewClient(sqlc.Config{
+		DataSourceName: startTestServer(t) + "?interpolateParams=true",
+		GetSQL:         "select v from kv where k = ? and state = 1",
+		SetSQL:         "insert into kv(k, v, state, updated, created) values(?, ?, 1, ?, ?)",
+		DelSQL:         "delete from kv where k = ?",
+		ArgBindings: map[string][]string{
+			"GetSQL": {"Key"},
+			"SetSQL": {"Key", "Value", "Time", "Time"},
+			"DelSQL": {"Key"},
+		},
+	})
+	defer client.Close()
+
+	for k, v := range map[string]string{
+		"k'1":  "it's' or '1'='1",
+		`k\2`:  `C:\temp\' --`,
+		"键3":   "值 ✓ \"quoted\"",
+		"k'; ": "'; drop table kv; --",
+	} {
+		assert.Nil(t, client.Set(k, v))
+		client.Invalidate(k)
+
+		found, got, _, err := client.Get(k, nil)
+		assert.Nil(t, err)
+		assert.True(t, found, k)
+		assert.Equal(t, v, got)
+
+		assert.Nil(t, client.Del(k))
+		found, _, _, err = client.Get(k, nil)
+		assert.Nil(t, err)
+		assert.False(t, found, k)
+	}
+
+	found, v, _, err := client.Get("Key1", nil)
+	assert.Nil(t, err)
+	assert.True(t, found)
+	assert.Equal(t, `"value1"`, v, "the other rows are intact")
+}
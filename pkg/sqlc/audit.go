@@ -0,0 +1,57 @@
+package sqlc
+
+import (
+	"context"
+	"time"
+)
+
+// AuditSink records the mutations of the key values, e.g. to a tamper-evident audit trail.
+type AuditSink interface {
+	// Record records the mutation op ("set", "del", "merge" or "touch") of the key k from oldV to newV
+	// at the time at, the value of "touch" is unchanged.
+	Record(op string, k, oldV, newV string, at time.Time)
+}
+
+// priorValue returns the value of the key before a mutation for the AuditSink,
+// from the cache, or from the database in the AuditPreRead mode.
+func (c *Client) priorValue(ctx context.Context, k string) (string, error) {
+	if c.AuditSink == nil {
+		return "", nil
+	}
+
+	c.cacheLock.Lock()
+	cv, ok := c.Cache.Get(k)
+	c.cacheLock.Unlock()
+
+	if ok || !c.AuditPreRead {
+		return cv.Value, nil
+	}
+
+	_, v, _, err := c.get(ctx, k)
+
+	return v, err
+}
+
+// priorValues returns the values of the keys before a mutation for the AuditSink like priorValue.
+func (c *Client) priorValues(ctx context.Context, keys []string) (map[string]string, error) {
+	if c.AuditSink == nil {
+		return nil, nil
+	}
+
+	values := make(map[string]string, len(keys))
+	for _, k := range keys {
+		v, err := c.priorValue(ctx, k)
+		if err != nil {
+			return nil, err
+		}
+		values[k] = v
+	}
+
+	return values, nil
+}
+
+func (c *Client) audit(op, k, oldV, newV string) {
+	if c.AuditSink != nil {
+		c.AuditSink.Record(op, k, oldV, newV, c.Now())
+	}
+}
@@ -0,0 +1,54 @@
+package sqlc_test
+
+import (
+	"github.com/bingoohuang/gokv/pkg/sqlc"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+type auditRecord struct {
+	Op, Key, Old, New string
+}
+
+type recordingSink struct {
+	records []auditRecord
+}
+
+func (s *recordingSink) Record(op string, k, oldV, newV string, _ time.Time) {
+	s.records = append(s.records, auditRecord{Op: op, Key: k, Old: oldV, New: newV})
+}
+
+func TestAuditSink(t *testing.T) {
+	dsn := startTestServer(t)
+
+	sink := &recordingSink{}
+	client := sqlc.NewClient(sqlc.Config{
+		DataSourceName: dsn,
+		SetSQL:         updateSetSQL,
+		AuditSink:      sink,
+		AuditPreRead:   true,
+	})
+	defer client.Close()
+
+	assert.Nil(t, client.Set("Key1", "updated1"))
+	assert.Nil(t, client.Set("Key1", "updated2"))
+	assert.Nil(t, client.Del("Key1"))
+	assert.Nil(t, client.SetMany(map[string]string{"Key2": "many2", "Key3": "many3"}))
+
+	assert.Equal(t, []auditRecord{
+		{Op: "set", Key: "Key1", Old: `"value1"`, New: "updated1"},
+		{Op: "set", Key: "Key1", Old: "updated1", New: "updated2"},
+		{Op: "del", Key: "Key1", Old: "updated2"},
+		{Op: "set", Key: "Key2", Old: `"value2"`, New: "many2"},
+		{Op: "set", Key: "Key3", Old: `"value3"`, New: "many3"},
+	}, sink.records)
+
+	// without the pre-read, the prior values of the keys not cached are empty
+	sink = &recordingSink{}
+	client = sqlc.NewClient(sqlc.Config{DataSourceName: dsn, SetSQL: updateSetSQL, AuditSink: sink})
+	defer client.Close()
+
+	assert.Nil(t, client.Set("Key2", "updated"))
+	assert.Equal(t, []auditRecord{{Op: "set", Key: "Key2", New: "updated"}}, sink.records)
+}
@@ -0,0 +1,60 @@
+package sqlc
+
+import (
+	"math"
+	"math/rand"
+	"time"
+)
+
+// Backoff decides the delays between the retries.
+type Backoff interface {
+	// Next returns the delay before the attempt-th retry, starting from 1.
+	Next(attempt int) time.Duration
+}
+
+// ConstantBackoff delays the retries by the same duration.
+type ConstantBackoff time.Duration
+
+// Next returns the constant delay.
+func (b ConstantBackoff) Next(int) time.Duration { return time.Duration(b) }
+
+// ExponentialBackoff delays the retries by Base * Factor^(attempt-1), up to Max if it is positive.
+type ExponentialBackoff struct {
+	Base time.Duration
+	Max  time.Duration
+	// Factor is the multiplier of the delays, default to 2.
+	Factor float64
+}
+
+// Next returns the exponential delay of the attempt.
+func (b ExponentialBackoff) Next(attempt int) time.Duration {
+	factor := b.Factor
+	if factor <= 0 {
+		factor = 2
+	}
+
+	d := float64(b.Base) * math.Pow(factor, float64(attempt-1))
+	if b.Max > 0 && d > float64(b.Max) {
+		return b.Max
+	}
+
+	return time.Duration(d)
+}
+
+// JitteredBackoff randomizes the delays of the Backoff in [0, delay) to spread the retries of the clients,
+// which is known as the full jitter.
+type JitteredBackoff struct {
+	Backoff Backoff
+	// Rand returns a random number in [0, 1), default to rand.Float64.
+	Rand func() float64
+}
+
+// Next returns the jittered delay of the attempt.
+func (b JitteredBackoff) Next(attempt int) time.Duration {
+	r := b.Rand
+	if r == nil {
+		r = rand.Float64
+	}
+
+	return time.Duration(r() * float64(b.Backoff.Next(attempt)))
+}
@@ -0,0 +1,61 @@
+package sqlc_test
+
+import (
+	"database/sql/driver"
+	"errors"
+	"github.com/bingoohuang/gokv/pkg/sqlc"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func delays(b sqlc.Backoff, attempts int) (ds []time.Duration) {
+	for i := 1; i <= attempts; i++ {
+		ds = append(ds, b.Next(i))
+	}
+
+	return ds
+}
+
+func TestBackoff(t *testing.T) {
+	ms := time.Millisecond
+
+	assert.Equal(t, []time.Duration{10 * ms, 10 * ms, 10 * ms}, delays(sqlc.ConstantBackoff(10*ms), 3))
+
+	exponential := sqlc.ExponentialBackoff{Base: 10 * ms, Max: 50 * ms}
+	assert.Equal(t, []time.Duration{10 * ms, 20 * ms, 40 * ms, 50 * ms, 50 * ms}, delays(exponential, 5))
+
+	triple := sqlc.ExponentialBackoff{Base: 10 * ms, Factor: 3}
+	assert.Equal(t, []time.Duration{10 * ms, 30 * ms, 90 * ms}, delays(triple, 3))
+
+	jittered := sqlc.JitteredBackoff{Backoff: exponential, Rand: func() float64 { return 0.5 }}
+	assert.Equal(t, []time.Duration{5 * ms, 10 * ms, 20 * ms, 25 * ms}, delays(jittered, 4))
+
+	for i, d := range delays(sqlc.JitteredBackoff{Backoff: exponential}, 5) {
+		assert.True(t, d >= 0 && d < exponential.Next(i+1))
+	}
+}
+
+type recordingBackoff struct {
+	attempts []int
+}
+
+func (b *recordingBackoff) Next(attempt int) time.Duration {
+	b.attempts = append(b.attempts, attempt)
+	return time.Millisecond
+}
+
+func TestRetryBackoff(t *testing.T) {
+	d, driverName := newFakeDriver()
+	d.Query = func(string, []driver.NamedValue) ([]string, [][]driver.Value, error) {
+		return nil, nil, errors.New("broken connection")
+	}
+
+	backoff := &recordingBackoff{}
+	client := sqlc.NewClient(sqlc.Config{DriverName: driverName, Retries: 3, Backoff: backoff})
+	defer client.Close()
+
+	_, _, _, err := client.Get("k", nil)
+	assert.NotNil(t, err)
+	assert.Equal(t, []int{1, 2, 3}, backoff.attempts)
+}
@@ -0,0 +1,167 @@
+package sqlc
+
+import (
+	"context"
+	"fmt"
+	"go.uber.org/multierr"
+	"sort"
+	"sync"
+	"time"
+)
+
+// batchResult is the result of a key in a batch of Gets.
+type batchResult struct {
+	found     bool
+	v         string
+	rawOption []byte
+	err       error
+}
+
+// keyBatch is a batch of the pending Gets, keyed by the keys to their waiters.
+type keyBatch struct {
+	waiters map[string][]chan batchResult
+	once    sync.Once
+}
+
+// getBatcher coalesces the concurrent Gets of the BatchWindow into the queries of GetBatchSQL.
+type getBatcher struct {
+	c *Client
+
+	lock    sync.Mutex
+	current *keyBatch
+}
+
+// get adds the key to the current batch, which is queried after the BatchWindow,
+// or as soon as it has BatchMaxKeys keys, and waits for the result of the key.
+// The waiting is abandoned when the ctx is done, while the batch is still queried for the other waiters.
+func (b *getBatcher) get(ctx context.Context, k string) (found bool, v string, rawOption []byte, err error) {
+	result := make(chan batchResult, 1)
+
+	b.lock.Lock()
+	batch := b.current
+	if batch == nil {
+		batch = &keyBatch{waiters: make(map[string][]chan batchResult)}
+		b.current = batch
+		time.AfterFunc(b.c.BatchWindow, func() { b.flush(batch) })
+	}
+	batch.waiters[k] = append(batch.waiters[k], result)
+	full := len(batch.waiters) >= b.c.BatchMaxKeys
+	b.lock.Unlock()
+
+	if full {
+		go b.flush(batch)
+	}
+
+	select {
+	case r := <-result:
+		return r.found, r.v, r.rawOption, r.err
+	case <-ctx.Done():
+		return false, "", nil, ctx.Err()
+	}
+}
+
+// flush queries the batch once, and distributes the results to the waiters.
+func (b *getBatcher) flush(batch *keyBatch) {
+	batch.once.Do(func() {
+		b.lock.Lock()
+		if b.current == batch {
+			b.current = nil
+		}
+		b.lock.Unlock()
+
+		// the keys are grouped by the data source names for ReadYourWrites
+		groups := make(map[string][]string)
+		for k := range batch.waiters {
+			dsn := b.c.readDataSourceName(k)
+			groups[dsn] = append(groups[dsn], k)
+		}
+
+		for dsn, keys := range groups {
+			sort.Strings(keys)
+			results, err := b.c.getBatch(dsn, keys)
+			for _, k := range keys {
+				r := results[k]
+				if err != nil {
+					r = batchResult{err: err}
+				}
+				for _, w := range batch.waiters[k] {
+					w <- r
+				}
+			}
+		}
+	})
+}
+
+// getBatch queries the values and the raw options of the keys by GetBatchSQL, the missing keys are absent.
+func (c *Client) getBatch(dataSourceName string, keys []string) (results map[string]batchResult, er error) {
+	defer c.metrics.observe("getbatch", time.Now())
+
+	backendKeys := make([]string, len(keys))
+	for i, k := range keys {
+		backendKeys[i] = c.backendKey(k)
+	}
+
+	query, args, err := c.render(c.GetBatchSQL, map[string]interface{}{"Keys": backendKeys})
+	if err != nil {
+		return nil, err
+	}
+	c.logQuery(query)
+
+	db, err := c.openDB(dataSourceName)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), c.QueryTimeout)
+	defer cancel()
+
+	rows, release, err := c.queryContext(ctx, db, "getbatch", "", query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() { er = multierr.Combine(er, drainAndClose(rows), release()) }()
+
+	cols, _ := rows.Columns()
+	if len(cols) < 2 {
+		return nil, fmt.Errorf("GetBatchSQL selects %d columns instead of the key and the columns of GetSQL", len(cols))
+	}
+	if err := c.checkColumns("GetBatchSQL", cols[1:]); err != nil {
+		return nil, err
+	}
+
+	// the rows are decoded like the ones of GetSQL, except for the leading key column
+	results = make(map[string]batchResult, len(keys))
+	for rows.Next() {
+		columns, err := c.scanColumns(rows, len(cols))
+		if err != nil {
+			return nil, err
+		}
+
+		k := c.logicalKey(columns[0].String)
+		if r, ok := results[k]; ok && r.err == nil {
+			switch c.MultiValuePolicy {
+			case MultiValueFirst:
+				continue
+			case MultiValueLast:
+			default:
+				results[k] = batchResult{err: fmt.Errorf("key:%s, error:%w", k, ErrTooManyValues)}
+				continue
+			}
+		} else if ok {
+			continue
+		}
+
+		r := batchResult{found: true}
+		if r.v, r.rawOption, r.err = c.decodeValue(k, cols[1:], columns[1:]); r.err == nil &&
+			c.RequireOption && r.rawOption == nil {
+			r.err = fmt.Errorf("key:%s, error:%w", k, ErrOptionRequired)
+		}
+		if r.err != nil {
+			r = batchResult{err: r.err}
+		}
+		results[k] = r
+	}
+
+	return results, nil
+}
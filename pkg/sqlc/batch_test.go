@@ -0,0 +1,108 @@
+package sqlc_test
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"github.com/bingoohuang/gokv/pkg/sqlc"
+	"github.com/stretchr/testify/assert"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestBatchWindow(t *testing.T) {
+	d, driverName := newFakeDriver()
+	d.Query = func(query string, args []driver.NamedValue) ([]string, [][]driver.Value, error) {
+		var rows [][]driver.Value
+		for _, arg := range args {
+			if k := arg.Value.(string); k != "missing" {
+				rows = append(rows, []driver.Value{k, "v-" + k})
+			}
+		}
+		return []string{"k", "v"}, rows, nil
+	}
+
+	client := sqlc.NewClient(sqlc.Config{DriverName: driverName, BatchWindow: 50 * time.Millisecond})
+	defer client.Close()
+
+	var wg sync.WaitGroup
+	values := make([]string, 50)
+	for i := range values {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, values[i], _, _ = client.Get(fmt.Sprintf("k%d", i), nil)
+		}(i)
+	}
+	wg.Wait()
+
+	assert.Equal(t, 1, d.StatementCount(), "the concurrent Gets are served by one query")
+	for i, v := range values {
+		assert.Equal(t, fmt.Sprintf("v-k%d", i), v)
+	}
+
+	found, _, _, err := client.Get("missing", nil)
+	assert.Nil(t, err)
+	assert.False(t, found)
+	assert.Equal(t, 2, d.StatementCount())
+}
+
+func TestBatchMaxKeys(t *testing.T) {
+	d, driverName := newFakeDriver()
+	d.Query = func(query string, args []driver.NamedValue) ([]string, [][]driver.Value, error) {
+		return []string{"k", "v"}, [][]driver.Value{{args[0].Value, "v"}}, nil
+	}
+
+	client := sqlc.NewClient(sqlc.Config{DriverName: driverName, BatchWindow: time.Hour, BatchMaxKeys: 1})
+	defer client.Close()
+
+	found, v, _, err := client.Get("k1", nil)
+	assert.Nil(t, err)
+	assert.True(t, found)
+	assert.Equal(t, "v", v, "the full batch is queried without waiting for the window")
+}
+
+func TestBatchDecoding(t *testing.T) {
+	d, driverName := newFakeDriver()
+	d.Query = func(string, []driver.NamedValue) ([]string, [][]driver.Value, error) {
+		return []string{"k", "o", "value"}, [][]driver.Value{
+			{"k1", `{"TTL":0,"NoCache":false}`, "v1"},
+			{"k2", `{"TTL":0,"NoCache":false}`, "v2"},
+			{"k2", `{"TTL":0,"NoCache":false}`, "v2'"},
+			{"k3", nil, "v3"},
+		}, nil
+	}
+
+	client := sqlc.NewClient(sqlc.Config{
+		DriverName:    driverName,
+		BatchWindow:   20 * time.Millisecond,
+		GetBatchSQL:   "select k, o, value from kv where k in ({{range $i, $k := .Keys}}{{if $i}}, {{end}}{{arg $k}}{{end}})",
+		ValueColumn:   "value",
+		RequireOption: true,
+	})
+	defer client.Close()
+
+	var wg sync.WaitGroup
+	errs := make(map[string]error)
+	var lock sync.Mutex
+	for _, k := range []string{"k1", "k2", "k3"} {
+		wg.Add(1)
+		go func(k string) {
+			defer wg.Done()
+			_, v, _, err := client.Get(k, nil)
+			if k == "k1" {
+				assert.Equal(t, "v1", v, "the ValueColumn is decoded")
+			}
+			lock.Lock()
+			errs[k] = err
+			lock.Unlock()
+		}(k)
+	}
+	wg.Wait()
+
+	assert.Equal(t, 1, d.StatementCount())
+	assert.Nil(t, errs["k1"])
+	assert.True(t, errors.Is(errs["k2"], sqlc.ErrTooManyValues))
+	assert.True(t, errors.Is(errs["k3"], sqlc.ErrOptionRequired))
+}
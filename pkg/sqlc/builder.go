@@ -0,0 +1,98 @@
+package sqlc
+
+import (
+	"github.com/Masterminds/squirrel"
+	"time"
+)
+
+// QueryBuilder builds the statements with their arguments instead of the SQL templates.
+// The keys passed are the ones stored in the database, i.e. hashed by KeyHashFn if it is set.
+type QueryBuilder interface {
+	BuildGet(key string) (query string, args []interface{})
+	// BuildSet builds the statement to store the value, with the option encoded by the codec.
+	BuildSet(key string, value interface{}, option string) (query string, args []interface{})
+	BuildDel(key string) (query string, args []interface{})
+	BuildKeys() (query string, args []interface{})
+}
+
+// SquirrelBuilder is a QueryBuilder of the standard kv schema (see README.md) by squirrel,
+// which binds the keys and the values as arguments. Set is an upsert of mysql.
+type SquirrelBuilder struct {
+	// Table is the table name, default to kv.
+	Table string
+	// OptionColumn is the column of the option, which is not stored or selected when it is empty.
+	OptionColumn string
+	// Placeholder is the placeholder format, default to squirrel.Question.
+	Placeholder squirrel.PlaceholderFormat
+	// Now returns the time of the created and updated columns, default to time.Now.
+	Now func() time.Time
+}
+
+var _ QueryBuilder = (*SquirrelBuilder)(nil)
+
+func (b *SquirrelBuilder) table() string { return Default(b.Table, "kv") }
+
+func (b *SquirrelBuilder) builder() squirrel.StatementBuilderType {
+	if b.Placeholder == nil {
+		return squirrel.StatementBuilder
+	}
+
+	return squirrel.StatementBuilder.PlaceholderFormat(b.Placeholder)
+}
+
+func (b *SquirrelBuilder) now() time.Time {
+	if b.Now == nil {
+		return time.Now()
+	}
+
+	return b.Now()
+}
+
+// BuildGet builds the select of the value, and the option if OptionColumn is set.
+func (b *SquirrelBuilder) BuildGet(key string) (string, []interface{}) {
+	columns := []string{"v"}
+	if b.OptionColumn != "" {
+		columns = append(columns, b.OptionColumn)
+	}
+
+	return must(b.builder().Select(columns...).From(b.table()).
+		Where(squirrel.Eq{"k": key, "state": 1}).ToSql())
+}
+
+// BuildSet builds the upsert of the value, and the option if OptionColumn is set.
+func (b *SquirrelBuilder) BuildSet(key string, value interface{}, option string) (string, []interface{}) {
+	now := b.now()
+	columns := []string{"k", "v", "state", "created"}
+	values := []interface{}{key, value, 1, now}
+	update := "on duplicate key update v = ?, updated = ?, state = 1"
+	updateArgs := []interface{}{value, now}
+	if b.OptionColumn != "" {
+		columns = append(columns, b.OptionColumn)
+		values = append(values, option)
+		update += ", " + b.OptionColumn + " = ?"
+		updateArgs = append(updateArgs, option)
+	}
+
+	return must(b.builder().Insert(b.table()).Columns(columns...).Values(values...).
+		Suffix(update, updateArgs...).ToSql())
+}
+
+// BuildDel builds the soft deletion of the key.
+func (b *SquirrelBuilder) BuildDel(key string) (string, []interface{}) {
+	return must(b.builder().Update(b.table()).Set("state", 0).Where(squirrel.Eq{"k": key}).ToSql())
+}
+
+// BuildKeys builds the select of the keys.
+func (b *SquirrelBuilder) BuildKeys() (string, []interface{}) {
+	return must(b.builder().Select("k").From(b.table()).Where(squirrel.Eq{"state": 1}).ToSql())
+}
+
+// must panics on the error of the statements which are built from constants,
+// so that it only happens on programming errors.
+func must(query string, args []interface{}, err error) (string, []interface{}) {
+	if err != nil {
+		panic(err)
+	}
+
+	return query, args
+}
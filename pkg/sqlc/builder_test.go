@@ -0,0 +1,78 @@
+package sqlc_test
+
+import (
+	"database/sql/driver"
+	"github.com/Masterminds/squirrel"
+	"github.com/bingoohuang/gokv"
+	"github.com/bingoohuang/gokv/pkg/sqlc"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestSquirrelBuilder(t *testing.T) {
+	now := time.Date(2020, 10, 1, 12, 0, 0, 0, time.UTC)
+	b := &sqlc.SquirrelBuilder{OptionColumn: "o", Now: func() time.Time { return now }}
+
+	query, args := b.BuildGet("k'1")
+	assert.Equal(t, "SELECT v, o FROM kv WHERE k = ? AND state = ?", query)
+	assert.Equal(t, []interface{}{"k'1", 1}, args)
+
+	query, args = b.BuildSet("k'1", "v'1", `{"TTL":0}`)
+	assert.Equal(t, "INSERT INTO kv (k,v,state,created,o) VALUES (?,?,?,?,?) "+
+		"on duplicate key update v = ?, updated = ?, state = 1, o = ?", query)
+	assert.Equal(t, []interface{}{"k'1", "v'1", 1, now, `{"TTL":0}`, "v'1", now, `{"TTL":0}`}, args)
+
+	query, args = b.BuildDel("k'1")
+	assert.Equal(t, "UPDATE kv SET state = ? WHERE k = ?", query)
+	assert.Equal(t, []interface{}{0, "k'1"}, args)
+
+	query, args = (&sqlc.SquirrelBuilder{Table: "t", Placeholder: squirrel.Dollar}).BuildKeys()
+	assert.Equal(t, "SELECT k FROM t WHERE state = $1", query)
+	assert.Equal(t, []interface{}{1}, args)
+}
+
+func TestQueryBuilder(t *testing.T) {
+	d, driverName := newFakeDriver()
+	var bound [][]driver.Value
+	record := func(args []driver.NamedValue) {
+		values := make([]driver.Value, len(args))
+		for i, arg := range args {
+			values[i] = arg.Value
+		}
+		bound = append(bound, values)
+	}
+	d.Exec = func(_ string, args []driver.NamedValue) (driver.Result, error) {
+		record(args)
+		return driver.RowsAffected(1), nil
+	}
+	d.Query = func(_ string, args []driver.NamedValue) ([]string, [][]driver.Value, error) {
+		record(args)
+		return []string{"v", "o"}, [][]driver.Value{{"v'1", `{"TTL":3600000000000}`}}, nil
+	}
+
+	client := sqlc.NewClient(sqlc.Config{
+		DriverName:   driverName,
+		QueryBuilder: &sqlc.SquirrelBuilder{OptionColumn: "o"},
+	})
+	defer client.Close()
+
+	found, v, option, err := client.Get("k'1", nil)
+	assert.Nil(t, err)
+	assert.True(t, found)
+	assert.Equal(t, "v'1", v)
+	assert.Equal(t, time.Hour, option.TTL)
+
+	assert.Nil(t, client.Set("k'2", "v'2", gokv.TTL(time.Hour)))
+	assert.Nil(t, client.Del("k'2"))
+
+	assert.Equal(t, []string{
+		"SELECT v, o FROM kv WHERE k = ? AND state = ?",
+		"INSERT INTO kv (k,v,state,created,o) VALUES (?,?,?,?,?) " +
+			"on duplicate key update v = ?, updated = ?, state = 1, o = ?",
+		"UPDATE kv SET state = ? WHERE k = ?",
+	}, d.Statements)
+	assert.Equal(t, []driver.Value{"k'1", int64(1)}, bound[0])
+	assert.Equal(t, []driver.Value{"k'2", "v'2", int64(1)}, bound[1][:3])
+	assert.Equal(t, []driver.Value{int64(0), "k'2"}, bound[2])
+}
@@ -0,0 +1,53 @@
+package sqlc
+
+import (
+	"github.com/bingoohuang/gokv"
+	"text/template"
+	"time"
+)
+
+// argFuncs returns the template functions of the statements with bound arguments,
+// {{arg .Value}} renders the placeholder of the driver (see Placeholder) and appends the value to args.
+func (c *Client) argFuncs(args *[]interface{}) template.FuncMap {
+	return template.FuncMap{
+		"arg": func(v interface{}) string {
+			*args = append(*args, v)
+			return Placeholder(c.DriverName, len(*args))
+		},
+	}
+}
+
+// SetBytes stores the binary value for the given key by SetBytesSQL,
+// where the value is bound as a []byte argument rather than interpolated as text.
+func (c *Client) SetBytes(k string, v []byte, fns ...gokv.OptionFn) error {
+	defer c.metrics.observe("set", time.Now())
+
+	if err := c.validateKV(k, string(v)); err != nil {
+		return err
+	}
+
+	ctx := background()
+	oldV, err := c.priorValue(ctx, k)
+	if err != nil {
+		return err
+	}
+
+	if _, err := c.set(ctx, c.SetBytesSQL, nil, k, string(v), v, fns...); err != nil {
+		return err
+	}
+
+	c.audit("set", k, oldV, string(v))
+
+	return nil
+}
+
+// GetBytes retrieves the binary value for the given key, the value column selected by GetSQL should be binary.
+// If no value is found it returns (false, nil, nil).
+func (c *Client) GetBytes(k string) (found bool, v []byte, err error) {
+	found, s, _, err := c.Get(k, nil)
+	if err != nil || !found {
+		return false, nil, err
+	}
+
+	return true, []byte(s), nil
+}
@@ -0,0 +1,103 @@
+package sqlc_test
+
+import (
+	"database/sql/driver"
+	"github.com/bingoohuang/gokv"
+	"github.com/bingoohuang/gokv/pkg/sqlc"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestSetBytes(t *testing.T) {
+	raw := make([]byte, 256)
+	for i := range raw {
+		raw[i] = byte(i)
+	}
+
+	d, driverName := newFakeDriver()
+	var blob driver.Value
+	d.Exec = func(_ string, args []driver.NamedValue) (driver.Result, error) {
+		blob = args[0].Value
+		return driver.RowsAffected(1), nil
+	}
+	d.Query = func(string, []driver.NamedValue) ([]string, [][]driver.Value, error) {
+		return []string{"b"}, [][]driver.Value{{blob}}, nil
+	}
+
+	config := sqlc.Config{
+		DriverName:  driverName,
+		SetBytesSQL: "insert into kv(k, b) values('{{.Key}}', {{arg .Value}})",
+		GetSQL:      "select b from kv where k = '{{.Key}}'",
+	}
+	writer := sqlc.NewClient(config)
+	defer writer.Close()
+
+	assert.Nil(t, writer.SetBytes("k", raw))
+	assert.Equal(t, []string{"insert into kv(k, b) values('k', ?)"}, d.Statements)
+	assert.Equal(t, raw, blob)
+
+	client := sqlc.NewClient(config)
+	defer client.Close()
+
+	found, v, err := client.GetBytes("k")
+	assert.Nil(t, err)
+	assert.True(t, found)
+	assert.Equal(t, raw, v)
+
+	found, v, err = writer.GetBytes("k")
+	assert.Nil(t, err)
+	assert.True(t, found)
+	assert.Equal(t, raw, v)
+}
+
+func TestPlaceholder(t *testing.T) {
+	assert.Equal(t, "?", sqlc.Placeholder("mysql", 2))
+	assert.Equal(t, "$2", sqlc.Placeholder("postgres", 2))
+	assert.Equal(t, "@p2", sqlc.Placeholder("sqlserver", 2))
+	assert.Equal(t, ":2", sqlc.Placeholder("godror", 2))
+}
+
+func TestSetArgs(t *testing.T) {
+	d, driverName := newFakeDriver()
+
+	var args []driver.NamedValue
+	d.Exec = func(_ string, a []driver.NamedValue) (driver.Result, error) {
+		args = a
+		return driver.RowsAffected(1), nil
+	}
+
+	client := sqlc.NewClient(sqlc.Config{
+		DriverName: driverName,
+		SetSQL:     "insert into kv(k, v, o, updated) values(?, ?, ?, ?)",
+		SetArgs:    []string{"Key", "Value", "Option", "Time"},
+		Now:        func() time.Time { return time.Date(2021, 3, 1, 10, 0, 0, 0, time.UTC) },
+	})
+	defer client.Close()
+
+	assert.Nil(t, client.Set("k1", "it's a secret", gokv.TTL(time.Second)))
+	assert.Equal(t, []string{"insert into kv(k, v, o, updated) values(?, ?, ?, ?)"}, d.Statements)
+
+	values := make([]driver.Value, len(args))
+	for i, a := range args {
+		values[i] = a.Value
+	}
+	assert.Equal(t, []driver.Value{"k1", "it's a secret", `{"TTL":1000000000,"NoCache":false}`,
+		"2021-03-01 10:00:00.000"}, values)
+
+	mixed := sqlc.NewClient(sqlc.Config{
+		DriverName: driverName,
+		SetSQL:     "insert into kv(k, v) values(?, {{arg .Value}})",
+		SetArgs:    []string{"Key"},
+	})
+	defer mixed.Close()
+	assert.NotNil(t, mixed.Set("k1", "v1"))
+
+	unknown := sqlc.NewClient(sqlc.Config{
+		DriverName: driverName,
+		SetSQL:     "insert into kv(k) values(?)",
+		SetArgs:    []string{"Name"},
+	})
+	defer unknown.Close()
+	assert.NotNil(t, unknown.Set("k1", "v1"))
+}
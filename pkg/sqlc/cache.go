@@ -0,0 +1,74 @@
+package sqlc
+
+import "sync"
+
+// Cache is the cache of the key values of the client, which must be safe for concurrent use,
+// e.g. a Redis cache shared by the instances. The CacheValue should be kept as is in the LazyOption mode,
+// since it holds the option not decoded yet, otherwise only its exported fields need to be kept.
+type Cache interface {
+	// Get returns the cached value of the key.
+	Get(k string) (CacheValue, bool)
+	// Set caches the value of the key.
+	Set(k string, v CacheValue)
+	// Del removes the key from the cache.
+	Del(k string)
+	// Keys returns the cached keys.
+	Keys() []string
+	// Clear removes all the keys from the cache.
+	Clear()
+}
+
+// MapCache is the default Cache in memory of the process.
+type MapCache struct {
+	lock sync.RWMutex
+	m    map[string]CacheValue
+}
+
+// NewMapCache creates an empty MapCache.
+func NewMapCache() *MapCache { return &MapCache{m: make(map[string]CacheValue)} }
+
+// Get returns the cached value of the key.
+func (c *MapCache) Get(k string) (CacheValue, bool) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	v, ok := c.m[k]
+	return v, ok
+}
+
+// Set caches the value of the key.
+func (c *MapCache) Set(k string, v CacheValue) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.m[k] = v
+}
+
+// Del removes the key from the cache.
+func (c *MapCache) Del(k string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	delete(c.m, k)
+}
+
+// Keys returns the cached keys.
+func (c *MapCache) Keys() []string {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	keys := make([]string, 0, len(c.m))
+	for k := range c.m {
+		keys = append(keys, k)
+	}
+
+	return keys
+}
+
+// Clear removes all the keys from the cache.
+func (c *MapCache) Clear() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.m = make(map[string]CacheValue)
+}
@@ -0,0 +1,106 @@
+package sqlc_test
+
+import (
+	"database/sql/driver"
+	"github.com/bingoohuang/gokv/pkg/sqlc"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// recordingCache is a sqlc.Cache recording the hits and the misses of Get.
+type recordingCache struct {
+	*sqlc.MapCache
+
+	sync.Mutex
+	hits, misses []string
+}
+
+func (c *recordingCache) Get(k string) (sqlc.CacheValue, bool) {
+	v, ok := c.MapCache.Get(k)
+
+	c.Lock()
+	defer c.Unlock()
+
+	if ok {
+		c.hits = append(c.hits, k)
+	} else {
+		c.misses = append(c.misses, k)
+	}
+
+	return v, ok
+}
+
+func TestCache(t *testing.T) {
+	d, driverName := newFakeDriver()
+	d.Query = func(string, []driver.NamedValue) ([]string, [][]driver.Value, error) {
+		return []string{"v"}, [][]driver.Value{{"v1"}}, nil
+	}
+
+	cache := &recordingCache{MapCache: sqlc.NewMapCache()}
+	client := sqlc.NewClient(sqlc.Config{DriverName: driverName, Cache: cache})
+	defer client.Close()
+
+	for i := 0; i < 2; i++ {
+		found, v, _, err := client.Get("k1", nil)
+		assert.Nil(t, err)
+		assert.True(t, found)
+		assert.Equal(t, "v1", v)
+	}
+
+	assert.Equal(t, []string{"k1"}, cache.misses)
+	assert.Contains(t, cache.hits, "k1")
+	assert.Equal(t, 1, d.StatementCount(), "the second Get hits the cache")
+
+	assert.Nil(t, client.Set("k2", "v2"))
+	cv, ok := cache.MapCache.Get("k2")
+	assert.True(t, ok)
+	assert.Equal(t, "v2", cv.Value)
+
+	assert.Nil(t, client.Del("k2"))
+	_, ok = cache.MapCache.Get("k2")
+	assert.False(t, ok)
+
+	assert.ElementsMatch(t, []string{"k1"}, cache.Keys())
+	client.InvalidateAll()
+	assert.Empty(t, cache.Keys())
+}
+
+func TestCacheQueryHash(t *testing.T) {
+	d, driverName := newFakeDriver()
+	d.Query = func(query string, _ []driver.NamedValue) ([]string, [][]driver.Value, error) {
+		if strings.Contains(query, "v2") {
+			return []string{"v"}, [][]driver.Value{{"projected"}}, nil
+		}
+		return []string{"v"}, [][]driver.Value{{"base"}}, nil
+	}
+
+	cache := sqlc.NewMapCache()
+	old := sqlc.NewClient(sqlc.Config{DriverName: driverName, Cache: cache})
+	defer old.Close()
+
+	_, v, _, err := old.Get("k1", nil)
+	assert.Nil(t, err)
+	assert.Equal(t, "base", v)
+
+	// the new version of the client shares the cache with a changed GetSQL
+	client := sqlc.NewClient(sqlc.Config{DriverName: driverName, Cache: cache,
+		GetSQL: "select concat('v2:', v) from kv where k = '{{.Key}}' and state = 1"})
+	defer client.Close()
+
+	_, v, _, err = client.Get("k1", nil)
+	assert.Nil(t, err)
+	assert.Equal(t, "projected", v, "the value cached by the old GetSQL is invalidated")
+	assert.Equal(t, 2, d.StatementCount())
+
+	_, v, _, err = client.Get("k1", nil)
+	assert.Nil(t, err)
+	assert.Equal(t, "projected", v)
+	assert.Equal(t, 2, d.StatementCount(), "the value cached by the current GetSQL is served")
+
+	assert.Nil(t, old.LoadCacheSnapshot(client.CacheSnapshot()))
+	_, v, _, err = old.Get("k1", nil)
+	assert.Nil(t, err)
+	assert.Equal(t, "base", v, "the snapshot of a different GetSQL is invalidated")
+}
@@ -0,0 +1,79 @@
+package sqlc
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"io"
+	"strings"
+)
+
+// CompressedPrefix is the magic prefix of the compressed values, followed by the base64 of the compressed bytes,
+// so that the compressed values are still text-safe.
+const CompressedPrefix = "\x1fgokv-z:"
+
+// Compressor compresses the values, e.g. Gzip. Other algorithms like zstd can be plugged in by implementing it.
+type Compressor interface {
+	Compress(data []byte) ([]byte, error)
+	Decompress(data []byte) ([]byte, error)
+}
+
+// Gzip is the Compressor of gzip.
+type Gzip struct{}
+
+// Compress compresses the data by gzip.
+func (Gzip) Compress(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// Decompress decompresses the gzip data.
+func (Gzip) Decompress(data []byte) ([]byte, error) {
+	r, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	return io.ReadAll(r)
+}
+
+// compress compresses the value to be stored when it is longer than CompressAbove.
+func (c *Client) compress(v string) (string, error) {
+	if c.CompressAbove <= 0 || len(v) <= c.CompressAbove {
+		return v, nil
+	}
+
+	data, err := c.Compressor.Compress([]byte(v))
+	if err != nil {
+		return "", err
+	}
+
+	return CompressedPrefix + base64.StdEncoding.EncodeToString(data), nil
+}
+
+// decompress decompresses the value read from the database if it has the CompressedPrefix.
+func (c *Client) decompress(v string) (string, error) {
+	if !strings.HasPrefix(v, CompressedPrefix) {
+		return v, nil
+	}
+
+	data, err := base64.StdEncoding.DecodeString(v[len(CompressedPrefix):])
+	if err != nil {
+		return "", err
+	}
+
+	if data, err = c.Compressor.Decompress(data); err != nil {
+		return "", err
+	}
+
+	return string(data), nil
+}
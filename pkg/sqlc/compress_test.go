@@ -0,0 +1,45 @@
+package sqlc_test
+
+import (
+	"database/sql"
+	"github.com/bingoohuang/gokv/pkg/sqlc"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func TestCompressAbove(t *testing.T) {
+	dsn := startTestServer(t)
+	large := strings.Repeat("gokv compresses the large values. ", 300)
+
+	writer := sqlc.NewClient(sqlc.Config{DataSourceName: dsn, SetSQL: insertSetSQL, CompressAbove: 1024})
+	defer writer.Close()
+	assert.Nil(t, writer.Set("Key4", large))
+	assert.Nil(t, writer.Set("Key5", "small"))
+	assert.Nil(t, writer.SetMany(map[string]string{"Key6": large}))
+
+	db, err := sql.Open("mysql", dsn)
+	assert.Nil(t, err)
+	defer db.Close()
+
+	var stored string
+	assert.Nil(t, db.QueryRow("select v from kv where k = 'Key4'").Scan(&stored))
+	assert.True(t, strings.HasPrefix(stored, sqlc.CompressedPrefix))
+	assert.True(t, len(stored) < len(large)/10)
+	assert.Nil(t, db.QueryRow("select v from kv where k = 'Key5'").Scan(&stored))
+	assert.Equal(t, "small", stored)
+
+	reader := sqlc.NewClient(sqlc.Config{DataSourceName: dsn})
+	defer reader.Close()
+
+	for k, expected := range map[string]string{"Key4": large, "Key5": "small", "Key6": large} {
+		found, v, _, err := reader.Get(k, nil)
+		assert.Nil(t, err)
+		assert.True(t, found)
+		assert.Equal(t, expected, v)
+	}
+
+	kvs, err := reader.All()
+	assert.Nil(t, err)
+	assert.Equal(t, large, kvs["Key6"])
+}
@@ -0,0 +1,82 @@
+package sqlc_test
+
+import (
+	"context"
+	"errors"
+	"github.com/bingoohuang/gokv/pkg/sqlc"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestContext(t *testing.T) {
+	d, driverName := newFakeDriver()
+	client := sqlc.NewClient(sqlc.Config{DriverName: driverName})
+	defer client.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, _, _, err := client.GetContext(ctx, "k1", nil)
+	assert.True(t, errors.Is(err, context.Canceled))
+	assert.True(t, errors.Is(client.SetContext(ctx, "k1", "v1"), context.Canceled))
+	_, err = client.KeysContext(ctx)
+	assert.True(t, errors.Is(err, context.Canceled))
+	assert.Equal(t, 0, d.StatementCount(), "nothing is sent to the database with the canceled ctx")
+
+	assert.Nil(t, client.SetContext(context.Background(), "k1", "v1"))
+	found, v, _, err := client.GetContext(context.Background(), "k1", nil)
+	assert.Nil(t, err)
+	assert.True(t, found)
+	assert.Equal(t, "v1", v)
+	assert.Nil(t, client.DelContext(context.Background(), "k1"))
+	assert.Equal(t, 2, d.StatementCount())
+}
+
+func TestQueryTimeout(t *testing.T) {
+	d, driverName := newFakeDriver()
+	d.Delay = time.Second
+
+	client := sqlc.NewClient(sqlc.Config{DriverName: driverName, QueryTimeout: 10 * time.Millisecond})
+	defer client.Close()
+
+	start := time.Now()
+	_, _, _, err := client.Get("k1", nil)
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+	assert.True(t, time.Since(start) < d.Delay, "the blocked query is abandoned")
+
+	_, err = client.Keys()
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+
+	d.Delay = 0
+	_, _, _, err = client.Get("k1", nil)
+	assert.Nil(t, err)
+}
+
+func TestQueryTimeoutContext(t *testing.T) {
+	d, driverName := newFakeDriver()
+	d.Delay = 50 * time.Millisecond
+
+	client := sqlc.NewClient(sqlc.Config{DriverName: driverName, QueryTimeout: 10 * time.Millisecond})
+	defer client.Close()
+
+	// the caller deadline longer than the QueryTimeout is honored by the Context variants
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	_, _, _, err := client.GetContext(ctx, "k1", nil)
+	assert.Nil(t, err)
+	_, err = client.KeysContext(ctx)
+	assert.Nil(t, err)
+	assert.Nil(t, client.SetContext(ctx, "k1", "v1"))
+	assert.Nil(t, client.DelContext(ctx, "k1"))
+
+	_, _, _, err = client.Get("k1", nil)
+	assert.True(t, errors.Is(err, context.DeadlineExceeded), "the QueryTimeout still bounds Get")
+
+	short, cancelShort := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancelShort()
+
+	_, _, _, err = client.GetContext(short, "k1", nil)
+	assert.True(t, errors.Is(err, context.DeadlineExceeded), "the caller deadline bounds GetContext")
+}
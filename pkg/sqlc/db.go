@@ -0,0 +1,85 @@
+package sqlc
+
+import (
+	"context"
+	"database/sql"
+	"go.uber.org/multierr"
+	"sync/atomic"
+)
+
+// Open creates a client like NewClient, and returns the errors of opening and pinging the databases,
+// so that a bad DataSourceName fails here instead of at the first operation.
+func Open(c Config) (*Client, error) {
+	client := NewClient(c)
+
+	ctx, cancel := context.WithTimeout(context.Background(), client.QueryTimeout)
+	defer cancel()
+
+	for _, dsn := range client.dataSourceNames() {
+		db, err := client.openDB(dsn)
+		if err == nil {
+			err = db.PingContext(ctx)
+		}
+		if err != nil {
+			return nil, multierr.Append(err, client.Close())
+		}
+	}
+
+	return client, nil
+}
+
+// dataSourceNames returns the data source names of the databases, and of the replica if any.
+func (c *Client) dataSourceNames() []string {
+	if c.ReadDataSourceName == "" {
+		return []string{c.DataSourceName}
+	}
+
+	return []string{c.DataSourceName, c.ReadDataSourceName}
+}
+
+// openDB returns the database of the data source name, which is opened by the DriverName at its first use,
+// and shared by all the operations of the client, so that its connection pool is reused until Close.
+func (c *Client) openDB(dataSourceName string) (*sql.DB, error) {
+	if atomic.LoadInt32(&c.closed) == 1 {
+		return nil, ErrClientClosed
+	}
+
+	c.dbLock.Lock()
+	defer c.dbLock.Unlock()
+
+	// checked again under the lock, since Close may have closed the databases after the check above
+	if atomic.LoadInt32(&c.closed) == 1 {
+		return nil, ErrClientClosed
+	}
+
+	if db, ok := c.dbs[dataSourceName]; ok {
+		return db, nil
+	}
+
+	db, err := sql.Open(c.DriverName, dataSourceName)
+	if err != nil {
+		return nil, err
+	}
+
+	atomic.AddUint64(&c.metrics.opens, 1)
+	if c.dbs == nil {
+		c.dbs = make(map[string]*sql.DB)
+	}
+	c.dbs[dataSourceName] = db
+
+	return db, nil
+}
+
+// closeDBs closes the databases opened by openDB.
+func (c *Client) closeDBs() (er error) {
+	c.dbLock.Lock()
+	defer c.dbLock.Unlock()
+
+	for dsn, db := range c.dbs {
+		atomic.AddUint64(&c.metrics.closes, 1)
+		er = multierr.Combine(er, c.closeStmts(db), db.Close())
+		delete(c.dbs, dsn)
+	}
+
+	return er
+}
@@ -0,0 +1,61 @@
+package sqlc_test
+
+import (
+	"errors"
+	"github.com/bingoohuang/gokv/pkg/sqlc"
+	"github.com/stretchr/testify/assert"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestOpen(t *testing.T) {
+	_, err := sqlc.Open(sqlc.Config{DriverName: "unknown"})
+	assert.NotNil(t, err, "the bad driver fails eagerly")
+
+	d, driverName := newFakeDriver()
+	client, err := sqlc.Open(sqlc.Config{DriverName: driverName, ReadDataSourceName: "replica"})
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(2), client.Stats().Opens, "the primary and the replica are opened")
+
+	conns, _ := d.Opened()
+	assert.Equal(t, 2, conns, "the pinged connections are kept in the pools")
+
+	assert.Nil(t, client.Close())
+	conns, _ = d.Opened()
+	assert.Equal(t, 0, conns)
+	assert.Equal(t, uint64(2), client.Stats().Closes)
+
+	_, err = sqlc.Open(sqlc.Config{DataSourceName: "not a dsn"})
+	assert.NotNil(t, err, "the malformed data source name fails at the construction")
+
+	_, err = sqlc.Open(sqlc.Config{DataSourceName: "root@tcp(127.0.0.1:1)/db", QueryTimeout: time.Second})
+	assert.NotNil(t, err, "the unreachable database fails the ping at the construction")
+
+	lazy := sqlc.NewClient(sqlc.Config{DriverName: "unknown"})
+	defer lazy.Close()
+	_, err = lazy.All()
+	assert.NotNil(t, err, "the bad driver fails at the first use")
+}
+
+func TestClose(t *testing.T) {
+	d, driverName := newFakeDriver()
+	goroutines := runtime.NumGoroutine()
+
+	client := sqlc.NewClient(sqlc.Config{DriverName: driverName, RefreshInterval: 5 * time.Millisecond})
+	assert.Eventually(t, func() bool { return d.StatementCount() > 1 }, time.Second, 5*time.Millisecond,
+		"the refresh is running")
+	assert.Nil(t, client.Set("k1", "v1"))
+
+	assert.Nil(t, client.Close())
+	assert.Eventually(t, func() bool { return runtime.NumGoroutine() <= goroutines }, time.Second,
+		5*time.Millisecond, "the refresh goroutine and the database are released")
+	conns, _ := d.Opened()
+	assert.Equal(t, 0, conns)
+
+	_, _, _, err := client.Get("k1", nil)
+	assert.True(t, errors.Is(err, sqlc.ErrClientClosed), "the cache is not hit after Close")
+	assert.True(t, errors.Is(client.Set("k1", "v2"), sqlc.ErrClientClosed))
+	assert.True(t, errors.Is(client.Del("k1"), sqlc.ErrClientClosed))
+	assert.Nil(t, client.Close(), "closing twice is fine")
+}
@@ -0,0 +1,64 @@
+package sqlc
+
+import "strconv"
+
+// ServerTimeSQL returns the SQL function of the database server time for the driver,
+// which is used as the {{.ServerTime}} template field:
+//
+//	mysql                    NOW()
+//	postgres, pgx            CURRENT_TIMESTAMP
+//	sqlite3                  STRFTIME('%Y-%m-%d %H:%M:%f', 'now')
+//	sqlserver, mssql         SYSDATETIME()
+//	oracle, godror, oci8     SYSTIMESTAMP
+//	others                   CURRENT_TIMESTAMP
+func ServerTimeSQL(driverName string) string {
+	switch driverName {
+	case "mysql":
+		return "NOW()"
+	case "sqlite3":
+		return "STRFTIME('%Y-%m-%d %H:%M:%f', 'now')"
+	case "sqlserver", "mssql":
+		return "SYSDATETIME()"
+	case "oracle", "godror", "oci8":
+		return "SYSTIMESTAMP"
+	default:
+		return "CURRENT_TIMESTAMP"
+	}
+}
+
+// Placeholder returns the placeholder of the nth (1-based) bound argument for the driver,
+// which is rendered by {{arg .Value}}:
+//
+//	postgres, pgx            $n
+//	sqlserver, mssql         @pn
+//	oracle, godror, oci8     :n
+//	others                   ?
+func Placeholder(driverName string, n int) string {
+	switch driverName {
+	case "postgres", "pgx":
+		return "$" + strconv.Itoa(n)
+	case "sqlserver", "mssql":
+		return "@p" + strconv.Itoa(n)
+	case "oracle", "godror", "oci8":
+		return ":" + strconv.Itoa(n)
+	default:
+		return "?"
+	}
+}
+
+// AdvisoryLockSQL returns the SQL to take and release the advisory lock named {{.LockKey}} for the driver:
+//
+//	mysql                    SELECT GET_LOCK('{{.LockKey}}', 10), SELECT RELEASE_LOCK('{{.LockKey}}')
+//	postgres, pgx            SELECT pg_advisory_lock(hashtext('{{.LockKey}}')), SELECT pg_advisory_unlock(...)
+//	others                   empty, which must be configured by Config.LockSQL and Config.UnlockSQL
+func AdvisoryLockSQL(driverName string) (lockSQL, unlockSQL string) {
+	switch driverName {
+	case "mysql":
+		return "SELECT GET_LOCK('{{.LockKey}}', 10)", "SELECT RELEASE_LOCK('{{.LockKey}}')"
+	case "postgres", "pgx":
+		return "SELECT pg_advisory_lock(hashtext('{{.LockKey}}'))",
+			"SELECT pg_advisory_unlock(hashtext('{{.LockKey}}'))"
+	default:
+		return "", ""
+	}
+}
@@ -0,0 +1,96 @@
+package sqlc
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// DSN builds the DataSourceName for a driver, e.g.
+// MySQL().Host("db").DB("kv").ParseTime(true).String(), or Postgres().Host("db").SSLMode("disable").String().
+type DSN struct {
+	driverName     string
+	user, password string
+	host           string
+	port           int
+	db             string
+	params         url.Values
+}
+
+// MySQL creates a DSN builder of github.com/go-sql-driver/mysql, default to localhost:3306.
+func MySQL() *DSN {
+	return &DSN{driverName: "mysql", host: "localhost", port: 3306, params: url.Values{}}
+}
+
+// Postgres creates a DSN builder of github.com/lib/pq or pgx in the URL form, default to localhost:5432.
+func Postgres() *DSN {
+	return &DSN{driverName: "postgres", host: "localhost", port: 5432, params: url.Values{}}
+}
+
+// DriverName returns the driver name of the DSN for Config.DriverName.
+func (d *DSN) DriverName() string { return d.driverName }
+
+// User sets the user and the password.
+func (d *DSN) User(user, password string) *DSN { d.user, d.password = user, password; return d }
+
+// Host sets the host.
+func (d *DSN) Host(host string) *DSN { d.host = host; return d }
+
+// Port sets the port.
+func (d *DSN) Port(port int) *DSN { d.port = port; return d }
+
+// DB sets the database name.
+func (d *DSN) DB(db string) *DSN { d.db = db; return d }
+
+// Param sets a driver specific parameter.
+func (d *DSN) Param(name, value string) *DSN { d.params.Set(name, value); return d }
+
+// ParseTime sets the mysql parameter parseTime to scan DATE and DATETIME into time.Time.
+func (d *DSN) ParseTime(parseTime bool) *DSN {
+	return d.Param("parseTime", strconv.FormatBool(parseTime))
+}
+
+// Charset sets the mysql parameter charset, e.g. utf8mb4.
+func (d *DSN) Charset(charset string) *DSN { return d.Param("charset", charset) }
+
+// Loc sets the mysql parameter loc of the time zone, e.g. Local or Asia/Shanghai.
+func (d *DSN) Loc(loc string) *DSN { return d.Param("loc", loc) }
+
+// Timeout sets the connecting timeout, which is the mysql parameter timeout,
+// or the postgres parameter connect_timeout in seconds.
+func (d *DSN) Timeout(timeout time.Duration) *DSN {
+	if d.driverName == "postgres" {
+		return d.Param("connect_timeout", strconv.Itoa(int(timeout/time.Second)))
+	}
+
+	return d.Param("timeout", timeout.String())
+}
+
+// SSLMode sets the postgres parameter sslmode, e.g. disable, require or verify-full.
+func (d *DSN) SSLMode(mode string) *DSN { return d.Param("sslmode", mode) }
+
+// String returns the DataSourceName, whose parameters are sorted by names.
+func (d *DSN) String() string {
+	addr := net.JoinHostPort(d.host, strconv.Itoa(d.port))
+
+	if d.driverName == "postgres" {
+		u := url.URL{Scheme: "postgres", Host: addr, Path: "/" + d.db, RawQuery: d.params.Encode()}
+		if d.user != "" {
+			u.User = url.UserPassword(d.user, d.password)
+		}
+
+		return u.String()
+	}
+
+	dsn := fmt.Sprintf("tcp(%s)/%s", addr, d.db)
+	if d.user != "" {
+		dsn = d.user + ":" + d.password + "@" + dsn
+	}
+	if len(d.params) > 0 {
+		dsn += "?" + d.params.Encode()
+	}
+
+	return dsn
+}
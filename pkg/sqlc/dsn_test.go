@@ -0,0 +1,29 @@
+package sqlc_test
+
+import (
+	"github.com/bingoohuang/gokv/pkg/sqlc"
+	"github.com/go-sql-driver/mysql"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestDSN(t *testing.T) {
+	dsn := sqlc.MySQL().User("user", "pass").Host("db").Port(3307).DB("kv").
+		ParseTime(true).Charset("utf8mb4").Loc("Asia/Shanghai").Timeout(5 * time.Second)
+	assert.Equal(t, "mysql", dsn.DriverName())
+	assert.Equal(t, "user:pass@tcp(db:3307)/kv?charset=utf8mb4&loc=Asia%2FShanghai&parseTime=true&timeout=5s",
+		dsn.String())
+
+	config, err := mysql.ParseDSN(dsn.String())
+	assert.Nil(t, err)
+	assert.Equal(t, "db:3307", config.Addr)
+	assert.True(t, config.ParseTime)
+	assert.Equal(t, "Asia/Shanghai", config.Loc.String())
+
+	assert.Equal(t, "tcp(localhost:3306)/kv", sqlc.MySQL().DB("kv").String())
+
+	dsn = sqlc.Postgres().User("user", "p@ss").Host("db").DB("kv").SSLMode("disable").Timeout(5 * time.Second)
+	assert.Equal(t, "postgres", dsn.DriverName())
+	assert.Equal(t, "postgres://user:p%40ss@db:5432/kv?connect_timeout=5&sslmode=disable", dsn.String())
+}
@@ -0,0 +1,29 @@
+package sqlc
+
+import (
+	"context"
+	"time"
+)
+
+// Each queries the key values by AllSQL like All, and calls fn with each of them as it is scanned,
+// without holding all of them in memory. It stops early and returns the error when fn returns one.
+// The cache is not refreshed by Each.
+func (c *Client) Each(fn func(k, v string) error) (er error) {
+	defer c.metrics.observe("all", time.Now())
+
+	query, args, err := c.render(c.AllSQL, map[string]string{})
+	if err != nil {
+		return err
+	}
+	c.logQuery(query)
+
+	db, err := c.openDB(c.readDataSourceName(""))
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), c.QueryTimeout)
+	defer cancel()
+
+	return c.eachKV(ctx, db, query, args, fn)
+}
@@ -0,0 +1,49 @@
+package sqlc
+
+import (
+	"context"
+	"strconv"
+	"time"
+)
+
+// ExportPage returns a page of at most limit key values after the cursor in the order of the keys
+// by ExportSQL, and the cursor of the next page, which is empty at the end.
+// Start with an empty cursor, and resume an interrupted export by the last returned cursor.
+// The limit defaults to BatchSize when it is not positive. The key values are not cached.
+func (c *Client) ExportPage(cursor string, limit int) (pairs []KV, nextCursor string, er error) {
+	defer c.metrics.observe("exportpage", time.Now())
+
+	if limit <= 0 {
+		limit = c.BatchSize
+	}
+
+	query, args, err := c.render(c.ExportSQL, map[string]string{"Cursor": cursor, "Limit": strconv.Itoa(limit)})
+	if err != nil {
+		return nil, "", err
+	}
+	c.logQuery(query)
+
+	db, err := c.openDB(c.readDataSourceName(""))
+	if err != nil {
+		return nil, "", err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), c.QueryTimeout)
+	defer cancel()
+
+	// the cursor is the key stored in the database, e.g. the hashed one with KeyHashFn
+	var last string
+	if err := c.eachRawKV(ctx, db, query, args, func(k, v string) error {
+		pairs = append(pairs, KV{Key: c.logicalKey(k), Value: v})
+		last = k
+		return nil
+	}); err != nil {
+		return nil, "", err
+	}
+
+	if len(pairs) < limit {
+		return pairs, "", nil
+	}
+
+	return pairs, last, nil
+}
@@ -0,0 +1,48 @@
+package sqlc_test
+
+import (
+	"fmt"
+	"github.com/bingoohuang/gokv/pkg/sqlc"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestExportPage(t *testing.T) {
+	dsn := startTestServer(t)
+	client := sqlc.NewClient(sqlc.Config{DataSourceName: dsn + "?interpolateParams=true", SetSQL: insertSetSQL})
+	defer client.Close()
+
+	for i := 0; i < 5; i++ {
+		assert.Nil(t, client.Set(fmt.Sprintf("e%d", i), fmt.Sprintf("v%d", i)))
+	}
+
+	all, err := client.All()
+	assert.Nil(t, err)
+
+	exported := make(map[string]string)
+	pages, cursor := 0, ""
+	for {
+		pairs, next, err := client.ExportPage(cursor, 3)
+		assert.Nil(t, err)
+		assert.LessOrEqual(t, len(pairs), 3)
+
+		for _, kv := range pairs {
+			assert.NotContains(t, exported, kv.Key, "the key is exported once")
+			exported[kv.Key] = kv.Value
+		}
+
+		pages++
+		if next == "" {
+			break
+		}
+		cursor = next
+	}
+
+	assert.Equal(t, all, exported)
+	assert.Equal(t, 3, pages, "8 rows in the pages of 3")
+
+	// resume the export after the key Key3
+	pairs, _, err := client.ExportPage("Key3", 2)
+	assert.Nil(t, err)
+	assert.Equal(t, []sqlc.KV{{Key: "e0", Value: "v0"}, {Key: "e1", Value: "v1"}}, pairs)
+}
@@ -0,0 +1,167 @@
+package sqlc_test
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var fakeDriverSeq int32
+
+// fakeDriver is a recording database/sql driver for the tests.
+type fakeDriver struct {
+	// Query returns the columns and the rows of the query.
+	Query func(query string, args []driver.NamedValue) ([]string, [][]driver.Value, error)
+	// Exec executes the statement.
+	Exec func(query string, args []driver.NamedValue) (driver.Result, error)
+	// Delay blocks the queries until the delay or the ctx is done, like a slow database.
+	Delay time.Duration
+
+	sync.Mutex
+	Statements []string
+	Prepared   []string
+	OpenConns  int
+	OpenRows   int
+}
+
+// newFakeDriver registers a new fake driver and returns it with its name.
+func newFakeDriver() (*fakeDriver, string) {
+	d := &fakeDriver{
+		Query: func(string, []driver.NamedValue) ([]string, [][]driver.Value, error) {
+			return []string{"v"}, nil, nil
+		},
+		Exec: func(string, []driver.NamedValue) (driver.Result, error) { return driver.RowsAffected(1), nil },
+	}
+	name := fmt.Sprintf("fake%d", atomic.AddInt32(&fakeDriverSeq, 1))
+	sql.Register(name, d)
+
+	return d, name
+}
+
+func (d *fakeDriver) record(f func()) {
+	d.Lock()
+	defer d.Unlock()
+
+	f()
+}
+
+// Opened returns the number of the open connections and rows.
+func (d *fakeDriver) Opened() (conns, rows int) {
+	d.Lock()
+	defer d.Unlock()
+
+	return d.OpenConns, d.OpenRows
+}
+
+// StatementCount returns the number of the executed statements.
+func (d *fakeDriver) StatementCount() int {
+	d.Lock()
+	defer d.Unlock()
+
+	return len(d.Statements)
+}
+
+func (d *fakeDriver) Open(string) (driver.Conn, error) {
+	d.record(func() { d.OpenConns++ })
+
+	return &fakeConn{d: d}, nil
+}
+
+type fakeConn struct {
+	d *fakeDriver
+}
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) {
+	c.d.record(func() { c.d.Prepared = append(c.d.Prepared, query) })
+
+	return &fakeStmt{conn: c, query: query}, nil
+}
+func (c *fakeConn) Begin() (driver.Tx, error) { return c, nil }
+func (c *fakeConn) Commit() error             { return nil }
+func (c *fakeConn) Rollback() error           { return nil }
+
+func (c *fakeConn) Close() error {
+	c.d.record(func() { c.d.OpenConns-- })
+
+	return nil
+}
+
+func (c *fakeConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	c.d.record(func() { c.d.Statements = append(c.d.Statements, query) })
+
+	select {
+	case <-time.After(c.d.Delay):
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	cols, rows, err := c.d.Query(query, args)
+	if err != nil {
+		return nil, err
+	}
+
+	c.d.record(func() { c.d.OpenRows++ })
+
+	return &fakeRows{d: c.d, cols: cols, rows: rows}, nil
+}
+
+func (c *fakeConn) ExecContext(_ context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	c.d.record(func() { c.d.Statements = append(c.d.Statements, query) })
+
+	return c.d.Exec(query, args)
+}
+
+type fakeRows struct {
+	d    *fakeDriver
+	cols []string
+	rows [][]driver.Value
+}
+
+func (r *fakeRows) Columns() []string { return r.cols }
+
+func (r *fakeRows) Close() error {
+	r.d.record(func() { r.d.OpenRows-- })
+
+	return nil
+}
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+
+	return nil
+}
+
+// fakeStmt is a prepared statement, which runs on its connection.
+type fakeStmt struct {
+	conn  *fakeConn
+	query string
+}
+
+func (s *fakeStmt) Close() error  { return nil }
+func (s *fakeStmt) NumInput() int { return -1 }
+
+func (s *fakeStmt) Exec([]driver.Value) (driver.Result, error) {
+	return nil, errors.New("use ExecContext")
+}
+func (s *fakeStmt) Query([]driver.Value) (driver.Rows, error) {
+	return nil, errors.New("use QueryContext")
+}
+
+func (s *fakeStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	return s.conn.ExecContext(ctx, s.query, args)
+}
+
+func (s *fakeStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	return s.conn.QueryContext(ctx, s.query, args)
+}
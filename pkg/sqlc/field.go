@@ -0,0 +1,62 @@
+package sqlc
+
+import (
+	"context"
+	"database/sql"
+	"github.com/bingoohuang/gokv"
+	"go.uber.org/multierr"
+	"time"
+)
+
+// KV is a key value pair.
+type KV = gokv.KV
+
+// GetByField returns the key values whose values have the field of fieldPath equal to value,
+// queried by FieldQuerySQL, which selects the key and the value columns, e.g. on a JSON value column of mysql:
+// select k, v from kv where state = 1 and v->>'{{.FieldPath}}' = '{{.FieldValue}}'
+// The key values are not cached. gokv.ErrNotSupported is returned when FieldQuerySQL is not set.
+func (c *Client) GetByField(fieldPath, value string) (kvs []KV, er error) {
+	defer c.metrics.observe("getbyfield", time.Now())
+
+	if c.FieldQuerySQL == "" {
+		return nil, gokv.ErrNotSupported
+	}
+
+	query, args, err := c.render(c.FieldQuerySQL, map[string]string{"FieldPath": fieldPath, "FieldValue": value})
+	if err != nil {
+		return nil, err
+	}
+	c.logQuery(query)
+
+	db, err := c.openDB(c.readDataSourceName(""))
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), c.QueryTimeout)
+	defer cancel()
+
+	rows, release, err := c.queryContext(ctx, db, "getbyfield", "", query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() { er = multierr.Combine(er, drainAndClose(rows), release()) }()
+
+	for rows.Next() {
+		var k, v sql.NullString
+		if err := rows.Scan(&k, &v); err != nil {
+			return nil, err
+		}
+		c.metrics.scanned()
+
+		decompressed, err := c.decompress(v.String)
+		if err != nil {
+			return nil, err
+		}
+
+		kvs = append(kvs, KV{Key: c.logicalKey(k.String), Value: decompressed})
+	}
+
+	return kvs, nil
+}
@@ -0,0 +1,36 @@
+package sqlc_test
+
+import (
+	"github.com/bingoohuang/gokv/pkg/sqlc"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestGetByField(t *testing.T) {
+	dsn := startTestServer(t)
+
+	client := sqlc.NewClient(sqlc.Config{
+		DataSourceName: dsn,
+		SetSQL:         insertSetSQL,
+		FieldQuerySQL: "select k, v from kv where state = 1 " +
+			"and JSON_UNQUOTE(JSON_EXTRACT(v, '{{.FieldPath}}')) = '{{.FieldValue}}'",
+	})
+	defer client.Close()
+
+	assert.Nil(t, client.SetMany(map[string]string{
+		"Key4": `{"user":{"name":"bingoo","status":"active"}}`,
+		"Key5": `{"user":{"name":"huang","status":"inactive"}}`,
+		"Key6": `{"user":{"name":"gokv","status":"active"}}`,
+	}))
+
+	kvs, err := client.GetByField("$.user.status", "active")
+	assert.Nil(t, err)
+	assert.ElementsMatch(t, []sqlc.KV{
+		{Key: "Key4", Value: `{"user":{"name":"bingoo","status":"active"}}`},
+		{Key: "Key6", Value: `{"user":{"name":"gokv","status":"active"}}`},
+	}, kvs)
+
+	kvs, err = client.GetByField("$.user.name", "nobody")
+	assert.Nil(t, err)
+	assert.Empty(t, kvs)
+}
@@ -0,0 +1,91 @@
+package sqlc
+
+import (
+	"context"
+	"fmt"
+	"github.com/bingoohuang/gokv"
+	"github.com/bingoohuang/gokv/pkg/codec"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+var returningRe = regexp.MustCompile(`(?i)\breturning\b`)
+
+// SetGenerateKey stores the value by InsertSQL, whose key is generated by the database, e.g. an auto-increment id,
+// and returns the generated key, which is cached with the value.
+// The key is scanned from the returned row when InsertSQL has a RETURNING clause,
+// e.g. postgres, sqlite3 3.35+ and mariadb 10.5+, otherwise it is the LastInsertId of the statement,
+// e.g. mysql and sqlite3, which is not supported by the drivers of postgres, sqlserver and oracle.
+// gokv.ErrNotSupported is returned when InsertSQL is not set.
+func (c *Client) SetGenerateKey(v string, fns ...gokv.OptionFn) (key string, er error) {
+	defer c.metrics.observe("set", time.Now())
+
+	if c.InsertSQL == "" {
+		return "", gokv.ErrNotSupported
+	}
+
+	stored, err := c.compress(v)
+	if err != nil {
+		return "", err
+	}
+
+	option := gokv.NewOption(fns...)
+	start := time.Now()
+	optionData, err := c.Codec.Marshal(option)
+	c.metrics.observePhase("codec", start)
+	if err != nil {
+		return "", codec.WrapError("marshal", option, err)
+	}
+
+	query, args, err := c.render(c.InsertSQL, map[string]interface{}{
+		"Value":      stored,
+		"Option":     string(optionData),
+		"Time":       c.formatTime(),
+		"ServerTime": c.ServerTimeSQL,
+	})
+	if err != nil {
+		return "", err
+	}
+	c.logQuery(query)
+
+	db, err := c.openDB(c.DataSourceName)
+	if err != nil {
+		return "", err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), c.QueryTimeout)
+	defer cancel()
+
+	if returningRe.MatchString(query) {
+		err = c.retry(ctx, true, func() error {
+			defer c.logSlow("insert", "", time.Now())
+			defer c.metrics.observePhase("sql", time.Now())
+
+			c.metrics.query()
+			return db.QueryRowContext(ctx, query, args...).Scan(&key)
+		})
+		if err != nil {
+			return "", err
+		}
+	} else {
+		result, err := c.execContext(ctx, db, "insert", "", query, args...)
+		if err != nil {
+			return "", err
+		}
+
+		id, err := result.LastInsertId()
+		if err != nil {
+			return "", fmt.Errorf("LastInsertId not supported, use a RETURNING clause instead: %w", err)
+		}
+
+		key = strconv.FormatInt(id, 10)
+	}
+
+	c.wrote(key)
+	c.cacheSet(key, v, option)
+	// the generated key is new, so there is no prior value
+	c.audit("set", key, "", v)
+
+	return key, nil
+}
@@ -0,0 +1,68 @@
+package sqlc_test
+
+import (
+	"database/sql/driver"
+	"github.com/bingoohuang/gokv"
+	"github.com/bingoohuang/gokv/pkg/sqlc"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+// autoIncrement is the driver.Result of an insert with an auto-increment id.
+type autoIncrement int64
+
+func (id autoIncrement) LastInsertId() (int64, error) { return int64(id), nil }
+func (id autoIncrement) RowsAffected() (int64, error) { return 1, nil }
+
+func TestSetGenerateKey(t *testing.T) {
+	rows := map[string]string{}
+	d, driverName := newFakeDriver()
+	d.Exec = func(query string, _ []driver.NamedValue) (driver.Result, error) {
+		rows["42"] = strings.TrimSuffix(strings.TrimPrefix(query, "insert into kv(v) values('"), "')")
+		return autoIncrement(42), nil
+	}
+	d.Query = func(query string, _ []driver.NamedValue) ([]string, [][]driver.Value, error) {
+		if strings.Contains(query, "returning") {
+			rows["43"] = "returned"
+			return []string{"k"}, [][]driver.Value{{"43"}}, nil
+		}
+
+		for k, v := range rows {
+			if strings.Contains(query, "'"+k+"'") {
+				return []string{"v"}, [][]driver.Value{{v}}, nil
+			}
+		}
+		return []string{"v"}, nil, nil
+	}
+
+	client := sqlc.NewClient(sqlc.Config{DriverName: driverName, InsertSQL: "insert into kv(v) values('{{.Value}}')"})
+	defer client.Close()
+
+	key, err := client.SetGenerateKey("v42")
+	assert.Nil(t, err)
+	assert.Equal(t, "42", key)
+	assert.Equal(t, "v42", client.CacheSnapshot()["42"].Value)
+
+	// a subsequent Get of another client reads the generated key
+	reader := sqlc.NewClient(sqlc.Config{DriverName: driverName})
+	defer reader.Close()
+
+	found, v, _, err := reader.Get(key, nil)
+	assert.Nil(t, err)
+	assert.True(t, found)
+	assert.Equal(t, "v42", v)
+
+	returning := sqlc.NewClient(sqlc.Config{
+		DriverName: driverName,
+		InsertSQL:  "insert into kv(v) values('{{.Value}}') returning k",
+	})
+	defer returning.Close()
+
+	key, err = returning.SetGenerateKey("returned")
+	assert.Nil(t, err)
+	assert.Equal(t, "43", key)
+
+	_, err = reader.SetGenerateKey("v")
+	assert.Equal(t, gokv.ErrNotSupported, err)
+}
@@ -0,0 +1,54 @@
+package sqlc
+
+import (
+	"fmt"
+	"github.com/bingoohuang/gokv"
+	"go.uber.org/multierr"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// ErrHTTPStatus is the error of a non-2xx response of the upstream of HTTPGenerator.
+type ErrHTTPStatus struct {
+	URL        string
+	StatusCode int
+}
+
+func (e ErrHTTPStatus) Error() string {
+	return fmt.Sprintf("GET %s: unexpected status %d %s", e.URL, e.StatusCode, http.StatusText(e.StatusCode))
+}
+
+// HTTPGenerator creates a gokv.GeneratorFn which generates the value of a missing key by the body of GET urlTemplate,
+// whose {{.Key}} is replaced by the path escaped key, e.g. https://api.example.com/config/{{.Key}}.
+// The non-2xx responses fail the generation with ErrHTTPStatus.
+// The client defaults to an http.Client with the timeout of 15s.
+func HTTPGenerator(client *http.Client, urlTemplate string) gokv.GeneratorFn {
+	if client == nil {
+		client = &http.Client{Timeout: 15 * time.Second}
+	}
+
+	return func(k string) (v string, fns []gokv.OptionFn, er error) {
+		u := strings.ReplaceAll(urlTemplate, "{{.Key}}", url.PathEscape(k))
+		rsp, err := client.Get(u)
+		if err != nil {
+			return "", nil, err
+		}
+
+		defer func() { er = multierr.Append(er, rsp.Body.Close()) }()
+
+		if rsp.StatusCode < 200 || rsp.StatusCode >= 300 {
+			_, _ = io.Copy(io.Discard, rsp.Body)
+			return "", nil, ErrHTTPStatus{URL: u, StatusCode: rsp.StatusCode}
+		}
+
+		body, err := io.ReadAll(rsp.Body)
+		if err != nil {
+			return "", nil, err
+		}
+
+		return string(body), nil, nil
+	}
+}
@@ -0,0 +1,54 @@
+package sqlc_test
+
+import (
+	"errors"
+	"github.com/bingoohuang/gokv/pkg/sqlc"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHTTPGenerator(t *testing.T) {
+	var paths []string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.EscapedPath())
+		if r.URL.Path == "/config/missing" {
+			http.NotFound(w, r)
+			return
+		}
+
+		_, _ = w.Write([]byte("upstream " + r.URL.Path))
+	}))
+	defer upstream.Close()
+
+	dsn := startTestServer(t)
+	client := sqlc.NewClient(sqlc.Config{DataSourceName: dsn, SetSQL: insertSetSQL})
+	defer client.Close()
+
+	fn := sqlc.HTTPGenerator(upstream.Client(), upstream.URL+"/config/{{.Key}}")
+
+	found, v, _, err := client.Get("a b", fn)
+	assert.Nil(t, err)
+	assert.True(t, found)
+	assert.Equal(t, "upstream /config/a b", v)
+	assert.Equal(t, []string{"/config/a%20b"}, paths)
+
+	// the generated value is persisted
+	reader := sqlc.NewClient(sqlc.Config{DataSourceName: dsn})
+	defer reader.Close()
+
+	found, v, _, err = reader.Get("a b", nil)
+	assert.Nil(t, err)
+	assert.True(t, found)
+	assert.Equal(t, "upstream /config/a b", v)
+
+	_, _, _, err = client.Get("missing", fn)
+	var statusErr sqlc.ErrHTTPStatus
+	assert.True(t, errors.As(err, &statusErr))
+	assert.Equal(t, http.StatusNotFound, statusErr.StatusCode)
+
+	found, _, _, err = reader.Get("missing", nil)
+	assert.Nil(t, err)
+	assert.False(t, found, "nothing is persisted on the upstream errors")
+}
@@ -0,0 +1,40 @@
+package sqlc
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// SHA256Hex is a KeyHashFn which hashes the key to its 64 chars SHA-256 hex.
+func SHA256Hex(k string) string {
+	h := sha256.Sum256([]byte(k))
+	return hex.EncodeToString(h[:])
+}
+
+// backendKey returns the key stored in the database for the logical key k,
+// and remembers the mapping for logicalKey.
+func (c *Client) backendKey(k string) string {
+	if c.KeyHashFn == nil {
+		return k
+	}
+
+	hashed := c.KeyHashFn(k)
+	c.hashedKeys.Store(hashed, k)
+
+	return hashed
+}
+
+// logicalKey returns the logical key of the key read from the database.
+// The key is returned as is when it is not known to this client,
+// e.g. it is stored by another process, or it is the original key selected from a separate column.
+func (c *Client) logicalKey(k string) string {
+	if c.KeyHashFn == nil {
+		return k
+	}
+
+	if logical, ok := c.hashedKeys.Load(k); ok {
+		return logical.(string)
+	}
+
+	return k
+}
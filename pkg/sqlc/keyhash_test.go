@@ -0,0 +1,49 @@
+package sqlc_test
+
+import (
+	"github.com/bingoohuang/gokv/pkg/sqlc"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestKeyHashFn(t *testing.T) {
+	dsn := startTestServer(t)
+
+	// the key column is varchar(10)
+	const longKey = "a-key-exceeding-the-column-limit"
+	shortHash := func(k string) string { return sqlc.SHA256Hex(k)[:10] }
+
+	client := sqlc.NewClient(sqlc.Config{DataSourceName: dsn, SetSQL: insertSetSQL})
+	defer client.Close()
+
+	assert.NotNil(t, client.Set(longKey, "value"))
+
+	client = sqlc.NewClient(sqlc.Config{DataSourceName: dsn, SetSQL: insertSetSQL, KeyHashFn: shortHash})
+	defer client.Close()
+
+	assert.Nil(t, client.Set(longKey, "value"))
+
+	keys, err := client.Keys()
+	assert.Nil(t, err)
+	assert.ElementsMatch(t, []string{"Key1", "Key2", "Key3", longKey}, keys)
+
+	kvs, err := client.All()
+	assert.Nil(t, err)
+	assert.Equal(t, "value", kvs[longKey])
+
+	// another client reads the value by the logical key
+	reader := sqlc.NewClient(sqlc.Config{DataSourceName: dsn, KeyHashFn: shortHash})
+	defer reader.Close()
+
+	found, v, _, err := reader.Get(longKey, nil)
+	assert.Nil(t, err)
+	assert.True(t, found)
+	assert.Equal(t, "value", v)
+
+	raw := sqlc.NewClient(sqlc.Config{DataSourceName: dsn})
+	defer raw.Close()
+
+	keys, err = raw.Keys()
+	assert.Nil(t, err)
+	assert.Contains(t, keys, shortHash(longKey))
+}
@@ -0,0 +1,81 @@
+package sqlc
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/bingoohuang/gokv"
+	"go.uber.org/multierr"
+)
+
+// ErrLockNotAcquired is the error when the advisory lock is not acquired, e.g. GET_LOCK timed out.
+var ErrLockNotAcquired = errors.New("advisory lock not acquired")
+
+// generateLocked generates the value of the key holding the advisory lock of it,
+// the value is read again on the primary after the lock is acquired in case another node has generated it.
+func (c *Client) generateLocked(ctx context.Context, k string, fn gokv.GeneratorFn) (
+	found bool, v string, option gokv.Option, er error) {
+	if c.LockSQL == "" || c.UnlockSQL == "" {
+		return false, "", option, errors.New("LockSQL and UnlockSQL are required for GenerateLock")
+	}
+
+	data := map[string]string{"Key": c.backendKey(k), "RawKey": k, "LockKey": "gokv:" + SHA256Hex(k)[:32]}
+	lockSQL, lockArgs, err := c.render(c.LockSQL, data)
+	if err != nil {
+		return false, "", option, err
+	}
+	unlockSQL, unlockArgs, err := c.render(c.UnlockSQL, data)
+	if err != nil {
+		return false, "", option, err
+	}
+
+	db, err := c.openDB(c.DataSourceName)
+	if err != nil {
+		return false, "", option, err
+	}
+
+	lockCtx, cancel := c.queryTimeout(ctx)
+	defer cancel()
+
+	// the advisory locks are bound to the session, so both lock and unlock run on the same connection
+	conn, err := db.Conn(lockCtx)
+	if err != nil {
+		return false, "", option, err
+	}
+
+	defer func() { er = multierr.Append(er, conn.Close()) }()
+
+	c.logQuery(lockSQL)
+	var acquired sql.NullString
+	c.metrics.query()
+	if err := conn.QueryRowContext(lockCtx, lockSQL, lockArgs...).Scan(&acquired); err != nil {
+		return false, "", option, err
+	}
+	if acquired.Valid && acquired.String == "0" {
+		return false, "", option, ErrLockNotAcquired
+	}
+
+	defer func() {
+		// released with a fresh context, even when lockCtx is expired
+		unlockCtx, unlockCancel := context.WithTimeout(context.Background(), c.QueryTimeout)
+		defer unlockCancel()
+
+		c.logQuery(unlockSQL)
+		var released sql.NullString
+		c.metrics.query()
+		er = multierr.Append(er, conn.QueryRowContext(unlockCtx, unlockSQL, unlockArgs...).Scan(&released))
+	}()
+
+	// read again on the locked connection, since the replica or the batcher may not see the value
+	// generated by another node yet, and another connection may not be available while this one is held
+	found, v, rawOption, err := c.getOn(lockCtx, conn, k)
+	if err != nil {
+		return false, "", option, err
+	}
+	if found {
+		option, err = c.loaded(k, v, rawOption)
+		return err == nil, v, option, err
+	}
+
+	return c.generate(ctx, k, fn)
+}
@@ -0,0 +1,126 @@
+package sqlc_test
+
+import (
+	"database/sql/driver"
+	"errors"
+	"github.com/bingoohuang/gokv"
+	"github.com/bingoohuang/gokv/pkg/sqlc"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGenerateLock(t *testing.T) {
+	d, driverName := newFakeDriver()
+	lockResult := "1"
+	d.Query = func(query string, _ []driver.NamedValue) ([]string, [][]driver.Value, error) {
+		if strings.HasPrefix(query, "SELECT GET_LOCK") {
+			return []string{"r"}, [][]driver.Value{{lockResult}}, nil
+		}
+		if strings.HasPrefix(query, "SELECT RELEASE_LOCK") {
+			return []string{"r"}, [][]driver.Value{{"1"}}, nil
+		}
+		return []string{"v"}, nil, nil
+	}
+
+	lockKey := "gokv:" + sqlc.SHA256Hex("k")[:32]
+	lockSQL, unlockSQL := sqlc.AdvisoryLockSQL("mysql")
+	client := sqlc.NewClient(sqlc.Config{
+		DriverName:   driverName,
+		GetSQL:       "select v from kv where k = '{{.Key}}'",
+		SetSQL:       "insert into kv(k, v) values('{{.Key}}', '{{.Value}}')",
+		GenerateLock: true,
+		LockSQL:      lockSQL,
+		UnlockSQL:    unlockSQL,
+	})
+	defer client.Close()
+
+	generated := 0
+	generator := func(k string) (string, []gokv.OptionFn, error) {
+		generated++
+		return "generated", nil, nil
+	}
+
+	found, v, _, err := client.Get("k", generator)
+	assert.Nil(t, err)
+	assert.True(t, found)
+	assert.Equal(t, "generated", v)
+	assert.Equal(t, 1, generated)
+	assert.Equal(t, []string{
+		"select v from kv where k = 'k'",
+		"SELECT GET_LOCK('" + lockKey + "', 10)",
+		"select v from kv where k = 'k'",
+		"insert into kv(k, v) values('k', 'generated')",
+		"SELECT RELEASE_LOCK('" + lockKey + "')",
+	}, d.Statements)
+
+	// the lock is released when the generator fails
+	assert.Nil(t, client.Del("k"))
+	d.Statements = nil
+	errGenerate := errors.New("generate failed")
+	_, _, _, err = client.Get("k", func(string) (string, []gokv.OptionFn, error) { return "", nil, errGenerate })
+	assert.True(t, errors.Is(err, errGenerate))
+	assert.Equal(t, "SELECT RELEASE_LOCK('"+lockKey+"')", d.Statements[len(d.Statements)-1])
+
+	lockResult = "0"
+	_, _, _, err = client.Get("k", generator)
+	assert.True(t, errors.Is(err, sqlc.ErrLockNotAcquired))
+	assert.Equal(t, 1, generated)
+
+	assert.Nil(t, client.Close())
+	conns, rows := d.Opened()
+	assert.Equal(t, 0, conns)
+	assert.Equal(t, 0, rows)
+}
+
+func TestGenerateLockReadsLockedConn(t *testing.T) {
+	d, driverName := newFakeDriver()
+	locked := false
+	d.Query = func(query string, _ []driver.NamedValue) ([]string, [][]driver.Value, error) {
+		switch {
+		case strings.HasPrefix(query, "SELECT GET_LOCK"):
+			locked = true
+			return []string{"r"}, [][]driver.Value{{"1"}}, nil
+		case strings.HasPrefix(query, "SELECT RELEASE_LOCK"):
+			return []string{"r"}, [][]driver.Value{{"1"}}, nil
+		case locked && query == "select v from kv where k = 'k'":
+			// generated by another node while waiting for the lock
+			return []string{"v"}, [][]driver.Value{{"other"}}, nil
+		}
+		return []string{"k", "v"}, nil, nil
+	}
+
+	lockSQL, unlockSQL := sqlc.AdvisoryLockSQL("mysql")
+	client := sqlc.NewClient(sqlc.Config{
+		DriverName:         driverName,
+		ReadDataSourceName: "replica",
+		BatchWindow:        time.Millisecond,
+		GetSQL:             "select v from kv where k = '{{.Key}}'",
+		GenerateLock:       true,
+		LockSQL:            lockSQL,
+		UnlockSQL:          unlockSQL,
+	})
+	defer client.Close()
+
+	found, v, _, err := client.Get("k", func(string) (string, []gokv.OptionFn, error) {
+		t.Fatal("the value generated by another node is read on the locked connection")
+		return "", nil, nil
+	})
+	assert.Nil(t, err)
+	assert.True(t, found)
+	assert.Equal(t, "other", v)
+
+	lockKey := "gokv:" + sqlc.SHA256Hex("k")[:32]
+	statements := d.Statements[len(d.Statements)-3:]
+	assert.Equal(t, []string{
+		"SELECT GET_LOCK('" + lockKey + "', 10)",
+		"select v from kv where k = 'k'",
+		"SELECT RELEASE_LOCK('" + lockKey + "')",
+	}, statements, "the batcher is bypassed by the read holding the lock")
+
+	assert.Nil(t, client.Close())
+	conns, rows := d.Opened()
+	assert.Equal(t, 0, conns)
+	assert.Equal(t, 0, rows)
+}
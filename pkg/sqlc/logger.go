@@ -0,0 +1,56 @@
+package sqlc
+
+import (
+	"log"
+	"regexp"
+	"time"
+)
+
+// Logger is the logger of the client, e.g. *log.Logger.
+// The messages are prefixed with the levels like D! for debug and W! for warning.
+type Logger interface {
+	Printf(format string, v ...interface{})
+}
+
+// stdLogger logs by the standard logger of the log package.
+type stdLogger struct{}
+
+func (stdLogger) Printf(format string, v ...interface{}) { log.Printf(format, v...) }
+
+// quotedOrWhere matches the quoted literals, with the quotes escaped by doubling them, and the where keyword.
+var quotedOrWhere = regexp.MustCompile(`'(?:[^']|'')*'|(?i:\bwhere\b)`)
+
+// RedactValues is the default RedactFn which replaces the quoted literals with '***', e.g. the values lists
+// and the assignments, except the ones after where, which are the keys looked up, e.g.
+// insert into kv(k, v) values('***', '***') on duplicate key update v = '***',
+// update kv set v = '***' where k = 'k1'. The arguments bound by {{arg}} are never logged.
+func RedactValues(query string) string {
+	inWhere := false
+	return quotedOrWhere.ReplaceAllStringFunc(query, func(s string) string {
+		if s[0] != '\'' {
+			inWhere = true
+			return s
+		}
+		if inWhere {
+			return s
+		}
+		return "'***'"
+	})
+}
+
+// logQuery logs the query redacted by RedactFn in the debug level.
+func (c *Client) logQuery(query string) {
+	c.Logger.Printf("D! query: %s", c.RedactFn(query))
+}
+
+// logSlow logs a warning when the query of the operation op on the key k started at start
+// takes longer than SlowQueryThreshold.
+func (c *Client) logSlow(op, k string, start time.Time) {
+	if c.SlowQueryThreshold <= 0 {
+		return
+	}
+
+	if d := time.Since(start); d > c.SlowQueryThreshold {
+		c.Logger.Printf("W! slow query op:%s, key:%s, duration:%s", op, k, d)
+	}
+}
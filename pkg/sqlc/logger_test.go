@@ -0,0 +1,118 @@
+package sqlc_test
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"github.com/bingoohuang/gokv/pkg/sqlc"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type recordingLogger struct {
+	sync.Mutex
+	messages []string
+}
+
+func (l *recordingLogger) Printf(format string, v ...interface{}) {
+	l.Lock()
+	defer l.Unlock()
+
+	l.messages = append(l.messages, fmt.Sprintf(format, v...))
+}
+
+func (l *recordingLogger) warnings() (warnings []string) {
+	l.Lock()
+	defer l.Unlock()
+
+	for _, m := range l.messages {
+		if strings.HasPrefix(m, "W! ") {
+			warnings = append(warnings, m)
+		}
+	}
+
+	return warnings
+}
+
+func TestSlowQueryThreshold(t *testing.T) {
+	d, driverName := newFakeDriver()
+	d.Query = func(query string, _ []driver.NamedValue) ([]string, [][]driver.Value, error) {
+		if strings.Contains(query, "'slow'") {
+			time.Sleep(30 * time.Millisecond)
+		}
+		return []string{"v"}, nil, nil
+	}
+
+	logger := &recordingLogger{}
+	client := sqlc.NewClient(sqlc.Config{
+		DriverName:         driverName,
+		Logger:             logger,
+		SlowQueryThreshold: 10 * time.Millisecond,
+	})
+	defer client.Close()
+
+	_, _, _, err := client.Get("fast", nil)
+	assert.Nil(t, err)
+	assert.Empty(t, logger.warnings())
+	assert.NotEmpty(t, logger.messages, "the debug queries are logged by the logger too")
+
+	_, _, _, err = client.Get("slow", nil)
+	assert.Nil(t, err)
+	warnings := logger.warnings()
+	assert.Len(t, warnings, 1)
+	assert.Contains(t, warnings[0], "slow query op:get, key:slow, duration:")
+}
+
+func TestRedactFn(t *testing.T) {
+	assert.Equal(t, "update kv set v = '***' where k = 'it''s' and state = 1",
+		sqlc.RedactValues("update kv set v = 'secret where' where k = 'it''s' and state = 1"))
+
+	// the literals of the values lists of DefaultSetSQL are redacted too
+	rendered := strings.NewReplacer("{{.Key}}", "k1", "{{.Value}}", "my-password",
+		"{{.Time}}", "2021-03-01 10:00:00.000").Replace(sqlc.DefaultSetSQL)
+	redacted := sqlc.RedactValues(rendered)
+	assert.NotContains(t, redacted, "my-password")
+	assert.Contains(t, redacted, "values('***', '***', 1, '***')")
+	assert.Contains(t, redacted, "on duplicate key update v = '***', updated = '***', state = 1")
+
+	_, driverName := newFakeDriver()
+
+	logger := &recordingLogger{}
+	client := sqlc.NewClient(sqlc.Config{
+		DriverName: driverName,
+		SetSQL:     "update kv set v = '{{.Value}}' where k = '{{.Key}}'",
+		Logger:     logger,
+	})
+	defer client.Close()
+
+	assert.Nil(t, client.Set("k1", "my-password"))
+	assert.Contains(t, logger.messages, "D! query: update kv set v = '***' where k = 'k1'")
+	for _, m := range logger.messages {
+		assert.NotContains(t, m, "my-password")
+	}
+
+	// the default SetSQL logs no value either
+	logger = &recordingLogger{}
+	defaults := sqlc.NewClient(sqlc.Config{DriverName: driverName, Logger: logger})
+	defer defaults.Close()
+
+	assert.Nil(t, defaults.Set("k1", "my-password"))
+	assert.NotEmpty(t, logger.messages)
+	for _, m := range logger.messages {
+		assert.NotContains(t, m, "my-password")
+	}
+
+	logger = &recordingLogger{}
+	raw := sqlc.NewClient(sqlc.Config{
+		DriverName: driverName,
+		SetSQL:     "update kv set v = '{{.Value}}' where k = '{{.Key}}'",
+		Logger:     logger,
+		RedactFn:   func(sql string) string { return sql },
+	})
+	defer raw.Close()
+
+	assert.Nil(t, raw.Set("k1", "my-password"))
+	assert.Contains(t, logger.messages, "D! query: update kv set v = 'my-password' where k = 'k1'")
+}
@@ -0,0 +1,52 @@
+package sqlc_test
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"github.com/bingoohuang/gokv/pkg/sqlc"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestMaxResults(t *testing.T) {
+	d, driverName := newFakeDriver()
+
+	rows := 10
+	d.Query = func(string, []driver.NamedValue) ([]string, [][]driver.Value, error) {
+		var values [][]driver.Value
+		for i := 0; i < rows; i++ {
+			values = append(values, []driver.Value{fmt.Sprintf("k%d", i), "v"})
+		}
+		return []string{"k", "v"}, values, nil
+	}
+
+	client := sqlc.NewClient(sqlc.Config{DriverName: driverName, MaxResults: 5})
+	defer client.Close()
+
+	_, err := client.Keys()
+	var tooLarge sqlc.ErrResultTooLarge
+	assert.True(t, errors.As(err, &tooLarge))
+	assert.Equal(t, 5, tooLarge.Limit)
+
+	_, err = client.All()
+	assert.Equal(t, sqlc.ErrResultTooLarge{Limit: 5}, err)
+	assert.Empty(t, client.CacheSnapshot(), "the cache is not replaced")
+
+	rows = 5
+	keys, err := client.Keys()
+	assert.Nil(t, err)
+	assert.Len(t, keys, 5)
+
+	kvs, err := client.All()
+	assert.Nil(t, err)
+	assert.Len(t, kvs, 5)
+
+	rows = 10
+	unlimited := sqlc.NewClient(sqlc.Config{DriverName: driverName})
+	defer unlimited.Close()
+
+	keys, err = unlimited.Keys()
+	assert.Nil(t, err)
+	assert.Len(t, keys, 10)
+}
@@ -0,0 +1,233 @@
+package sqlc
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/bingoohuang/gokv"
+	"github.com/bingoohuang/gokv/pkg/codec"
+	"go.uber.org/multierr"
+	"time"
+)
+
+// MergeArrayPolicy decides how Merge merges an array of the patch into the current value.
+type MergeArrayPolicy int
+
+const (
+	// MergeArraysReplace replaces the current array by the array of the patch.
+	MergeArraysReplace MergeArrayPolicy = iota
+	// MergeArraysAppend appends the elements of the patch array to the current array.
+	MergeArraysAppend
+)
+
+// Merge deep-merges the patch into the JSON object value of the key, which is created when the key is missing.
+// The nested objects are merged recursively, the fields of the nil values in the patch are removed
+// like the JSON merge patch of RFC 7386, and the arrays are merged by MergeArrays.
+// The value is decoded by the Codec, read by MergeGetSQL and written back by SetSQL with its option kept
+// in a transaction to avoid the lost updates, unless MergeSQL is set to merge in the database.
+func (c *Client) Merge(k string, patch map[string]interface{}) (er error) {
+	defer c.metrics.observe("merge", time.Now())
+
+	if c.MergeSQL != "" {
+		return c.mergeSQL(k, patch)
+	}
+
+	db, err := c.openDB(c.DataSourceName)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), c.QueryTimeout)
+	defer cancel()
+
+	var oldV, newV string
+	var option gokv.Option
+	c.wrote(k)
+	if err := c.retry(ctx, true, func() (err error) {
+		oldV, newV, option, err = c.mergeTx(ctx, db, k, patch)
+		return err
+	}); err != nil {
+		return err
+	}
+
+	c.cacheSet(k, newV, option)
+	c.audit("merge", k, oldV, newV)
+
+	return nil
+}
+
+// mergeTx reads, merges and writes back the value of the key in a transaction.
+func (c *Client) mergeTx(ctx context.Context, db *sql.DB, k string, patch map[string]interface{}) (
+	oldV, newV string, option gokv.Option, er error) {
+	defer c.logSlow("merge", k, time.Now())
+
+	var query string
+	var args []interface{}
+	var err error
+	// GetSQL is read with its ArgBindings unless MergeGetSQL is set
+	name, sqlTemplate := "GetSQL", c.GetSQL
+	if c.MergeGetSQL != "" {
+		name, sqlTemplate = "MergeGetSQL", c.MergeGetSQL
+	}
+
+	if c.QueryBuilder != nil {
+		query, args = c.QueryBuilder.BuildGet(c.backendKey(k))
+	} else if query, args, err = c.renderArgs(name, sqlTemplate,
+		map[string]interface{}{"Key": c.backendKey(k), "RawKey": k}); err != nil {
+		return "", "", option, err
+	}
+	c.logQuery(query)
+
+	tx, release, err := c.beginTx(ctx, db)
+	if err != nil {
+		return "", "", option, err
+	}
+
+	// the connection is released after the transaction is committed or rolled back below
+	defer func() { er = multierr.Append(er, release()) }()
+
+	defer func() {
+		if er != nil {
+			if err := tx.Rollback(); !errors.Is(err, sql.ErrTxDone) {
+				er = multierr.Append(er, err)
+			}
+		}
+	}()
+
+	oldV, rawOption, err := c.queryTx(ctx, tx, k, query, args)
+	if err != nil {
+		return "", "", option, err
+	}
+
+	current := make(map[string]interface{})
+	if oldV != "" {
+		if err := c.codecOf(k).Unmarshal([]byte(oldV), &current); err != nil {
+			return "", "", option, fmt.Errorf("key:%s, error:%w", k, codec.WrapError("unmarshal", &current, err))
+		}
+	}
+
+	if option, err = c.decodeOption(k, rawOption); err != nil {
+		return "", "", option, err
+	}
+
+	merged := mergePatch(current, patch, c.MergeArrays)
+	data, err := c.codecOf(k).Marshal(merged)
+	if err != nil {
+		return "", "", option, fmt.Errorf("key:%s, error:%w", k, codec.WrapError("marshal", merged, err))
+	}
+
+	newV = string(data)
+	if err := c.validateKV(k, newV); err != nil {
+		return "", "", option, err
+	}
+
+	stored, err := c.compress(newV)
+	if err != nil {
+		return "", "", option, err
+	}
+
+	if query, args, err = c.setStatement(c.SetSQL, c.setArgs(), k, stored, option, c.Now()); err != nil {
+		return "", "", option, err
+	}
+
+	start := time.Now()
+	c.metrics.query()
+	_, err = tx.ExecContext(ctx, query, args...)
+	c.metrics.observePhase("sql", start)
+	if err != nil {
+		return "", "", option, err
+	}
+
+	return oldV, newV, option, tx.Commit()
+}
+
+// queryTx queries the value and the raw option of the key in the transaction.
+func (c *Client) queryTx(ctx context.Context, tx *sql.Tx, k, query string, args []interface{}) (
+	v string, rawOption []byte, er error) {
+	start := time.Now()
+	c.metrics.query()
+	rows, err := tx.QueryContext(ctx, query, args...)
+	c.metrics.observePhase("sql", start)
+	if err != nil {
+		return "", nil, err
+	}
+
+	defer func() { er = multierr.Append(er, drainAndClose(rows)) }()
+
+	cols, _ := rows.Columns()
+	for row := 0; rows.Next(); row++ {
+		if row >= 1 {
+			return "", nil, fmt.Errorf("key:%s, error:%w", k, ErrTooManyValues)
+		}
+
+		if v, rawOption, err = c.scanValue(k, rows, cols); err != nil {
+			return "", nil, err
+		}
+	}
+
+	return v, rawOption, nil
+}
+
+// mergeSQL merges the patch in the database by MergeSQL, and evicts the key from the cache.
+func (c *Client) mergeSQL(k string, patch map[string]interface{}) (er error) {
+	data, err := json.Marshal(patch)
+	if err != nil {
+		return fmt.Errorf("key:%s, error:%w", k, codec.WrapError("marshal", patch, err))
+	}
+
+	query, args, err := c.render(c.MergeSQL, map[string]interface{}{
+		"Key":        c.backendKey(k),
+		"RawKey":     k,
+		"Patch":      string(data),
+		"Time":       c.formatTime(),
+		"ServerTime": c.ServerTimeSQL,
+	})
+	if err != nil {
+		return err
+	}
+	c.logQuery(query)
+
+	db, err := c.openDB(c.DataSourceName)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), c.QueryTimeout)
+	defer cancel()
+
+	if _, err := c.execContext(ctx, db, "merge", k, query, args...); err != nil {
+		return err
+	}
+
+	c.Invalidate(k)
+
+	return nil
+}
+
+// mergePatch merges the patch into dst recursively and returns dst.
+func mergePatch(dst, patch map[string]interface{}, arrays MergeArrayPolicy) map[string]interface{} {
+	for name, pv := range patch {
+		switch p := pv.(type) {
+		case nil:
+			delete(dst, name)
+		case map[string]interface{}:
+			d, ok := dst[name].(map[string]interface{})
+			if !ok {
+				d = make(map[string]interface{})
+			}
+			dst[name] = mergePatch(d, p, arrays)
+		case []interface{}:
+			if d, ok := dst[name].([]interface{}); ok && arrays == MergeArraysAppend {
+				dst[name] = append(d, p...)
+			} else {
+				dst[name] = p
+			}
+		default:
+			dst[name] = pv
+		}
+	}
+
+	return dst
+}
@@ -0,0 +1,130 @@
+package sqlc_test
+
+import (
+	"database/sql/driver"
+	"errors"
+	"github.com/bingoohuang/gokv/pkg/sqlc"
+	"github.com/bingoohuang/gokv/pkg/util"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func TestMerge(t *testing.T) {
+	d, driverName := newFakeDriver()
+
+	stored := ""
+	d.Query = func(string, []driver.NamedValue) ([]string, [][]driver.Value, error) {
+		if stored == "" {
+			return []string{"v"}, nil, nil
+		}
+		return []string{"v"}, [][]driver.Value{{stored}}, nil
+	}
+	d.Exec = func(_ string, args []driver.NamedValue) (driver.Result, error) {
+		stored = args[0].Value.(string)
+		return driver.RowsAffected(1), nil
+	}
+
+	client := sqlc.NewClient(sqlc.Config{
+		DriverName: driverName,
+		SetSQL:     "update kv set v = {{arg .Value}} where k = {{arg .Key}}",
+	})
+	defer client.Close()
+
+	// adding the fields to a missing key
+	assert.Nil(t, client.Merge("k1", map[string]interface{}{
+		"name": "bingoo", "age": 18, "tags": []interface{}{"a"}, "addr": map[string]interface{}{"city": "bj"},
+	}))
+	assert.JSONEq(t, `{"name":"bingoo","age":18,"tags":["a"],"addr":{"city":"bj"}}`, stored)
+
+	// overwriting and removing the fields, merging the nested objects
+	assert.Nil(t, client.Merge("k1", map[string]interface{}{
+		"age": 19, "name": nil, "tags": []interface{}{"b"}, "addr": map[string]interface{}{"zip": "100000"},
+	}))
+	assert.JSONEq(t, `{"age":19,"tags":["b"],"addr":{"city":"bj","zip":"100000"}}`, stored)
+
+	found, v, _, err := client.Get("k1", nil)
+	assert.Nil(t, err)
+	assert.True(t, found)
+	assert.JSONEq(t, stored, v)
+
+	appender := sqlc.NewClient(sqlc.Config{
+		DriverName:  driverName,
+		SetSQL:      "update kv set v = {{arg .Value}} where k = {{arg .Key}}",
+		MergeArrays: sqlc.MergeArraysAppend,
+	})
+	defer appender.Close()
+
+	assert.Nil(t, appender.Merge("k1", map[string]interface{}{"tags": []interface{}{"c"}}))
+	assert.JSONEq(t, `{"age":19,"tags":["b","c"],"addr":{"city":"bj","zip":"100000"}}`, stored)
+
+	stored = `"not an object"`
+	assert.NotNil(t, client.Merge("k1", map[string]interface{}{"age": 20}))
+	assert.Equal(t, `"not an object"`, stored)
+}
+
+func TestMergeSQL(t *testing.T) {
+	d, driverName := newFakeDriver()
+
+	var patch string
+	d.Exec = func(_ string, args []driver.NamedValue) (driver.Result, error) {
+		patch = args[0].Value.(string)
+		return driver.RowsAffected(1), nil
+	}
+
+	client := sqlc.NewClient(sqlc.Config{
+		DriverName: driverName,
+		MergeSQL:   "update kv set v = JSON_MERGE_PATCH(v, {{arg .Patch}}) where k = '{{.Key}}'",
+	})
+	defer client.Close()
+
+	assert.Nil(t, client.Merge("k1", map[string]interface{}{"age": 19, "name": nil}))
+	assert.JSONEq(t, `{"age":19,"name":null}`, patch)
+	assert.Equal(t, 1, d.StatementCount())
+	assert.True(t, strings.HasPrefix(d.Statements[0], "update kv set v = JSON_MERGE_PATCH"))
+}
+
+func TestMergeArgBindings(t *testing.T) {
+	d, driverName := newFakeDriver()
+
+	var queryArgs []driver.NamedValue
+	d.Query = func(_ string, args []driver.NamedValue) ([]string, [][]driver.Value, error) {
+		queryArgs = args
+		return []string{"v"}, [][]driver.Value{{`{"a":1}`}}, nil
+	}
+
+	client := sqlc.NewClient(sqlc.Config{
+		DriverName:  driverName,
+		GetSQL:      "select v from kv where k = ? and state = 1",
+		SetSQL:      "update kv set v = ? where k = ?",
+		ArgBindings: map[string][]string{"GetSQL": {"Key"}, "SetSQL": {"Value", "Key"}},
+	})
+	defer client.Close()
+
+	assert.Nil(t, client.Merge("k'1", map[string]interface{}{"b": 2}))
+	assert.Equal(t, "select v from kv where k = ? and state = 1", d.Statements[0])
+	assert.Equal(t, []driver.NamedValue{{Ordinal: 1, Value: "k'1"}}, queryArgs)
+}
+
+func TestMergeValidation(t *testing.T) {
+	d, driverName := newFakeDriver()
+	d.Query = func(string, []driver.NamedValue) ([]string, [][]driver.Value, error) {
+		return []string{"v"}, [][]driver.Value{{`{"a":1}`}}, nil
+	}
+
+	var execs int
+	d.Exec = func(string, []driver.NamedValue) (driver.Result, error) {
+		execs++
+		return driver.RowsAffected(1), nil
+	}
+
+	client := sqlc.NewClient(sqlc.Config{
+		DriverName: driverName,
+		Validation: &util.ValidationConfig{MaxValueLen: 10},
+	})
+	defer client.Close()
+
+	err := client.Merge("k1", map[string]interface{}{"b": "a value too long"})
+	assert.True(t, errors.Is(err, util.ErrValueTooLong), "the merged value is validated")
+	assert.Equal(t, 0, execs)
+}
@@ -0,0 +1,116 @@
+package sqlc
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"sync/atomic"
+	"time"
+)
+
+// metrics holds the internal counters of the client.
+type metrics struct {
+	hits      uint64
+	misses    uint64
+	evictions uint64
+
+	opens       uint64
+	closes      uint64
+	queries     uint64
+	rowsScanned uint64
+	prepares    uint64
+
+	latency *prometheus.HistogramVec
+	phases  *prometheus.HistogramVec
+}
+
+func newMetrics() *metrics {
+	return &metrics{
+		latency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: "gokv",
+			Subsystem: "sqlc",
+			Name:      "operation_duration_seconds",
+			Help:      "The latency of the store operations.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"op"}),
+		phases: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: "gokv",
+			Subsystem: "sqlc",
+			Name:      "phase_duration_seconds",
+			Help:      "The latency of the phases of the store operations, render, codec and sql.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"phase"}),
+	}
+}
+
+func (m *metrics) hit()        { atomic.AddUint64(&m.hits, 1) }
+func (m *metrics) miss()       { atomic.AddUint64(&m.misses, 1) }
+func (m *metrics) evict(n int) { atomic.AddUint64(&m.evictions, uint64(n)) }
+func (m *metrics) query()      { atomic.AddUint64(&m.queries, 1) }
+func (m *metrics) scanned()    { atomic.AddUint64(&m.rowsScanned, 1) }
+func (m *metrics) counter(p *uint64) func() float64 {
+	return func() float64 { return float64(atomic.LoadUint64(p)) }
+}
+
+// observe records the latency of the operation op started at start.
+func (m *metrics) observe(op string, start time.Time) {
+	m.latency.WithLabelValues(op).Observe(time.Since(start).Seconds())
+}
+
+// observePhase records the latency of the phase started at start, which is one of
+// render for the SQL templates, codec for the encoding and decoding, and sql for the database round trips.
+func (m *metrics) observePhase(phase string, start time.Time) {
+	m.phases.WithLabelValues(phase).Observe(time.Since(start).Seconds())
+}
+
+// Collector returns a prometheus.Collector reporting the cache size, the cache hit/miss/eviction counters,
+// the database usage counters of Stats and the latency histograms of the operations and their phases.
+// Register it with a registry to expose the metrics.
+func (c *Client) Collector() prometheus.Collector {
+	opts := func(name, help string) prometheus.Opts {
+		return prometheus.Opts{Namespace: "gokv", Subsystem: "sqlc", Name: name, Help: help}
+	}
+
+	return collectors{
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts(opts("cache_size", "The number of the cached keys.")),
+			func() float64 { return float64(c.cacheSize()) }),
+		prometheus.NewCounterFunc(prometheus.CounterOpts(opts("cache_hits_total", "The number of the cache hits.")),
+			c.metrics.counter(&c.metrics.hits)),
+		prometheus.NewCounterFunc(prometheus.CounterOpts(opts("cache_misses_total", "The number of the cache misses.")),
+			c.metrics.counter(&c.metrics.misses)),
+		prometheus.NewCounterFunc(prometheus.CounterOpts(opts("cache_evictions_total", "The number of the cache evictions.")),
+			c.metrics.counter(&c.metrics.evictions)),
+		prometheus.NewCounterFunc(prometheus.CounterOpts(opts("sql_opens_total", "The number of the databases opened.")),
+			c.metrics.counter(&c.metrics.opens)),
+		prometheus.NewCounterFunc(prometheus.CounterOpts(opts("sql_closes_total", "The number of the databases closed.")),
+			c.metrics.counter(&c.metrics.closes)),
+		prometheus.NewCounterFunc(prometheus.CounterOpts(opts("sql_queries_total", "The number of the queries executed.")),
+			c.metrics.counter(&c.metrics.queries)),
+		prometheus.NewCounterFunc(prometheus.CounterOpts(opts("sql_rows_scanned_total", "The number of the rows scanned.")),
+			c.metrics.counter(&c.metrics.rowsScanned)),
+		prometheus.NewCounterFunc(prometheus.CounterOpts(opts("sql_prepares_total", "The number of the statements prepared.")),
+			c.metrics.counter(&c.metrics.prepares)),
+		c.metrics.latency,
+		c.metrics.phases,
+	}
+}
+
+func (c *Client) cacheSize() int {
+	c.cacheLock.Lock()
+	defer c.cacheLock.Unlock()
+
+	return len(c.Cache.Keys())
+}
+
+// collectors composes multiple collectors into one.
+type collectors []prometheus.Collector
+
+func (cs collectors) Describe(ch chan<- *prometheus.Desc) {
+	for _, c := range cs {
+		c.Describe(ch)
+	}
+}
+
+func (cs collectors) Collect(ch chan<- prometheus.Metric) {
+	for _, c := range cs {
+		c.Collect(ch)
+	}
+}
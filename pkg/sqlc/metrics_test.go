@@ -0,0 +1,75 @@
+package sqlc_test
+
+import (
+	"github.com/bingoohuang/gokv/pkg/sqlc"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestCollector(t *testing.T) {
+	client := sqlc.NewClient(sqlc.Config{DataSourceName: startTestServer(t), SetSQL: insertSetSQL})
+
+	registry := prometheus.NewRegistry()
+	assert.Nil(t, registry.Register(client.Collector()))
+
+	assert.Nil(t, client.Set("Key4", "v4"))
+	_, _, _, _ = client.Get("Key4", nil)
+	_, _, _, _ = client.Get("Key1", nil)
+	assert.Nil(t, client.Del("Key4"))
+
+	families, err := registry.Gather()
+	assert.Nil(t, err)
+
+	values := make(map[string]float64)
+	for _, f := range families {
+		m := f.GetMetric()[0]
+		switch {
+		case m.Gauge != nil:
+			values[f.GetName()] = m.Gauge.GetValue()
+		case m.Counter != nil:
+			values[f.GetName()] = m.Counter.GetValue()
+		case m.Histogram != nil:
+			values[f.GetName()] = float64(len(f.GetMetric()))
+		}
+	}
+
+	assert.Equal(t, map[string]float64{
+		"gokv_sqlc_cache_size":                 1,
+		"gokv_sqlc_cache_hits_total":           1,
+		"gokv_sqlc_cache_misses_total":         1,
+		"gokv_sqlc_cache_evictions_total":      1,
+		"gokv_sqlc_operation_duration_seconds": 3,
+		"gokv_sqlc_phase_duration_seconds":     3,
+		"gokv_sqlc_sql_opens_total":            1,
+		"gokv_sqlc_sql_closes_total":           0,
+		"gokv_sqlc_sql_queries_total":          3,
+		"gokv_sqlc_sql_rows_scanned_total":     1,
+		"gokv_sqlc_sql_prepares_total":         0,
+	}, values)
+}
+
+func TestPhaseDurations(t *testing.T) {
+	client := sqlc.NewClient(sqlc.Config{DataSourceName: startTestServer(t), SetSQL: insertSetSQL})
+
+	registry := prometheus.NewRegistry()
+	assert.Nil(t, registry.Register(client.Collector()))
+
+	assert.Nil(t, client.Set("Key4", "v4"))
+
+	families, err := registry.Gather()
+	assert.Nil(t, err)
+
+	counts := make(map[string]uint64)
+	for _, f := range families {
+		if f.GetName() != "gokv_sqlc_phase_duration_seconds" {
+			continue
+		}
+
+		for _, m := range f.GetMetric() {
+			counts[m.GetLabel()[0].GetValue()] = m.GetHistogram().GetSampleCount()
+		}
+	}
+
+	assert.Equal(t, map[string]uint64{"render": 1, "codec": 1, "sql": 1}, counts)
+}
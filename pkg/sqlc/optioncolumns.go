@@ -0,0 +1,99 @@
+package sqlc
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"github.com/bingoohuang/gokv"
+	"reflect"
+	"strconv"
+)
+
+// optionColumnValues formats the fields of the option mapped by OptionColumns to their column values:
+// the booleans as true or false, the integers and the durations in nanoseconds as decimals,
+// the strings as they are, and the others as JSON.
+func (c *Client) optionColumnValues(option gokv.Option) (map[string]string, error) {
+	values := make(map[string]string, len(c.OptionColumns))
+	ov := reflect.ValueOf(option)
+	for field, column := range c.OptionColumns {
+		fv := ov.FieldByName(field)
+		if !fv.IsValid() {
+			return nil, fmt.Errorf("OptionColumns: unknown option field %s", field)
+		}
+
+		switch fv.Kind() {
+		case reflect.Bool:
+			values[column] = strconv.FormatBool(fv.Bool())
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			values[column] = strconv.FormatInt(fv.Int(), 10)
+		case reflect.String:
+			values[column] = fv.String()
+		default:
+			data, err := json.Marshal(fv.Interface())
+			if err != nil {
+				return nil, err
+			}
+			values[column] = string(data)
+		}
+	}
+
+	return values, nil
+}
+
+// optionFromColumns assembles the option from the columns named by OptionColumns, and encodes it by the codec
+// of the key as the raw option. The raw option is nil when all the option columns are NULL or absent.
+func (c *Client) optionFromColumns(k string, names []string, columns []sql.NullString) ([]byte, error) {
+	fields := make(map[string]string, len(c.OptionColumns))
+	for field, column := range c.OptionColumns {
+		fields[column] = field
+	}
+
+	var option gokv.Option
+	ov := reflect.ValueOf(&option).Elem()
+	assembled := false
+	for i, name := range names {
+		field, ok := fields[name]
+		if !ok || !columns[i].Valid {
+			continue
+		}
+
+		fv := ov.FieldByName(field)
+		if !fv.IsValid() {
+			return nil, fmt.Errorf("OptionColumns: unknown option field %s", field)
+		}
+
+		if err := setOptionField(fv, columns[i].String); err != nil {
+			return nil, fmt.Errorf("key:%s, option column:%s, error:%w", k, name, err)
+		}
+		assembled = true
+	}
+
+	if !assembled {
+		return nil, nil
+	}
+
+	return c.codecOf(k).Marshal(option)
+}
+
+func setOptionField(fv reflect.Value, s string) error {
+	switch fv.Kind() {
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		fv.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return err
+		}
+		fv.SetInt(n)
+	case reflect.String:
+		fv.SetString(s)
+	default:
+		return json.Unmarshal([]byte(s), fv.Addr().Interface())
+	}
+
+	return nil
+}
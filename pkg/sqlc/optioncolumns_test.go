@@ -0,0 +1,42 @@
+package sqlc_test
+
+import (
+	"github.com/bingoohuang/gokv"
+	"github.com/bingoohuang/gokv/pkg/sqlc"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestOptionColumns(t *testing.T) {
+	config := sqlc.Config{
+		DataSourceName: startTestServer(t),
+		SetSQL: "insert into kvo(k, v, ttl, no_cache) " +
+			"values('{{.Key}}', '{{.Value}}', {{.Columns.ttl}}, '{{.Columns.no_cache}}')",
+		GetSQL:        "select v, ttl, no_cache from kvo where k = '{{.Key}}'",
+		OptionColumns: map[string]string{"TTL": "ttl", "NoCache": "no_cache"},
+		StrictColumns: true,
+	}
+
+	client := sqlc.NewClient(config)
+	defer client.Close()
+
+	assert.Nil(t, client.Set("k1", "v1", gokv.TTL(time.Minute)))
+	assert.Nil(t, client.Set("k2", "v2", gokv.TTL(time.Hour), gokv.NoCache()))
+
+	reader := sqlc.NewClient(config)
+	defer reader.Close()
+
+	for k, expected := range map[string]gokv.Option{
+		"k1": {TTL: time.Minute},
+		"k2": {TTL: time.Hour, NoCache: true},
+	} {
+		found, _, option, err := reader.Get(k, nil)
+		assert.Nil(t, err)
+		assert.True(t, found)
+		assert.Equal(t, expected, option)
+	}
+
+	config.OptionColumns = map[string]string{"Owner": "owner"}
+	assert.NotNil(t, sqlc.NewClient(config).Set("k3", "v3"))
+}
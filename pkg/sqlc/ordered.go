@@ -0,0 +1,44 @@
+package sqlc
+
+import (
+	"context"
+	"time"
+)
+
+// AllOrdered lists the key values in the store in the order of the keys by AllOrderedSQL,
+// and refreshes the cache with them like All. The database sorts all the rows for it,
+// so prefer All for the large stores when the order does not matter.
+func (c *Client) AllOrdered() (kvs []KV, er error) {
+	defer c.metrics.observe("all", time.Now())
+
+	query, args, err := c.render(c.AllOrderedSQL, map[string]string{})
+	if err != nil {
+		return nil, err
+	}
+	c.logQuery(query)
+
+	db, err := c.openDB(c.readDataSourceName(""))
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), c.QueryTimeout)
+	defer cancel()
+
+	m := make(map[string]string)
+	if err := c.eachKV(ctx, db, query, args, func(k, v string) error {
+		if c.MaxResults > 0 && len(kvs) == c.MaxResults {
+			return ErrResultTooLarge{Limit: c.MaxResults}
+		}
+
+		kvs = append(kvs, KV{Key: k, Value: v})
+		m[k] = v
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	c.replaceCache(m)
+
+	return kvs, nil
+}
@@ -0,0 +1,32 @@
+package sqlc_test
+
+import (
+	"github.com/bingoohuang/gokv"
+	"github.com/bingoohuang/gokv/pkg/sqlc"
+	"github.com/stretchr/testify/assert"
+	"sort"
+	"testing"
+)
+
+func TestAllOrdered(t *testing.T) {
+	dsn := startTestServer(t)
+	client := sqlc.NewClient(sqlc.Config{DataSourceName: dsn, SetSQL: insertSetSQL})
+	defer client.Close()
+
+	for _, k := range []string{"b", "a10", "a2", "a"} {
+		assert.Nil(t, client.Set(k, "v"+k))
+	}
+
+	var _ gokv.OrderedStore = client
+	kvs, err := client.AllOrdered()
+	assert.Nil(t, err)
+
+	var keys []string
+	for _, kv := range kvs {
+		keys = append(keys, kv.Key)
+	}
+	assert.Equal(t, []string{"Key1", "Key2", "Key3", "a", "a10", "a2", "b"}, keys)
+	assert.True(t, sort.StringsAreSorted(keys))
+	assert.Equal(t, gokv.KV{Key: "a10", Value: "va10"}, kvs[4])
+	assert.Len(t, client.CacheSnapshot(), 7, "the cache is refreshed")
+}
@@ -0,0 +1,58 @@
+package sqlc
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/bingoohuang/gokv"
+	"github.com/bingoohuang/gokv/pkg/codec"
+	"sync"
+	"time"
+)
+
+// optionEncoder is a JSON encoder over a reusable buffer.
+type optionEncoder struct {
+	buf bytes.Buffer
+	enc *json.Encoder
+}
+
+// optionEncoders pools the encoders of the options for the JSON codec, which is on the hot path of the writes.
+var optionEncoders = sync.Pool{New: func() interface{} {
+	e := &optionEncoder{}
+	e.enc = json.NewEncoder(&e.buf)
+	return e
+}}
+
+// maxPooledBuffer keeps the buffers grown by the huge options, e.g. with many tags, out of the pool.
+const maxPooledBuffer = 64 << 10
+
+// marshalOption encodes the option of the key by its codec, the same as the codec does,
+// while codec.JSON encodes by a pooled encoder to save the allocations.
+func (c *Client) marshalOption(k string, option gokv.Option) (string, error) {
+	defer c.metrics.observePhase("codec", time.Now())
+
+	cd := c.codecOf(k)
+	if _, ok := cd.(codec.JSONCodec); !ok {
+		data, err := cd.Marshal(option)
+		if err != nil {
+			return "", fmt.Errorf("key:%s, error:%w", k, codec.WrapError("marshal", option, err))
+		}
+
+		return string(data), nil
+	}
+
+	e := optionEncoders.Get().(*optionEncoder)
+	defer func() {
+		if e.buf.Cap() <= maxPooledBuffer {
+			optionEncoders.Put(e)
+		}
+	}()
+
+	e.buf.Reset()
+	if err := e.enc.Encode(option); err != nil {
+		return "", fmt.Errorf("key:%s, error:%w", k, codec.WrapError("marshal", option, err))
+	}
+
+	// Encode terminates the value by a newline, which json.Marshal does not
+	return string(bytes.TrimSuffix(e.buf.Bytes(), []byte("\n"))), nil
+}
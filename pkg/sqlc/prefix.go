@@ -0,0 +1,55 @@
+package sqlc
+
+import (
+	"context"
+	"strings"
+	"time"
+)
+
+// likeEscaper escapes the wildcards of LIKE by the default escape character \.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// GetByPrefix returns the key values of the keys with the prefix by a single query of PrefixSQL,
+// and caches them, keeping the cached options.
+func (c *Client) GetByPrefix(prefix string) (kvs map[string]string, er error) {
+	defer c.metrics.observe("getbyprefix", time.Now())
+
+	query, args, err := c.render(c.PrefixSQL, map[string]string{"Pattern": likeEscaper.Replace(prefix) + "%"})
+	if err != nil {
+		return nil, err
+	}
+	c.logQuery(query)
+
+	db, err := c.openDB(c.readDataSourceName(""))
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), c.QueryTimeout)
+	defer cancel()
+
+	kvs = make(map[string]string)
+	if err := c.eachKV(ctx, db, query, args, func(k, v string) error {
+		kvs[k] = v
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	now := c.Now()
+
+	c.cacheLock.Lock()
+	defer c.cacheLock.Unlock()
+
+	for k, v := range kvs {
+		if c.noCache[k] || !c.cacheable(k) {
+			continue
+		}
+
+		old, _ := c.Cache.Get(k)
+		c.Cache.Set(k, CacheValue{Value: v, Option: old.Option, UpdateTime: now, QueryHash: c.queryHash,
+			rawOption: old.rawOption})
+	}
+
+	return kvs, nil
+}
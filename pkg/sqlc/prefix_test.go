@@ -0,0 +1,57 @@
+package sqlc_test
+
+import (
+	"database/sql/driver"
+	"github.com/bingoohuang/gokv/pkg/sqlc"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestGetByPrefix(t *testing.T) {
+	dsn := startTestServer(t)
+	client := sqlc.NewClient(sqlc.Config{DataSourceName: dsn + "?interpolateParams=true", SetSQL: insertSetSQL})
+	defer client.Close()
+
+	for k, v := range map[string]string{"a%1": "v1", "a%2": "v2", "ab": "v3", "a_c": "v4", "b%1": "v5"} {
+		assert.Nil(t, client.Set(k, v))
+	}
+	client.InvalidateAll()
+
+	kvs, err := client.GetByPrefix("a%")
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"a%1": "v1", "a%2": "v2"}, kvs, "the % in the prefix is literal")
+
+	kvs, err = client.GetByPrefix("a_")
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"a_c": "v4"}, kvs, "the _ in the prefix is literal")
+
+	kvs, err = client.GetByPrefix("Key")
+	assert.Nil(t, err)
+	assert.Len(t, kvs, 3)
+
+	assert.Contains(t, client.CacheSnapshot(), "a%1", "the key values are cached")
+}
+
+func TestGetByPrefixSingleQuery(t *testing.T) {
+	d, driverName := newFakeDriver()
+
+	var pattern driver.Value
+	d.Query = func(_ string, args []driver.NamedValue) ([]string, [][]driver.Value, error) {
+		pattern = args[0].Value
+		return []string{"k", "v"}, [][]driver.Value{{"50%_off", "v1"}, {"50%_off!", "v2"}}, nil
+	}
+
+	client := sqlc.NewClient(sqlc.Config{DriverName: driverName})
+	defer client.Close()
+
+	kvs, err := client.GetByPrefix(`50%_off`)
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"50%_off": "v1", "50%_off!": "v2"}, kvs)
+	assert.Equal(t, 1, d.StatementCount())
+	assert.Equal(t, `50\%\_off%`, pattern)
+
+	_, v, _, err := client.Get("50%_off!", nil)
+	assert.Nil(t, err)
+	assert.Equal(t, "v2", v)
+	assert.Equal(t, 1, d.StatementCount(), "the key values are cached")
+}
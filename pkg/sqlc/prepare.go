@@ -0,0 +1,102 @@
+package sqlc
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"go.uber.org/multierr"
+	"sync/atomic"
+)
+
+// Prepare parses and memoizes the configured SQL templates ahead of the first operations,
+// and returns the errors of the malformed ones. In the PrepareStatements mode, it also prepares the statements
+// of the SQL without template actions against the databases, so that the first operations do not pay for them.
+// It is safe and useful to call during startup.
+func (c *Client) Prepare(ctx context.Context) (err error) {
+	for name, sqlTemplate := range map[string]string{
+		"AllSQL": c.AllSQL, "KeysSQL": c.KeysSQL, "GetSQL": c.GetSQL, "SetSQL": c.SetSQL, "DelSQL": c.DelSQL,
+		"SetBytesSQL": c.SetBytesSQL, "SetManySQL": c.SetManySQL, "VersionGetSQL": c.VersionGetSQL,
+		"SetIfVersionSQL": c.SetIfVersionSQL, "TouchSQL": c.TouchSQL, "FieldQuerySQL": c.FieldQuerySQL,
+		"LockSQL": c.LockSQL, "UnlockSQL": c.UnlockSQL, "MergeGetSQL": c.MergeGetSQL, "MergeSQL": c.MergeSQL,
+		"PrefixSQL": c.PrefixSQL, "ExportSQL": c.ExportSQL, "AllOrderedSQL": c.AllOrderedSQL,
+		"GetBatchSQL": c.GetBatchSQL,
+	} {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return multierr.Append(err, ctxErr)
+		}
+
+		if sqlTemplate == "" {
+			continue
+		}
+
+		if _, e := c.parse(sqlTemplate); e != nil {
+			err = multierr.Append(err, fmt.Errorf("%s: %w", name, e))
+		}
+	}
+
+	if err != nil || !c.PrepareStatements {
+		return err
+	}
+
+	for _, s := range []struct{ dataSourceName, query string }{
+		{c.readDataSourceName(""), c.GetSQL}, {c.readDataSourceName(""), c.KeysSQL},
+		{c.readDataSourceName(""), c.AllSQL}, {c.DataSourceName, c.SetSQL}, {c.DataSourceName, c.DelSQL},
+	} {
+		if !c.fixedSQL[s.query] {
+			continue
+		}
+
+		db, err := c.openDB(s.dataSourceName)
+		if err != nil {
+			return err
+		}
+		if _, err := c.stmt(ctx, db, s.query); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// stmtKey is the key of the prepared statement of the query on the database.
+type stmtKey struct {
+	db    *sql.DB
+	query string
+}
+
+// preparable tells whether the query is prepared in the PrepareStatements mode.
+func (c *Client) preparable(query string) bool { return c.PrepareStatements && c.fixedSQL[query] }
+
+// stmt returns the prepared statement of the query on db, which is prepared at its first use.
+func (c *Client) stmt(ctx context.Context, db *sql.DB, query string) (*sql.Stmt, error) {
+	key := stmtKey{db: db, query: query}
+	if s, ok := c.stmts.Load(key); ok {
+		return s.(*sql.Stmt), nil
+	}
+
+	s, err := db.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	atomic.AddUint64(&c.metrics.prepares, 1)
+
+	if actual, loaded := c.stmts.LoadOrStore(key, s); loaded {
+		// prepared concurrently by another operation
+		return actual.(*sql.Stmt), s.Close()
+	}
+
+	return s, nil
+}
+
+// closeStmts closes the prepared statements of db.
+func (c *Client) closeStmts(db *sql.DB) (er error) {
+	c.stmts.Range(func(key, s interface{}) bool {
+		if key.(stmtKey).db == db {
+			c.stmts.Delete(key)
+			er = multierr.Append(er, s.(*sql.Stmt).Close())
+		}
+		return true
+	})
+
+	return er
+}
@@ -0,0 +1,56 @@
+package sqlc
+
+import (
+	"context"
+	"fmt"
+	"go.uber.org/multierr"
+	"time"
+)
+
+// GetRaw retrieves the stored value for the given key as the exact bytes of the value column,
+// skipping the decompression and the option decoding, which is the fast path for byte-forwarding proxies.
+// The value column is always read from the database, bypassing the cache and the batcher,
+// since both of them hold the decoded values, and the value read is not cached either.
+func (c *Client) GetRaw(k string) (found bool, raw []byte, er error) {
+	defer c.metrics.observe("get", time.Now())
+
+	query, args, err := c.getQuery(k)
+	if err != nil {
+		return false, nil, err
+	}
+
+	db, err := c.openDB(c.readDataSourceName(k))
+	if err != nil {
+		return false, nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), c.QueryTimeout)
+	defer cancel()
+
+	rows, release, err := c.queryContext(ctx, db, "get", k, query, args...)
+	if err != nil {
+		return false, nil, err
+	}
+
+	defer func() { er = multierr.Combine(er, drainAndClose(rows), release()) }()
+
+	found, err = c.scanRows(k, rows, func(cols []string) error {
+		valueIndex, _ := c.valueColumns(cols)
+		if valueIndex < 0 {
+			return fmt.Errorf("key:%s, column:%s, error:%w", k, c.ValueColumn, ErrValueColumn)
+		}
+
+		columns, err := c.scanColumns(rows, len(cols))
+		if err != nil {
+			return err
+		}
+
+		raw = []byte(columns[valueIndex].String)
+		return nil
+	})
+	if err != nil || !found {
+		return false, nil, err
+	}
+
+	return true, raw, nil
+}
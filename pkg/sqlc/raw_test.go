@@ -0,0 +1,75 @@
+package sqlc_test
+
+import (
+	"database/sql/driver"
+	"github.com/bingoohuang/gokv/pkg/sqlc"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func TestGetRaw(t *testing.T) {
+	const insertPrefix = "insert into kv(k, v) values('k', '"
+
+	d, driverName := newFakeDriver()
+	var stored []byte
+	d.Exec = func(query string, _ []driver.NamedValue) (driver.Result, error) {
+		stored = []byte(strings.TrimSuffix(strings.TrimPrefix(query, insertPrefix), "')"))
+		return driver.RowsAffected(1), nil
+	}
+	d.Query = func(query string, _ []driver.NamedValue) ([]string, [][]driver.Value, error) {
+		if query != "select v from kv where k = 'k'" {
+			return []string{"v"}, nil, nil
+		}
+		return []string{"v"}, [][]driver.Value{{stored}}, nil
+	}
+
+	config := sqlc.Config{
+		DriverName: driverName,
+		GetSQL:     "select v from kv where k = '{{.Key}}'",
+		SetSQL:     "insert into kv(k, v) values('{{.Key}}', '{{.Value}}')",
+	}
+	writer := sqlc.NewClient(config)
+	defer writer.Close()
+
+	raw := []byte{0xff, 0xfe, 'k', 0x80, 0xc3}
+	assert.Nil(t, writer.Set("k", string(raw)))
+	assert.Equal(t, raw, stored)
+
+	found, v, err := writer.GetRaw("k")
+	assert.Nil(t, err)
+	assert.True(t, found)
+	assert.Equal(t, raw, v)
+
+	client := sqlc.NewClient(config)
+	defer client.Close()
+
+	found, v, err = client.GetRaw("k")
+	assert.Nil(t, err)
+	assert.True(t, found)
+	assert.Equal(t, raw, v)
+	assert.NotContains(t, client.CacheSnapshot(), "k")
+
+	found, v, err = client.GetRaw("missing")
+	assert.Nil(t, err)
+	assert.False(t, found)
+	assert.Nil(t, v)
+
+	// the compressed column is returned as stored, instead of the decompressed value cached by Set
+	config.CompressAbove = 16
+	compressing := sqlc.NewClient(config)
+	defer compressing.Close()
+
+	large := strings.Repeat("compressed ", 10)
+	assert.Nil(t, compressing.Set("k", large))
+	assert.True(t, strings.HasPrefix(string(stored), sqlc.CompressedPrefix))
+
+	found, v, err = compressing.GetRaw("k")
+	assert.Nil(t, err)
+	assert.True(t, found)
+	assert.Equal(t, stored, v)
+
+	_, decoded, _, err := compressing.Get("k", nil)
+	assert.Nil(t, err)
+	assert.Equal(t, large, decoded)
+}
@@ -0,0 +1,38 @@
+package sqlc
+
+import "time"
+
+// readDataSourceName returns the DSN to read the key k, or all the keys when k is empty.
+// It is the ReadDataSourceName, unless the key, or any key for k is empty,
+// has been written by this client within ReadYourWrites.
+func (c *Client) readDataSourceName(k string) string {
+	if c.ReadDataSourceName == "" {
+		return c.DataSourceName
+	}
+
+	if t, ok := c.writes.Load(k); ok {
+		if time.Since(t.(time.Time)) < c.ReadYourWrites {
+			return c.DataSourceName
+		}
+
+		c.writes.Delete(k)
+	}
+
+	return c.ReadDataSourceName
+}
+
+// wrote remembers the times of the keys just written for ReadYourWrites.
+func (c *Client) wrote(keys ...string) {
+	if c.ReadDataSourceName == "" || c.ReadYourWrites <= 0 {
+		return
+	}
+
+	now := time.Now()
+	for _, k := range keys {
+		c.writes.Store(k, now)
+	}
+
+	if len(keys) > 0 {
+		c.writes.Store("", now)
+	}
+}
@@ -0,0 +1,61 @@
+package sqlc_test
+
+import (
+	"github.com/bingoohuang/gokv/pkg/sqlc"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestReadDataSourceName(t *testing.T) {
+	primary := startTestServer(t)
+	replica := startTestServer(t)
+
+	config := sqlc.Config{DataSourceName: primary, ReadDataSourceName: replica, SetSQL: insertSetSQL}
+
+	client := sqlc.NewClient(config)
+	defer client.Close()
+
+	assert.Nil(t, client.Set("Key4", "value4"))
+
+	// the write is not replicated to the replica
+	reader := sqlc.NewClient(config)
+	defer reader.Close()
+
+	found, _, _, err := reader.Get("Key4", nil)
+	assert.Nil(t, err)
+	assert.False(t, found)
+
+	keys, err := reader.Keys()
+	assert.Nil(t, err)
+	assert.ElementsMatch(t, []string{"Key1", "Key2", "Key3"}, keys)
+
+	primaryReader := sqlc.NewClient(sqlc.Config{DataSourceName: primary})
+	defer primaryReader.Close()
+
+	found, v, _, err := primaryReader.Get("Key4", nil)
+	assert.Nil(t, err)
+	assert.True(t, found)
+	assert.Equal(t, "value4", v)
+
+	// the client reads its own writes from the primary within ReadYourWrites
+	config.ReadYourWrites = time.Minute
+	writer := sqlc.NewClient(config)
+	defer writer.Close()
+
+	assert.Nil(t, writer.Set("Key5", "value5"))
+	writer.InvalidateAll()
+
+	found, v, _, err = writer.Get("Key5", nil)
+	assert.Nil(t, err)
+	assert.True(t, found)
+	assert.Equal(t, "value5", v)
+
+	keys, err = writer.Keys()
+	assert.Nil(t, err)
+	assert.ElementsMatch(t, []string{"Key1", "Key2", "Key3", "Key4", "Key5"}, keys)
+
+	found, _, _, err = writer.Get("Key1", nil)
+	assert.Nil(t, err)
+	assert.True(t, found)
+}
@@ -0,0 +1,113 @@
+package sqlc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Limiter limits the rate of the pages in the batched refresh, e.g. *rate.Limiter.
+type Limiter interface {
+	// Wait blocks until the next page is allowed or the ctx is done.
+	Wait(ctx context.Context) error
+}
+
+// ErrUnpagedAllSQL is the error when AllSQL returns more rows than RefreshBatchSize in the batched refresh.
+var ErrUnpagedAllSQL = errors.New("AllSQL should page by {{.Limit}} and {{.Offset}} in the batched refresh")
+
+// RefreshStatus is the status of the last refresh, e.g. for the health checks.
+type RefreshStatus struct {
+	// LastRun is the start time of the last refresh, zero when no refresh has run.
+	LastRun time.Time
+	// LastDuration is how long the last refresh took.
+	LastDuration time.Duration
+	// KeysLoaded is the number of the keys loaded by the last refresh, 0 when it failed.
+	KeysLoaded int
+	// LastError is the error of the last refresh, nil when it succeeded.
+	LastError error
+}
+
+// RefreshStatus returns the status of the last refresh, by Refresh or the ticker refreshing.
+func (c *Client) RefreshStatus() RefreshStatus {
+	c.statusLock.Lock()
+	defer c.statusLock.Unlock()
+
+	return c.status
+}
+
+// Refresh refreshes the cache from the database, by pages of RefreshBatchSize rows if it is set.
+// The refresh is abandoned when it does not complete within the RefreshTimeout.
+func (c *Client) Refresh() error { return c.refresh(context.Background()) }
+
+func (c *Client) refresh(ctx context.Context) (err error) {
+	ctx, cancel := context.WithTimeout(ctx, c.RefreshTimeout)
+	defer cancel()
+
+	status := RefreshStatus{LastRun: c.Now()}
+	start := time.Now()
+	defer func() {
+		status.LastDuration, status.LastError = time.Since(start), err
+
+		c.statusLock.Lock()
+		c.status = status
+		c.statusLock.Unlock()
+	}()
+
+	if c.RefreshBatchSize <= 0 {
+		kvs, err := c.all(ctx)
+		status.KeysLoaded = len(kvs)
+		return err
+	}
+
+	status.KeysLoaded, err = c.refreshBatches(ctx)
+
+	return err
+}
+
+// refreshBatches refreshes the cache by pages of AllSQL, waiting for the RefreshLimiter before each page,
+// and returns the number of the keys loaded.
+func (c *Client) refreshBatches(ctx context.Context) (n int, er error) {
+	defer c.metrics.observe("all", time.Now())
+
+	db, err := c.openDB(c.readDataSourceName(""))
+	if err != nil {
+		return 0, err
+	}
+
+	kvs := make(map[string]string)
+	for offset := 0; ; offset += c.RefreshBatchSize {
+		if err := c.RefreshLimiter.Wait(ctx); err != nil {
+			return 0, fmt.Errorf("refresh not completed in %s after %d rows: %w", c.RefreshTimeout, offset, err)
+		}
+
+		query, args, err := c.render(c.AllSQL, map[string]int{"Limit": c.RefreshBatchSize, "Offset": offset})
+		if err != nil {
+			return 0, err
+		}
+		c.logQuery(query)
+
+		page, rows, err := c.queryKVs(ctx, db, query, args...)
+		if err != nil {
+			return 0, err
+		}
+		if rows > c.RefreshBatchSize {
+			return 0, ErrUnpagedAllSQL
+		}
+
+		for k, v := range page {
+			kvs[k] = v
+		}
+		if c.MaxResults > 0 && len(kvs) > c.MaxResults {
+			return 0, ErrResultTooLarge{Limit: c.MaxResults}
+		}
+
+		if rows < c.RefreshBatchSize {
+			break
+		}
+	}
+
+	c.replaceCache(kvs)
+
+	return len(kvs), nil
+}
@@ -0,0 +1,129 @@
+package sqlc_test
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"github.com/bingoohuang/gokv/pkg/sqlc"
+	"github.com/stretchr/testify/assert"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type countingLimiter struct {
+	waits int
+	err   error
+}
+
+func (l *countingLimiter) Wait(context.Context) error {
+	l.waits++
+	return l.err
+}
+
+func TestRefreshBatches(t *testing.T) {
+	d, driverName := newFakeDriver()
+	d.Query = func(query string, _ []driver.NamedValue) ([]string, [][]driver.Value, error) {
+		var limit, offset int
+		if _, err := fmt.Sscanf(query, "select k, v from kv limit %d offset %d", &limit, &offset); err != nil {
+			return nil, nil, err
+		}
+
+		var rows [][]driver.Value
+		for i := offset; i < offset+limit && i < 5; i++ {
+			rows = append(rows, []driver.Value{fmt.Sprintf("k%d", i), fmt.Sprintf("v%d", i)})
+		}
+
+		return []string{"k", "v"}, rows, nil
+	}
+
+	limiter := &countingLimiter{}
+	client := sqlc.NewClient(sqlc.Config{
+		DriverName:       driverName,
+		AllSQL:           "select k, v from kv limit {{.Limit}} offset {{.Offset}}",
+		RefreshInterval:  time.Hour,
+		RefreshBatchSize: 2,
+		RefreshLimiter:   limiter,
+	})
+	defer client.Close()
+
+	assert.Nil(t, client.Refresh())
+	assert.Equal(t, 3, limiter.waits)
+	assert.Equal(t, []string{
+		"select k, v from kv limit 2 offset 0",
+		"select k, v from kv limit 2 offset 2",
+		"select k, v from kv limit 2 offset 4",
+	}, d.Statements)
+	assert.Len(t, client.CacheSnapshot(), 5)
+
+	limiter.err = context.DeadlineExceeded
+	assert.True(t, errors.Is(client.Refresh(), context.DeadlineExceeded))
+	assert.Len(t, client.CacheSnapshot(), 5)
+
+	unpaged := sqlc.NewClient(sqlc.Config{
+		DriverName:       driverName,
+		AllSQL:           "select k, v from kv limit 10 offset 0",
+		RefreshBatchSize: 2,
+	})
+	defer unpaged.Close()
+
+	assert.True(t, errors.Is(unpaged.Refresh(), sqlc.ErrUnpagedAllSQL))
+}
+
+func TestRefreshSkipsOverlappingTicks(t *testing.T) {
+	var started int32
+	release := make(chan struct{})
+
+	d, driverName := newFakeDriver()
+	d.Query = func(string, []driver.NamedValue) ([]string, [][]driver.Value, error) {
+		atomic.AddInt32(&started, 1)
+		<-release
+		return []string{"k", "v"}, nil, nil
+	}
+
+	logger := &recordingLogger{}
+	client := sqlc.NewClient(sqlc.Config{
+		DriverName:      driverName,
+		Logger:          logger,
+		RefreshInterval: 10 * time.Millisecond,
+		RefreshTimeout:  time.Minute,
+	})
+
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&started))
+	assert.Contains(t, logger.warnings(), "W! refresh skipped, the previous refresh is still running")
+
+	close(release)
+	assert.Nil(t, client.Close())
+}
+
+func TestRefreshStatus(t *testing.T) {
+	d, driverName := newFakeDriver()
+	d.Query = func(string, []driver.NamedValue) ([]string, [][]driver.Value, error) {
+		return []string{"k", "v"}, [][]driver.Value{{"k1", "v1"}, {"k2", "v2"}, {"k3", "v3"}}, nil
+	}
+
+	now := time.Date(2021, 3, 1, 10, 0, 0, 0, time.UTC)
+	client := sqlc.NewClient(sqlc.Config{DriverName: driverName, Now: func() time.Time { return now }})
+	defer client.Close()
+
+	assert.Equal(t, sqlc.RefreshStatus{}, client.RefreshStatus())
+
+	assert.Nil(t, client.Refresh())
+	status := client.RefreshStatus()
+	assert.Equal(t, now, status.LastRun)
+	assert.Equal(t, 3, status.KeysLoaded)
+	assert.True(t, status.LastDuration > 0)
+	assert.Nil(t, status.LastError)
+
+	failure := errors.New("database is down")
+	d.Query = func(string, []driver.NamedValue) ([]string, [][]driver.Value, error) { return nil, nil, failure }
+	now = now.Add(time.Minute)
+
+	assert.NotNil(t, client.Refresh())
+	status = client.RefreshStatus()
+	assert.Equal(t, now, status.LastRun)
+	assert.Equal(t, 0, status.KeysLoaded)
+	assert.True(t, errors.Is(status.LastError, failure))
+}
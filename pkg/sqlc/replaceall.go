@@ -0,0 +1,162 @@
+package sqlc
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/bingoohuang/gokv"
+	"go.uber.org/multierr"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ReplaceAll publishes the pairs as the whole keyspace in a transaction, the pairs are stored by SetSQL,
+// and the keys selected by KeysSQL but missing from the pairs are deleted by DelSQL,
+// only the ones prefixed with ReplaceAllPrefix when it is set.
+// The cache is updated with the pairs and the deleted keys are evicted after the transaction is committed.
+func (c *Client) ReplaceAll(pairs map[string]string) (er error) {
+	defer c.metrics.observe("replace_all", time.Now())
+
+	stored := make(map[string]string, len(pairs))
+	for k, v := range pairs {
+		if err := c.validateKV(k, v); err != nil {
+			return err
+		}
+
+		var err error
+		if stored[k], err = c.compress(v); err != nil {
+			return err
+		}
+	}
+
+	keys := make([]string, 0, len(pairs))
+	for k := range pairs {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	oldValues, err := c.priorValues(background(), keys)
+	if err != nil {
+		return err
+	}
+
+	db, err := c.openDB(c.DataSourceName)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), c.QueryTimeout)
+	defer cancel()
+
+	var stale []string
+	if err := c.retry(ctx, true, func() (err error) {
+		stale, err = c.replaceAllTx(ctx, db, stored)
+		return err
+	}); err != nil {
+		return err
+	}
+
+	// the prior values of the stale keys are only known from the cache
+	staleValues := make(map[string]string, len(stale))
+	c.cacheLock.Lock()
+	for _, k := range stale {
+		if cv, ok := c.Cache.Get(k); ok {
+			staleValues[k] = cv.Value
+		}
+		c.evict(k)
+		delete(c.noCache, k)
+	}
+	c.cacheLock.Unlock()
+
+	for _, k := range keys {
+		c.wrote(k)
+		c.cacheSet(k, pairs[k], gokv.Option{})
+		c.audit("set", k, oldValues[k], pairs[k])
+	}
+	c.wrote(stale...)
+
+	for _, k := range stale {
+		c.audit("del", k, staleValues[k], "")
+	}
+
+	return nil
+}
+
+// replaceAllTx stores the pairs and deletes the stale keys in a transaction, and returns the stale keys.
+func (c *Client) replaceAllTx(ctx context.Context, db *sql.DB, stored map[string]string) (stale []string, er error) {
+	defer c.logSlow("replace_all", "", time.Now())
+	defer c.metrics.observePhase("sql", time.Now())
+
+	var query string
+	var args []interface{}
+	var err error
+	if c.QueryBuilder != nil {
+		query, args = c.QueryBuilder.BuildKeys()
+	} else if query, args, err = c.render(c.KeysSQL, map[string]string{}); err != nil {
+		return nil, err
+	}
+	c.logQuery(query)
+
+	tx, release, err := c.beginTx(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+
+	// the connection is released after the transaction is committed or rolled back below
+	defer func() { er = multierr.Append(er, release()) }()
+
+	defer func() {
+		if er != nil {
+			if err := tx.Rollback(); !errors.Is(err, sql.ErrTxDone) {
+				er = multierr.Append(er, err)
+			}
+		}
+	}()
+
+	c.metrics.query()
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	keys, err := c.scanKeys(rows)
+	// the rows are closed before the next statements on the connection of the transaction
+	if err = multierr.Append(err, drainAndClose(rows)); err != nil {
+		return nil, err
+	}
+
+	for _, k := range keys {
+		if _, ok := stored[k]; !ok && strings.HasPrefix(k, c.ReplaceAllPrefix) {
+			stale = append(stale, k)
+		}
+	}
+
+	sorted := make([]string, 0, len(stored))
+	for k := range stored {
+		sorted = append(sorted, k)
+	}
+	sort.Strings(sorted)
+
+	for _, k := range sorted {
+		if query, args, err = c.setStatement(c.SetSQL, c.setArgs(), k, stored[k], gokv.Option{}, c.Now()); err != nil {
+			return nil, err
+		}
+		c.metrics.query()
+		if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, k := range stale {
+		if query, args, err = c.delStatement(k); err != nil {
+			return nil, err
+		}
+		c.metrics.query()
+		if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+			return nil, err
+		}
+	}
+
+	return stale, tx.Commit()
+}
@@ -0,0 +1,76 @@
+package sqlc_test
+
+import (
+	"database/sql/driver"
+	"errors"
+	"github.com/bingoohuang/gokv/pkg/sqlc"
+	"github.com/stretchr/testify/assert"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestReplaceAll(t *testing.T) {
+	d, driverName := newFakeDriver()
+
+	var lock sync.Mutex
+	rows := map[string]string{"app.a": "1", "app.b": "2", "other.x": "9"}
+	d.Query = func(query string, args []driver.NamedValue) ([]string, [][]driver.Value, error) {
+		lock.Lock()
+		defer lock.Unlock()
+
+		var keys [][]driver.Value
+		for k := range rows {
+			keys = append(keys, []driver.Value{k})
+		}
+		return []string{"k"}, keys, nil
+	}
+
+	failDel := false
+	d.Exec = func(query string, args []driver.NamedValue) (driver.Result, error) {
+		lock.Lock()
+		defer lock.Unlock()
+
+		switch k := args[0].Value.(string); {
+		case strings.HasPrefix(query, "upsert"):
+			rows[k] = args[1].Value.(string)
+		case failDel:
+			return nil, errors.New("del failed")
+		default:
+			delete(rows, k)
+		}
+		return driver.RowsAffected(1), nil
+	}
+
+	client := sqlc.NewClient(sqlc.Config{
+		DriverName:       driverName,
+		SetSQL:           "upsert kv {{arg .Key}} {{arg .Value}}",
+		DelSQL:           "delete kv {{arg .Key}}",
+		ReplaceAllPrefix: "app.",
+	})
+	defer client.Close()
+
+	assert.Nil(t, client.ReplaceAll(map[string]string{"app.a": "10", "app.c": "3"}))
+	assert.Equal(t, map[string]string{"app.a": "10", "app.c": "3", "other.x": "9"}, rows)
+
+	statements := d.StatementCount()
+	for k, expected := range map[string]string{"app.a": "10", "app.c": "3"} {
+		found, v, _, err := client.Get(k, nil)
+		assert.Nil(t, err)
+		assert.True(t, found)
+		assert.Equal(t, expected, v)
+	}
+	assert.Equal(t, statements, d.StatementCount(), "the new pairs are cached")
+
+	keys, err := client.Keys()
+	assert.Nil(t, err)
+	sort.Strings(keys)
+	assert.Equal(t, []string{"app.a", "app.c", "other.x"}, keys)
+
+	failDel = true
+	assert.NotNil(t, client.ReplaceAll(map[string]string{"app.d": "4"}))
+	found, _, _, err := client.Get("app.a", nil)
+	assert.Nil(t, err)
+	assert.True(t, found, "the cache is kept when the transaction fails")
+}
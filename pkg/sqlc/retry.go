@@ -0,0 +1,253 @@
+package sqlc
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"go.uber.org/multierr"
+	"time"
+)
+
+// ErrPoolExhausted is the error when no slot of MaxConcurrentOps, or no connection, is acquired
+// within the AcquireTimeout.
+type ErrPoolExhausted struct {
+	// Resource is what is waited for, a "slot of MaxConcurrentOps" or a "connection".
+	Resource string
+	Timeout  time.Duration
+}
+
+func (e ErrPoolExhausted) Error() string {
+	return fmt.Sprintf("no %s acquired within the acquire timeout %s", e.Resource, e.Timeout)
+}
+
+const (
+	slotResource = "slot of MaxConcurrentOps"
+	connResource = "connection"
+)
+
+// queryContext queries the database for the operation op of the key k, retrying the failures by Retries.
+// The release must be called after the rows are closed, to return the connection acquired for them,
+// and the slot of MaxConcurrentOps, which is held until the rows are consumed.
+func (c *Client) queryContext(ctx context.Context, db *sql.DB, op, k, query string, args ...interface{}) (
+	rows *sql.Rows, release func() error, err error) {
+	release = func() error { return nil }
+	err = c.retryHolding(ctx, false, func(releaseSlot func()) (err error) {
+		defer c.logSlow(op, k, time.Now())
+		defer c.metrics.observePhase("sql", time.Now())
+
+		closeConn := func() error { return nil }
+		defer func() {
+			if err != nil {
+				err = multierr.Append(err, closeConn())
+				releaseSlot()
+			}
+		}()
+
+		conn, err := c.acquireConn(ctx, db)
+		if err != nil {
+			return err
+		}
+
+		c.metrics.query()
+		if conn == nil && c.preparable(query) {
+			stmt, err := c.stmt(ctx, db, query)
+			if err != nil {
+				return err
+			}
+			rows, err = stmt.QueryContext(ctx, args...)
+		} else if conn == nil {
+			rows, err = db.QueryContext(ctx, query, args...)
+		} else {
+			closeConn = conn.Close
+			rows, err = conn.QueryContext(ctx, query, args...)
+		}
+		if err != nil {
+			return err
+		}
+
+		release = func() error {
+			defer releaseSlot()
+			return closeConn()
+		}
+		return nil
+	})
+
+	return rows, release, c.mapError(err)
+}
+
+// execContext executes the statement for the operation op of the key k,
+// retrying the failures by Retries only when RetryWrites is set.
+// The key is remembered as written for ReadYourWrites, even when it fails, since it may be partially applied.
+func (c *Client) execContext(ctx context.Context, db *sql.DB, op, k, query string, args ...interface{}) (
+	result sql.Result, err error) {
+	c.wrote(k)
+
+	err = c.retry(ctx, true, func() (err error) {
+		defer c.logSlow(op, k, time.Now())
+		defer c.metrics.observePhase("sql", time.Now())
+
+		conn, err := c.acquireConn(ctx, db)
+		if err != nil {
+			return err
+		}
+
+		c.metrics.query()
+		if conn == nil && c.preparable(query) {
+			stmt, err := c.stmt(ctx, db, query)
+			if err != nil {
+				return err
+			}
+			result, err = stmt.ExecContext(ctx, args...)
+			return err
+		} else if conn == nil {
+			result, err = db.ExecContext(ctx, query, args...)
+			return err
+		}
+
+		result, err = conn.ExecContext(ctx, query, args...)
+		return multierr.Append(err, conn.Close())
+	})
+
+	return result, c.mapError(err)
+}
+
+// beginTx begins a transaction on a connection acquired within the AcquireTimeout,
+// the release must be called after the transaction is done, to return the connection.
+func (c *Client) beginTx(ctx context.Context, db *sql.DB) (tx *sql.Tx, release func() error, err error) {
+	conn, err := c.acquireConn(ctx, db)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if conn == nil {
+		tx, err = db.BeginTx(ctx, nil)
+		return tx, func() error { return nil }, err
+	}
+
+	if tx, err = conn.BeginTx(ctx, nil); err != nil {
+		return nil, nil, multierr.Append(err, conn.Close())
+	}
+
+	return tx, conn.Close, nil
+}
+
+// mapError maps the error of the driver by the ErrorMapper.
+func (c *Client) mapError(err error) error {
+	if err == nil || c.ErrorMapper == nil {
+		return err
+	}
+
+	return c.ErrorMapper(err)
+}
+
+// retry calls f until it succeeds, up to 1+Retries times, or only once for the writes without RetryWrites.
+func (c *Client) retry(ctx context.Context, write bool, f func() error) error {
+	return c.retryHolding(ctx, write, func(releaseSlot func()) error {
+		defer releaseSlot()
+		return f()
+	})
+}
+
+// retryHolding is like retry, but f takes over the slot of MaxConcurrentOps of each attempt,
+// which must be released by f, or later by the caller after the result of f is consumed.
+func (c *Client) retryHolding(ctx context.Context, write bool, f func(releaseSlot func()) error) (err error) {
+	attempts := 1
+	if !write || c.RetryWrites {
+		attempts += c.Retries
+	}
+
+	for i := 0; ; i++ {
+		if err = c.limit(ctx, f); err == nil || i+1 >= attempts || errors.As(err, &ErrPoolExhausted{}) {
+			return err
+		}
+
+		c.Logger.Printf("W! retry after error %v", err)
+
+		t := time.NewTimer(c.Backoff.Next(i + 1))
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return err
+		case <-t.C:
+		}
+	}
+}
+
+// limit calls f with a slot of MaxConcurrentOps, waiting for a free slot until the ctx is done,
+// or until the AcquireTimeout. f must release the slot.
+func (c *Client) limit(ctx context.Context, f func(releaseSlot func()) error) error {
+	if c.ops == nil {
+		return f(func() {})
+	}
+
+	acquireCtx, cancel := c.acquireContext(ctx)
+	defer cancel()
+
+	select {
+	case c.ops <- struct{}{}:
+	case <-acquireCtx.Done():
+		return c.acquireErr(ctx, slotResource)
+	}
+
+	return f(func() { <-c.ops })
+}
+
+// backgroundKey marks the ctx of the operations called without a ctx, e.g. Get instead of GetContext.
+type backgroundKey struct{}
+
+// background is the ctx of the operations called without a ctx, whose queries are bounded by QueryTimeout.
+func background() context.Context {
+	return context.WithValue(context.Background(), backgroundKey{}, true)
+}
+
+// queryTimeout bounds the ctx of a query by the QueryTimeout for the operations called without a ctx,
+// while the ctx of the Context variants is left as the callers set it, e.g. with a longer deadline.
+func (c *Client) queryTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if ctx.Value(backgroundKey{}) == nil {
+		return ctx, func() {}
+	}
+
+	return context.WithTimeout(ctx, c.QueryTimeout)
+}
+
+// acquireContext bounds the ctx by the AcquireTimeout for the acquisition of a slot or a connection.
+func (c *Client) acquireContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if c.AcquireTimeout <= 0 {
+		return ctx, func() {}
+	}
+
+	return context.WithTimeout(ctx, c.AcquireTimeout)
+}
+
+// acquireErr returns the error of the acquisition of the resource, which is the error of ctx when it is done,
+// otherwise the AcquireTimeout expires.
+func (c *Client) acquireErr(ctx context.Context, resource string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return ErrPoolExhausted{Resource: resource, Timeout: c.AcquireTimeout}
+}
+
+// acquireConn acquires a connection of db within the AcquireTimeout for the following query or statement,
+// which runs on it, so that the operation does not block on a stalled pool or dial.
+// It returns nil without the AcquireTimeout, and the operation takes its connection from db.
+func (c *Client) acquireConn(ctx context.Context, db *sql.DB) (*sql.Conn, error) {
+	if c.AcquireTimeout <= 0 {
+		return nil, nil
+	}
+
+	acquireCtx, cancel := c.acquireContext(ctx)
+	defer cancel()
+
+	conn, err := db.Conn(acquireCtx)
+	if err != nil {
+		if acquireCtx.Err() != nil {
+			return nil, c.acquireErr(ctx, connResource)
+		}
+		return nil, err
+	}
+
+	return conn, nil
+}
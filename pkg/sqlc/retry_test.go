@@ -0,0 +1,188 @@
+package sqlc_test
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"github.com/bingoohuang/gokv"
+	"github.com/bingoohuang/gokv/pkg/sqlc"
+	"github.com/stretchr/testify/assert"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRetryReadsOnly(t *testing.T) {
+	errBroken := errors.New("broken connection")
+
+	for retryWrites, writeAttempts := range map[bool]int{false: 1, true: 3} {
+		d, driverName := newFakeDriver()
+		d.Query = func(string, []driver.NamedValue) ([]string, [][]driver.Value, error) {
+			return nil, nil, errBroken
+		}
+		d.Exec = func(string, []driver.NamedValue) (driver.Result, error) { return nil, errBroken }
+
+		client := sqlc.NewClient(sqlc.Config{
+			DriverName:    driverName,
+			Retries:       2,
+			RetryInterval: time.Millisecond,
+			RetryWrites:   retryWrites,
+		})
+
+		_, _, _, err := client.Get("k", nil)
+		assert.NotNil(t, err)
+		assert.Equal(t, 3, d.StatementCount())
+
+		d.Statements = nil
+		assert.NotNil(t, client.Set("k", "v"))
+		assert.Equal(t, writeAttempts, d.StatementCount())
+
+		d.Statements = nil
+		d.Query = func(string, []driver.NamedValue) ([]string, [][]driver.Value, error) {
+			if len(d.Statements) < 2 {
+				return nil, nil, errBroken
+			}
+			return []string{"v"}, [][]driver.Value{{"v1"}}, nil
+		}
+
+		found, v, _, err := client.Get("k", nil)
+		assert.Nil(t, err)
+		assert.True(t, found)
+		assert.Equal(t, "v1", v)
+		assert.Nil(t, client.Close())
+	}
+}
+
+func TestMaxConcurrentOps(t *testing.T) {
+	var inflight, max int32
+
+	d, driverName := newFakeDriver()
+	d.Query = func(string, []driver.NamedValue) ([]string, [][]driver.Value, error) {
+		n := atomic.AddInt32(&inflight, 1)
+		defer atomic.AddInt32(&inflight, -1)
+
+		for m := atomic.LoadInt32(&max); n > m && !atomic.CompareAndSwapInt32(&max, m, n); m = atomic.LoadInt32(&max) {
+		}
+
+		time.Sleep(5 * time.Millisecond)
+		return []string{"v"}, nil, nil
+	}
+
+	client := sqlc.NewClient(sqlc.Config{DriverName: driverName, MaxConcurrentOps: 3})
+	defer client.Close()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 30; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			_, _, _, err := client.Get(fmt.Sprintf("k%d", i), nil)
+			assert.Nil(t, err)
+		}(i)
+	}
+	wg.Wait()
+
+	assert.Equal(t, int32(3), atomic.LoadInt32(&max))
+}
+
+func TestMaxConcurrentOpsHeldByRows(t *testing.T) {
+	d, driverName := newFakeDriver()
+	d.Query = func(string, []driver.NamedValue) ([]string, [][]driver.Value, error) {
+		return []string{"k", "v"}, [][]driver.Value{{"k1", "v1"}}, nil
+	}
+
+	client := sqlc.NewClient(sqlc.Config{
+		DriverName:       driverName,
+		MaxConcurrentOps: 1,
+		AcquireTimeout:   20 * time.Millisecond,
+	})
+	defer client.Close()
+
+	// the slot is held until the rows are scanned and closed
+	assert.Nil(t, client.Each(func(k, v string) error {
+		_, err := client.Keys()
+		assert.True(t, errors.As(err, &sqlc.ErrPoolExhausted{}), "blocked by the rows open")
+		return nil
+	}))
+
+	_, err := client.Keys()
+	assert.Nil(t, err, "the slot is released after the rows are closed")
+}
+
+func TestAcquireTimeout(t *testing.T) {
+	started := make(chan struct{}, 1)
+	d, driverName := newFakeDriver()
+	d.Query = func(string, []driver.NamedValue) ([]string, [][]driver.Value, error) {
+		started <- struct{}{}
+		time.Sleep(200 * time.Millisecond)
+		return []string{"v"}, [][]driver.Value{{"v1"}}, nil
+	}
+
+	client := sqlc.NewClient(sqlc.Config{
+		DriverName:       driverName,
+		MaxConcurrentOps: 1,
+		AcquireTimeout:   20 * time.Millisecond,
+		Retries:          2,
+	})
+	defer client.Close()
+
+	slow := make(chan error, 1)
+	go func() {
+		_, _, _, err := client.Get("k1", nil)
+		slow <- err
+	}()
+	<-started
+
+	start := time.Now()
+	_, _, _, err := client.Get("k2", nil)
+	var exhausted sqlc.ErrPoolExhausted
+	assert.True(t, errors.As(err, &exhausted))
+	assert.Equal(t, 20*time.Millisecond, exhausted.Timeout)
+	assert.Equal(t, "no slot of MaxConcurrentOps acquired within the acquire timeout 20ms", err.Error())
+	assert.True(t, time.Since(start) < 150*time.Millisecond, "fails fast without waiting for the slow query")
+
+	assert.Nil(t, <-slow)
+
+	// the queries and the statements run on the acquired connections, which are returned after the rows are closed
+	_, err = client.Keys()
+	assert.Nil(t, err)
+	assert.Nil(t, client.Set("k3", "v3"))
+	conns, rows := d.Opened()
+	assert.Equal(t, 1, conns)
+	assert.Equal(t, 0, rows)
+}
+
+// duplicateError is a native error of the driver for a duplicate key.
+type duplicateError struct{ key string }
+
+func (e *duplicateError) Error() string { return "duplicate entry " + e.key }
+
+func TestErrorMapper(t *testing.T) {
+	d, driverName := newFakeDriver()
+	d.Exec = func(string, []driver.NamedValue) (driver.Result, error) { return nil, &duplicateError{key: "k1"} }
+
+	client := sqlc.NewClient(sqlc.Config{DriverName: driverName, ErrorMapper: func(err error) error {
+		var dup *duplicateError
+		if errors.As(err, &dup) {
+			return gokv.MapError(gokv.ErrConflict, err)
+		}
+		return err
+	}})
+	defer client.Close()
+
+	err := client.Set("k1", "v1")
+	assert.True(t, errors.Is(err, gokv.ErrConflict))
+
+	var dup *duplicateError
+	assert.True(t, errors.As(err, &dup), "the native error is preserved")
+	assert.Equal(t, "k1", dup.key)
+
+	unmapped := sqlc.NewClient(sqlc.Config{DriverName: driverName})
+	defer unmapped.Close()
+
+	err = unmapped.Set("k1", "v1")
+	assert.False(t, errors.Is(err, gokv.ErrConflict))
+	assert.True(t, errors.As(err, &dup))
+}
@@ -0,0 +1,155 @@
+package sqlc
+
+import (
+	"context"
+	"github.com/bingoohuang/gokv"
+	"sort"
+	"time"
+)
+
+// SetMany stores the key values with the same options.
+// The option is encoded only once when no CodecResolver is configured.
+// With SetManySQL, the rows are stored by multi-row statements of at most BatchSize rows,
+// otherwise they are stored by SetSQL one by one.
+func (c *Client) SetMany(kvs map[string]string, fns ...gokv.OptionFn) (er error) {
+	defer c.metrics.observe("setmany", time.Now())
+
+	if len(kvs) == 0 {
+		return nil
+	}
+
+	option := gokv.NewOption(fns...)
+	now := c.Now()
+	setArgs := c.setArgs()
+
+	keys := make([]string, 0, len(kvs))
+	for k := range kvs {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var optionData string
+	rows := make([]map[string]interface{}, 0, len(keys))
+	for i, k := range keys {
+		if err := c.validateKV(k, kvs[k]); err != nil {
+			return err
+		}
+
+		if i == 0 || c.CodecResolver != nil {
+			var err error
+			if optionData, err = c.marshalOption(k, option); err != nil {
+				return err
+			}
+		}
+
+		stored, err := c.compress(kvs[k])
+		if err != nil {
+			return err
+		}
+
+		row, err := c.setData(k, stored, optionData, option, now)
+		if err != nil {
+			return err
+		}
+		rows = append(rows, row)
+	}
+
+	sqlTemplate := c.SetSQL
+	if c.SetManySQL != "" {
+		sqlTemplate = c.SetManySQL
+	}
+
+	oldValues, err := c.priorValues(background(), keys)
+	if err != nil {
+		return err
+	}
+
+	db, err := c.openDB(c.DataSourceName)
+	if err != nil {
+		return err
+	}
+
+	c.wrote(keys...)
+
+	exec := func(query string, args []interface{}) error {
+		c.logQuery(query)
+
+		ctx, cancel := context.WithTimeout(context.Background(), c.QueryTimeout)
+		defer cancel()
+
+		_, err := c.execContext(ctx, db, "setmany", "", query, args...)
+
+		return err
+	}
+
+	if c.QueryBuilder != nil {
+		for _, row := range rows {
+			if err := exec(c.QueryBuilder.BuildSet(row["Key"].(string), row["Value"], row["Option"].(string))); err != nil {
+				return err
+			}
+		}
+	} else if c.SetManySQL != "" {
+		for start := 0; start < len(rows); start += c.BatchSize {
+			end := start + c.BatchSize
+			if end > len(rows) {
+				end = len(rows)
+			}
+
+			query, args, err := c.render(sqlTemplate, map[string]interface{}{
+				"Rows": rows[start:end], "Time": rows[0]["Time"], "ServerTime": c.ServerTimeSQL,
+			})
+			if err != nil {
+				return err
+			}
+
+			// the fields named by SetArgs are bound row by row
+			if len(setArgs) > 0 {
+				if args, err = bindRowArgs(setArgs, args, rows[start:end]); err != nil {
+					return err
+				}
+			}
+
+			if err := exec(query, args); err != nil {
+				return err
+			}
+		}
+	} else {
+		for _, row := range rows {
+			query, args, err := c.render(sqlTemplate, row)
+			if err != nil {
+				return err
+			}
+
+			if len(setArgs) > 0 {
+				if args, err = bindArgs("SetSQL", setArgs, args, row); err != nil {
+					return err
+				}
+			}
+
+			if err := exec(query, args); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, k := range keys {
+		c.cacheSet(k, kvs[k], option)
+		c.audit("set", k, oldValues[k], kvs[k])
+	}
+
+	return nil
+}
+
+// bindRowArgs returns the fields named by setArgs of the rows as the bound arguments of SetManySQL, row by row.
+func bindRowArgs(setArgs []string, templateArgs []interface{}, rows []map[string]interface{}) ([]interface{}, error) {
+	var args []interface{}
+	for _, row := range rows {
+		rowArgs, err := bindArgs("SetManySQL", setArgs, templateArgs, row)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, rowArgs...)
+	}
+
+	return args, nil
+}
@@ -0,0 +1,101 @@
+package sqlc_test
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"github.com/bingoohuang/gokv"
+	"github.com/bingoohuang/gokv/pkg/sqlc"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+const setManySQL = "insert into kv(k, v, state, updated, created, o) values " +
+	"{{range $i, $r := .Rows}}{{if $i}},{{end}}" +
+	"({{arg $r.Key}}, {{arg $r.Value}}, 1, {{arg $r.Time}}, {{arg $r.Time}}, {{arg $r.Option}}){{end}}"
+
+func TestSetManyBatches(t *testing.T) {
+	kvs := make(map[string]string)
+	for i := 0; i < 1000; i++ {
+		kvs[fmt.Sprintf("k%04d", i)] = fmt.Sprintf("v%d", i)
+	}
+
+	d, driverName := newFakeDriver()
+	var firstArgs []driver.NamedValue
+	d.Exec = func(_ string, args []driver.NamedValue) (driver.Result, error) {
+		if firstArgs == nil {
+			firstArgs = args
+		}
+		return driver.RowsAffected(int64(len(args))), nil
+	}
+	client := sqlc.NewClient(sqlc.Config{DriverName: driverName, SetManySQL: setManySQL, BatchSize: 128})
+	defer client.Close()
+
+	assert.Nil(t, client.SetMany(kvs, gokv.TTL(time.Minute)))
+	assert.Equal(t, (1000+128-1)/128, d.StatementCount())
+	assert.NotContains(t, d.Statements[0], "k0000", "the row values are bound")
+	assert.Len(t, firstArgs, 128*5)
+	assert.Equal(t, "k0000", firstArgs[0].Value)
+	assert.Equal(t, "v0", firstArgs[1].Value)
+	assert.Len(t, client.CacheSnapshot(), 1000)
+
+	d, driverName = newFakeDriver()
+	client = sqlc.NewClient(sqlc.Config{DriverName: driverName})
+	defer client.Close()
+
+	assert.Nil(t, client.SetMany(kvs))
+	assert.Equal(t, 1000, d.StatementCount())
+}
+
+func TestSetMany(t *testing.T) {
+	dsn := startTestServer(t)
+	client := sqlc.NewClient(sqlc.Config{
+		DataSourceName: dsn + "?interpolateParams=true", SetManySQL: setManySQL, BatchSize: 2,
+	})
+	defer client.Close()
+
+	assert.Nil(t, client.SetMany(map[string]string{"Key4": "v4", "Key5": "v5", "Key6": "v6"}, gokv.NoCache()))
+	assert.Empty(t, client.CacheSnapshot())
+
+	all, err := client.All()
+	assert.Nil(t, err)
+	assert.Equal(t, "v4", all["Key4"])
+	assert.Equal(t, "v5", all["Key5"])
+	assert.Equal(t, "v6", all["Key6"])
+}
+
+func TestSetManyArgBindings(t *testing.T) {
+	d, driverName := newFakeDriver()
+	var args [][]driver.NamedValue
+	d.Exec = func(_ string, a []driver.NamedValue) (driver.Result, error) {
+		args = append(args, a)
+		return driver.RowsAffected(1), nil
+	}
+
+	client := sqlc.NewClient(sqlc.Config{
+		DriverName:  driverName,
+		SetSQL:      "insert into kv(k, v) values(?, ?)",
+		ArgBindings: map[string][]string{"SetSQL": {"Key", "Value"}},
+	})
+	defer client.Close()
+
+	assert.Nil(t, client.SetMany(map[string]string{"k1": "it's", "k2": "v2"}))
+	assert.Equal(t, [][]driver.NamedValue{
+		{{Ordinal: 1, Value: "k1"}, {Ordinal: 2, Value: "it's"}},
+		{{Ordinal: 1, Value: "k2"}, {Ordinal: 2, Value: "v2"}},
+	}, args)
+
+	args = nil
+	batched := sqlc.NewClient(sqlc.Config{
+		DriverName: driverName,
+		SetManySQL: "insert into kv(k, v) values {{range $i, $r := .Rows}}{{if $i}},{{end}}(?, ?){{end}}",
+		SetArgs:    []string{"Key", "Value"},
+	})
+	defer batched.Close()
+
+	assert.Nil(t, batched.SetMany(map[string]string{"k1": "it's", "k2": "v2"}))
+	assert.Equal(t, "insert into kv(k, v) values (?, ?),(?, ?)", d.Statements[len(d.Statements)-1])
+	assert.Equal(t, [][]driver.NamedValue{{
+		{Ordinal: 1, Value: "k1"}, {Ordinal: 2, Value: "it's"}, {Ordinal: 3, Value: "k2"}, {Ordinal: 4, Value: "v2"},
+	}}, args)
+}
@@ -6,9 +6,16 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"github.com/bingoohuang/gokv"
+	"github.com/bingoohuang/gokv/pkg/codec"
+	"github.com/bingoohuang/gokv/pkg/util"
 	"go.uber.org/multierr"
-	"log"
+	"golang.org/x/time/rate"
+	"hash/fnv"
+	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"text/template"
 	"time"
 )
@@ -16,22 +23,325 @@ import (
 type Config struct {
 	DriverName     string
 	DataSourceName string
+	// ReadDataSourceName is the DSN of a read-only replica for All, Keys and Get when it is set,
+	// while DataSourceName is used for the writes. The other reads, like GetWithVersion and
+	// the locking of GenerateLock, still go to DataSourceName.
+	ReadDataSourceName string
+	// ReadYourWrites reads the keys from DataSourceName within the duration after they are written
+	// by this client, which should cover the replication lag, default to reading the replica always.
+	ReadYourWrites time.Duration
 
-	AllSQL string
+	AllSQL  string
+	KeysSQL string
+	// KeyColumn is the name of the key column selected by KeysSQL, default to the first column.
+	KeyColumn string
+	// GetSQL selects the value, and optionally the encoded option as the second column.
 	GetSQL string
+	// ValueColumn is the name of the value column selected by GetSQL, default to the first column,
+	// e.g. value for a computed value like select o, concat(prefix, v) as value from kv where ...
+	// The encoded option is then the first of the other columns.
+	ValueColumn string
+	// SetSQL stores the value, the encoded option is available as {{.Option}}.
+	// {{.Time}} is the client time formatted as a string literal, while {{.ServerTime}} is the SQL function
+	// of the database server time (see ServerTimeSQL), which should be used without quotes,
+	// e.g. updated = {{.ServerTime}}, to avoid clock skews among multiple writers.
 	SetSQL string
-	DelSQL string
+	// SetArgs names the fields bound as the arguments of SetSQL in order, which are Key, RawKey, Value, Option
+	// and Time, so that the values and the options are never interpolated into the statement, e.g.
+	// SetArgs ["Key", "Value", "Option"] with SetSQL insert into kv(k, v, o) values(?, ?, ?).
+	// The placeholders of SetSQL depend on the driver (see Placeholder), and {{arg}} should not be used with it.
+	SetArgs []string
+	// ArgBindings names the fields bound as the arguments in order, keyed by GetSQL, SetSQL, DelSQL, MergeGetSQL,
+	// VersionGetSQL and SetIfVersionSQL, so that the keys and the values are bound by the driver instead of
+	// being interpolated into the statements,
+	// e.g. {"GetSQL": {"Key"}, "DelSQL": {"Key"}} with GetSQL select v from kv where k = ? and state = 1.
+	// The fields are the ones of the templates, e.g. Key and RawKey of GetSQL, and the SQL without template actions
+	// is used as it is. ArgBindings["SetSQL"] takes precedence over SetArgs.
+	ArgBindings map[string][]string
+	// PrepareStatements prepares the statements of GetSQL, SetSQL, DelSQL, KeysSQL and AllSQL without template
+	// actions, e.g. with the placeholders bound by ArgBindings, once per database and reuses them until Close,
+	// see Prepare to prepare them ahead. The operations on the connections of AcquireTimeout do not use them.
+	PrepareStatements bool
+	// InsertSQL inserts the value of SetGenerateKey, whose key is generated by the database, with the same fields
+	// as SetSQL except for the keys, e.g. insert into kv(v, state, created) values('{{.Value}}', 1, '{{.Time}}'),
+	// optionally with a RETURNING clause of the generated key, e.g. returning k.
+	InsertSQL string
+	// SetBytesSQL stores the binary value of SetBytes, which should be bound by {{arg .Value}}
+	// instead of being quoted, default to DefaultSetBytesSQL. The value column should be binary,
+	// e.g. BLOB or VARBINARY, and selected by GetSQL for GetBytes.
+	SetBytesSQL string
+	// SetManySQL stores multiple rows in one statement, ranging over {{.Rows}}
+	// which have the same fields as SetSQL, whose values should be bound by {{arg}}, e.g.
+	// insert into kv(k, v, state, created) values
+	// {{range $i, $r := .Rows}}{{if $i}},{{end}}({{arg $r.Key}}, {{arg $r.Value}}, 1, {{arg $r.Time}}){{end}}
+	// With SetArgs, or ArgBindings["SetSQL"], the named fields of each row are bound in order, row by row.
+	SetManySQL string
+	DelSQL     string
+
+	// KeyHashFn hashes the keys before they hit the database, e.g. SHA256Hex,
+	// for the key columns with length limits. The hashed key is available as {{.Key}},
+	// and the original key as {{.RawKey}} in GetSQL, SetSQL, SetManySQL and DelSQL.
+	// All and Keys map the hashed keys back to the logical keys by an in-memory table of the keys
+	// used by this client, for the keys stored by others, persist {{.RawKey}} in a separate column
+	// and select it instead of the hashed key in AllSQL and KeysSQL.
+	// Two keys colliding to the same hash share the same row, the last written wins,
+	// so a cryptographic hash is recommended over truncating.
+	KeyHashFn func(string) string
+
+	// CompressAbove compresses the values longer than CompressAbove bytes by the Compressor in Set and SetMany,
+	// default to no compression. The compressed values are stored with the CompressedPrefix and
+	// decompressed transparently when read, regardless of CompressAbove.
+	CompressAbove int
+	// Compressor compresses the values, default to Gzip.
+	Compressor Compressor
+
+	// QueryBuilder builds the statements of Get, Set, Del and Keys instead of the SQL templates when it is set,
+	// e.g. SquirrelBuilder.
+	QueryBuilder QueryBuilder
+
+	// Retries is the number of the retries of a failed query or statement, default to no retry.
+	// Only the reads (All, Keys and Get) are retried, unless RetryWrites is set.
+	Retries int
+	// RetryInterval is the interval between the retries.
+	RetryInterval time.Duration
+	// QueryTimeout bounds each query and statement, e.g. longer for a slow KeysSQL, or shorter for
+	// the latency-sensitive Gets, default to 15s. The Context variants are bounded by their ctx instead.
+	QueryTimeout time.Duration
+	// MaxConcurrentOps limits the number of the concurrent queries and statements of the client,
+	// including the ones of the cache misses and the generating, default to no limit.
+	// The operations wait for a free slot until their timeouts.
+	MaxConcurrentOps int
+	// AcquireTimeout bounds the wait for a slot of MaxConcurrentOps and for a connection,
+	// separately from the timeout of the operation, which then fails fast with ErrPoolExhausted
+	// instead of piling up during a database stall, default to waiting until the operation timeout.
+	AcquireTimeout time.Duration
+
+	// BatchWindow coalesces the Gets missing the cache within the window into a query of GetBatchSQL,
+	// which delays them by up to the window, default to querying each key by GetSQL.
+	BatchWindow time.Duration
+	// BatchMaxKeys queries the batch as soon as it has the keys, before the BatchWindow, default to 100.
+	BatchMaxKeys int
+	// GetBatchSQL selects the keys, the values, and optionally the encoded options, of the keys {{.Keys}}
+	// for the BatchWindow, default to DefaultGetBatchSQL. The columns after the key are decoded like the ones
+	// of GetSQL, by ValueColumn, OptionColumns, StrictColumns, RequireOption and MultiValuePolicy,
+	// while GetSQL and its ArgBindings are not used by the batches, whose keys are bound by {{arg}}.
+	GetBatchSQL string
+
+	// ErrorMapper maps the native errors of the driver to the common errors of gokv by gokv.MapError, e.g.
+	// gokv.ErrConflict from the duplicate entry error 1062 of the *mysql.MySQLError,
+	// default to returning the native errors as they are.
+	ErrorMapper func(error) error
+
+	// Backoff decides the delays between the retries, e.g. ExponentialBackoff,
+	// default to ConstantBackoff(RetryInterval).
+	Backoff Backoff
+	// RetryWrites retries the statements of Set, SetMany and Del too. Enable it only when they are idempotent,
+	// e.g. an upsert SetSQL like DefaultSetSQL and an update DelSQL, otherwise a retried statement whose
+	// first attempt actually succeeded, e.g. a plain insert, may fail or write twice.
+	RetryWrites bool
+
+	// VersionGetSQL selects the value and its version for GetWithVersion, e.g.
+	// select v, version from kv where k = '{{.Key}}' and state = 1
+	VersionGetSQL string
+	// SetIfVersionSQL updates the value of the expected version {{.Version}} for SetIfVersion, e.g.
+	// update kv set v = {{arg .Value}}, version = version + 1 where k = {{arg .Key}} and version = {{arg .Version}}
+	// The version comes from the callers, so it should be bound by {{arg .Version}} or ArgBindings,
+	// it is required to be an integer when interpolated raw by {{.Version}}.
+	SetIfVersionSQL string
+
+	// TouchSQL extends the expiry of the key for Touch without changing its value, e.g.
+	// update kv set expired = '{{.Expiry}}' where k = '{{.Key}}' and state = 1
+	// {{.Expiry}} is the client time of now plus the TTL, and {{.TTL}} is the TTL in seconds.
+	TouchSQL string
+
+	// PrefixSQL selects the keys and the values of the keys with a prefix for GetByPrefix, default to DefaultPrefixSQL.
+	// {{.Pattern}} is the LIKE pattern of the prefix, whose %, _ and \ are escaped by \, and should be bound
+	// by {{arg .Pattern}}. The prefix matches the hashed keys instead of the original ones with KeyHashFn.
+	PrefixSQL string
+
+	// AllOrderedSQL selects the keys and the values ordered by the keys for AllOrdered,
+	// default to DefaultAllOrderedSQL. The keys are ordered as hashed with KeyHashFn.
+	AllOrderedSQL string
+
+	// ExportSQL selects a page of the keys and the values ordered by the keys for ExportPage,
+	// default to DefaultExportSQL. {{.Cursor}} is the last key of the previous page, which should be
+	// bound by {{arg .Cursor}}, and {{.Limit}} is the max number of the rows of the page.
+	ExportSQL string
+
+	// FieldQuerySQL selects the keys and the values by a field of the values for GetByField,
+	// with the template fields {{.FieldPath}} and {{.FieldValue}}.
+	FieldQuerySQL string
+
+	// MergeGetSQL selects the value, and optionally the option, in the transaction of Merge, default to GetSQL.
+	// Append "for update" to lock the row against the concurrent updates, e.g.
+	// select v from kv where k = '{{.Key}}' and state = 1 for update
+	MergeGetSQL string
+	// MergeSQL pushes the merge of Merge into the database with the JSON patch {{.Patch}}, e.g.
+	// update kv set v = JSON_MERGE_PATCH(v, {{arg .Patch}}) where k = '{{.Key}}'
+	// The values must be stored uncompressed as JSON, and the key is evicted from the cache.
+	MergeSQL string
+	// MergeArrays decides how Merge merges the arrays of the patch, default to MergeArraysReplace.
+	MergeArrays MergeArrayPolicy
+	// ReplaceAllPrefix limits the stale keys deleted by ReplaceAll to the ones with the prefix.
+	ReplaceAllPrefix string
+
+	// MaxResults is the max number of the rows of Keys and All, or the refresh, beyond which they stop scanning
+	// and fail with ErrResultTooLarge, e.g. to guard against a KeysSQL missing its where clause. 0 disables it.
+	MaxResults int
+
+	// BatchSize is the max number of rows in a statement of SetManySQL, default to 100.
+	BatchSize int
+
+	// Codec marshals and unmarshals the option, default to codec.JSON.
+	Codec codec.Codec
+	// CodecResolver resolves the codec for the key, falling back to Codec when it is nil or returns nil.
+	CodecResolver func(key string) codec.Codec
+	// OptionColumns maps the fields of gokv.Option to the dedicated columns, e.g. {"TTL": "ttl"},
+	// instead of one encoded option column. The column values are available as {{.Columns.ttl}} in SetSQL,
+	// where the booleans are true or false, the durations are in nanoseconds, and the others are as in JSON.
+	// GetSQL selects the value column first, and then the option columns by their names,
+	// from which the option is assembled.
+	OptionColumns map[string]string
+
+	// CacheableFn tells whether the key should be cached, default to caching all the keys.
+	// The keys not cacheable are always read from the database by Get, and skipped by Set and the refreshing.
+	CacheableFn func(key string) bool
+
+	// MigrationCodec decodes the options encoded by either JSON or gob, and encodes the new ones by JSON,
+	// for migrating the option column from gob to JSON. It replaces Codec with codec.Chain(codec.JSON, codec.Gob).
+	MigrationCodec bool
+
+	// RequireOption makes Get fail with ErrOptionRequired when the option column of an existing row
+	// is NULL or empty, or GetSQL selects no option column at all.
+	// Otherwise a missing option is treated as the zero gokv.Option.
+	RequireOption bool
+
+	// AuditSink records the mutations of Set and Del after they succeed, with the prior values from the cache,
+	// which are empty for the keys not cached, unless AuditPreRead is set to read them from the database
+	// at the cost of an extra query.
+	AuditSink    AuditSink
+	AuditPreRead bool
+
+	// Validation validates the keys and the values of the writes when it is not nil,
+	// the merged values of Merge are validated unless MergeSQL merges them in the database.
+	Validation *util.ValidationConfig
+
+	// StrictColumns requires GetSQL to select exactly the value and the option columns,
+	// otherwise the option column is optional and the extra columns are ignored.
+	StrictColumns bool
+
+	// GenerateLock holds a database advisory lock of the key around generating the missing value in Get,
+	// so that only one of the nodes runs the generator, and the others read the value generated.
+	// The lock is taken by LockSQL and released by UnlockSQL on the same connection, whose defaults
+	// are AdvisoryLockSQL(DriverName), and the lock name of the key is available as {{.LockKey}}.
+	GenerateLock bool
+	LockSQL      string
+	UnlockSQL    string
+
+	// MultiValuePolicy decides the value of Get when multiple rows of the key are selected,
+	// default to MultiValueError.
+	MultiValuePolicy MultiValuePolicy
+
+	// LazyOption skips decoding the option column in Get, the option is decoded on demand by OptionOf.
+	LazyOption bool
+
+	// Now returns the current time for the timestamps, default to time.Now.
+	Now func() time.Time
+	// TimeLocation is the location of the {{.Time}} template field, default to UTC.
+	// UTC is recommended to keep the timestamps consistent among servers in different time zones.
+	TimeLocation *time.Location
+	// ServerTimeSQL is the SQL function for {{.ServerTime}}, default to ServerTimeSQL(DriverName).
+	ServerTimeSQL string
+
+	// Cache caches the key values, default to a MapCache in memory.
+	Cache Cache
+
+	// Logger logs the queries and the warnings, default to the standard logger of the log package.
+	Logger Logger
+	// RedactFn redacts the sensitive values of the queries before they are logged, default to RedactValues.
+	// Set it to an identity function to log the queries as they are.
+	RedactFn func(sql string) string
+	// SlowQueryThreshold logs a warning of the operation and the key when a query takes longer than it,
+	// default to no logging.
+	SlowQueryThreshold time.Duration
 
 	// RefreshInterval will Refresh the key values from the database in every Refresh interval.
 	RefreshInterval time.Duration
+	// RefreshTimeout abandons a Refresh which does not complete within it, default to RefreshInterval.
+	// A tick of the refreshing is skipped with a warning when the refresh of the previous tick is still running.
+	RefreshTimeout time.Duration
+	// RefreshBatchSize makes Refresh query AllSQL by pages of at most RefreshBatchSize rows,
+	// AllSQL should page by {{.Limit}} and {{.Offset}} in a stable order, e.g.
+	// select k, v from kv where state = 1 order by k limit {{.Limit}} offset {{.Offset}}
+	RefreshBatchSize int
+	// RefreshRate limits the pages queried per second in the batched refresh, default to no limit.
+	RefreshRate float64
+	// RefreshLimiter overrides the limiter created by RefreshRate, which is waited for before each page.
+	RefreshLimiter Limiter
 }
 
 // Client is a gokv.Store implementation for SQL databases.
 type Client struct {
 	Config
 
-	cache     map[string]string
+	noCache map[string]bool
+	// queryHash is the hash of the GetSQL, which invalidates the values cached by a different one.
+	queryHash string
+	// cacheLock serializes the updates of the Cache and noCache.
 	cacheLock sync.Mutex
+
+	// templates memoizes the parsedTemplate of the SQL templates.
+	templates sync.Map
+
+	// hashedKeys maps the hashed keys to the logical keys when KeyHashFn is set.
+	hashedKeys sync.Map
+
+	metrics *metrics
+
+	dbLock sync.Mutex
+	// dbs are the shared databases keyed by the data source names.
+	dbs map[string]*sql.DB
+	// fixedSQL are the SQL without template actions, which are prepared in the PrepareStatements mode.
+	fixedSQL map[string]bool
+	// stmts are the prepared statements keyed by stmtKey.
+	stmts sync.Map
+
+	// batcher coalesces the Gets in the BatchWindow.
+	batcher *getBatcher
+	// ops is the semaphore of MaxConcurrentOps.
+	ops chan struct{}
+
+	// writes are the times of the last writes of the keys for ReadYourWrites,
+	// the empty key is the time of the last write of any key.
+	writes sync.Map
+
+	refreshLock sync.Mutex
+	refreshStop chan struct{}
+	refreshDone chan struct{}
+	// refreshing is 1 while a ticker refresh is running.
+	refreshing int32
+	// closed is 1 after Close until the client is restarted by StartRefresh.
+	closed int32
+
+	statusLock sync.Mutex
+	status     RefreshStatus
+}
+
+// CacheValue is the value cached in memory.
+type CacheValue struct {
+	Value      string
+	Option     gokv.Option
+	UpdateTime time.Time
+	// QueryHash is the hash of the GetSQL of the client which cached the value.
+	QueryHash string
+
+	// rawOption is the option not decoded yet in the LazyOption mode.
+	rawOption []byte
+}
+
+// Expired tells whether the cached value is expired at now according to its TTL.
+func (v CacheValue) Expired(now time.Time) bool {
+	return v.Option.TTL > 0 && now.Sub(v.UpdateTime) >= v.Option.TTL
 }
 
 func DefaultDuration(s, defaultValue time.Duration) time.Duration {
@@ -48,261 +358,1171 @@ func Default(s, defaultValue string) string {
 	return s
 }
 
+// timeLayout is the layout of the {{.Time}} template field.
+const timeLayout = `2006-01-02 15:04:05.000`
+
+// formatTime formats the current time in the TimeLocation for the {{.Time}} template field.
+func (c *Client) formatTime() string { return c.Now().In(c.TimeLocation).Format(timeLayout) }
+
 const (
-	DefaultAllSQL = `select k,v from kv where state = 1`
-	DefaultGetSQL = `select v from kv where k = '{{.Key}}' and state = 1`
-	DefaultSetSQL = `insert into kv(k, v, state, created) values('{{.Key}}', '{{.Value}}', 1, '{{.Time}}') 
+	DefaultAllSQL  = `select k,v from kv where state = 1`
+	DefaultKeysSQL = `select k from kv where state = 1`
+	DefaultGetSQL  = `select v from kv where k = '{{.Key}}' and state = 1`
+	DefaultSetSQL  = `insert into kv(k, v, state, created) values('{{.Key}}', '{{.Value}}', 1, '{{.Time}}') 
 					 on duplicate key update v = '{{.Value}}', updated = '{{.Time}}', state = 1`
-	DefaultDelSQL = `update kv set state = 0  where k = '{{.Key}}'`
+	DefaultPrefixSQL     = `select k, v from kv where k like {{arg .Pattern}} and state = 1`
+	DefaultAllOrderedSQL = `select k, v from kv where state = 1 order by k`
+	DefaultGetBatchSQL   = `select k, v from kv where k in ({{range $i, $k := .Keys}}{{if $i}}, {{end}}{{arg $k}}{{end}}) and state = 1`
+	DefaultExportSQL     = `select k, v from kv where k > {{arg .Cursor}} and state = 1 order by k limit {{.Limit}}`
+	DefaultDelSQL        = `update kv set state = 0  where k = '{{.Key}}'`
+	DefaultSetBytesSQL   = `insert into kv(k, v, state, created) values('{{.Key}}', {{arg .Value}}, 1, '{{.Time}}') 
+					 on duplicate key update v = {{arg .Value}}, updated = '{{.Time}}', state = 1`
 )
 
+// NewClient creates a client of the config, whose databases are opened at their first uses,
+// so a bad DataSourceName only fails the operations, use Open instead to fail at the construction.
 func NewClient(c Config) *Client {
 	c.RefreshInterval = DefaultDuration(c.RefreshInterval, 60*time.Second)
+	c.RefreshTimeout = DefaultDuration(c.RefreshTimeout, c.RefreshInterval)
+	c.QueryTimeout = DefaultDuration(c.QueryTimeout, 15*time.Second)
 	c.DriverName = Default(c.DriverName, "mysql")
+	c.ServerTimeSQL = Default(c.ServerTimeSQL, ServerTimeSQL(c.DriverName))
 	c.AllSQL = Default(c.AllSQL, DefaultAllSQL)
+	c.KeysSQL = Default(c.KeysSQL, DefaultKeysSQL)
 	c.GetSQL = Default(c.GetSQL, DefaultGetSQL)
 	c.SetSQL = Default(c.SetSQL, DefaultSetSQL)
 	c.DelSQL = Default(c.DelSQL, DefaultDelSQL)
+	c.PrefixSQL = Default(c.PrefixSQL, DefaultPrefixSQL)
+	c.ExportSQL = Default(c.ExportSQL, DefaultExportSQL)
+	c.AllOrderedSQL = Default(c.AllOrderedSQL, DefaultAllOrderedSQL)
+	c.GetBatchSQL = Default(c.GetBatchSQL, DefaultGetBatchSQL)
+	if c.BatchMaxKeys <= 0 {
+		c.BatchMaxKeys = 100
+	}
+	c.SetBytesSQL = Default(c.SetBytesSQL, DefaultSetBytesSQL)
+	lockSQL, unlockSQL := AdvisoryLockSQL(c.DriverName)
+	c.LockSQL = Default(c.LockSQL, lockSQL)
+	c.UnlockSQL = Default(c.UnlockSQL, unlockSQL)
+	if c.BatchSize <= 0 {
+		c.BatchSize = 100
+	}
+	if c.MigrationCodec {
+		c.Codec = codec.Chain(codec.JSON, codec.Gob)
+	} else if c.Codec == nil {
+		c.Codec = codec.JSON
+	}
+	if c.Logger == nil {
+		c.Logger = stdLogger{}
+	}
+	if c.Cache == nil {
+		c.Cache = NewMapCache()
+	}
+	if c.RedactFn == nil {
+		c.RedactFn = RedactValues
+	}
+	if c.Compressor == nil {
+		c.Compressor = Gzip{}
+	}
+	if c.Now == nil {
+		c.Now = time.Now
+	}
+	if c.Backoff == nil {
+		c.Backoff = ConstantBackoff(c.RetryInterval)
+	}
+	if c.TimeLocation == nil {
+		c.TimeLocation = time.UTC
+	}
+	if c.RefreshLimiter == nil {
+		c.RefreshLimiter = rate.NewLimiter(rate.Inf, 1)
+		if c.RefreshRate > 0 {
+			c.RefreshLimiter = rate.NewLimiter(rate.Limit(c.RefreshRate), 1)
+		}
+	}
 
 	client := &Client{
-		Config: c,
-		cache:  make(map[string]string),
+		Config:    c,
+		noCache:   make(map[string]bool),
+		queryHash: hashQuery(c.GetSQL),
+		metrics:   newMetrics(),
+	}
+	if c.MaxConcurrentOps > 0 {
+		client.ops = make(chan struct{}, c.MaxConcurrentOps)
+	}
+	client.fixedSQL = make(map[string]bool)
+	for _, query := range []string{c.GetSQL, c.SetSQL, c.DelSQL, c.KeysSQL, c.AllSQL} {
+		if !strings.Contains(query, "{{") {
+			client.fixedSQL[query] = true
+		}
+	}
+	if c.BatchWindow > 0 && c.QueryBuilder == nil {
+		client.batcher = &getBatcher{c: client}
 	}
 
-	go client.tickerRefresh()
+	client.StartRefresh()
 
 	return client
 }
 
+// MultiValuePolicy is the policy of Get when multiple rows of the key are selected.
+type MultiValuePolicy int
+
+const (
+	// MultiValueError fails Get with ErrTooManyValues.
+	MultiValueError MultiValuePolicy = iota
+	// MultiValueFirst takes the value of the first row.
+	MultiValueFirst
+	// MultiValueLast takes the value of the last row.
+	MultiValueLast
+)
+
 var (
 	// ErrTooManyValues is the error to identify more than one values associated with a key.
 	ErrTooManyValues = errors.New("more than one values associated with the key")
+	// ErrColumnCount is the error when GetSQL selects an unexpected number of columns in the StrictColumns mode.
+	ErrColumnCount = errors.New("unexpected number of columns")
+	// ErrOptionRequired is the error when the option is missing in the RequireOption mode.
+	ErrOptionRequired = errors.New("option is required but missing")
+	// ErrValueColumn is the error when GetSQL does not select the ValueColumn.
+	ErrValueColumn = errors.New("value column not selected")
+	// ErrClientClosed is the error of the operations after Close.
+	ErrClientClosed = errors.New("sqlc: client is closed")
 )
 
-func (c *Client) tickerRefresh() {
+var _ gokv.Closer = (*Client)(nil)
+
+// codecOf returns the codec for the key.
+func (c *Client) codecOf(k string) codec.Codec {
+	if c.CodecResolver != nil {
+		if kc := c.CodecResolver(k); kc != nil {
+			return kc
+		}
+	}
+
+	return c.Codec
+}
+
+// StartRefresh (re)starts the goroutine which refreshes the cache in every RefreshInterval.
+// It does nothing if the refreshing is already running or RefreshInterval is not positive.
+// A closed client is reopened by it, which is meant for long-lived supervisors,
+// otherwise creating a new client is preferred.
+func (c *Client) StartRefresh() {
+	c.refreshLock.Lock()
+	defer c.refreshLock.Unlock()
+
+	if c.RefreshInterval <= 0 || c.refreshStop != nil {
+		return
+	}
+
+	atomic.StoreInt32(&c.closed, 0)
+
+	c.refreshStop = make(chan struct{})
+	c.refreshDone = make(chan struct{})
+
+	go c.tickerRefresh(c.refreshStop, c.refreshDone)
+}
+
+// StopRefresh stops the refreshing goroutine and waits for it to exit.
+func (c *Client) StopRefresh() {
+	c.refreshLock.Lock()
+	defer c.refreshLock.Unlock()
+
+	if c.refreshStop == nil {
+		return
+	}
+
+	close(c.refreshStop)
+	<-c.refreshDone
+
+	c.refreshStop = nil
+	c.refreshDone = nil
+}
+
+// Close tears down the client by stopping the refreshing goroutine and closing the databases,
+// the following operations fail with ErrClientClosed.
+func (c *Client) Close() error {
+	atomic.StoreInt32(&c.closed, 1)
+	c.StopRefresh()
+
+	return c.closeDBs()
+}
+
+func (c *Client) tickerRefresh(stop <-chan struct{}, done chan<- struct{}) {
+	defer close(done)
+
+	// the running refresh is canceled and waited when stopped
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	defer cancel()
+
 	ticker := time.NewTicker(c.RefreshInterval)
-	for range ticker.C {
-		if _, err := c.All(); err != nil {
-			log.Printf("W! refersh error %v", err)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			if !atomic.CompareAndSwapInt32(&c.refreshing, 0, 1) {
+				c.Logger.Printf("W! refresh skipped, the previous refresh is still running")
+				continue
+			}
+
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				defer atomic.StoreInt32(&c.refreshing, 0)
+
+				if err := c.refresh(ctx); err != nil {
+					c.Logger.Printf("W! refersh error %v", err)
+				}
+			}()
 		}
 	}
 }
 
-// Keys list the keys in the store.
-func (c *Client) All() (kvs map[string]string, er error) {
-	t, err := template.New("").Parse(c.AllSQL)
+// All lists the key values in the store, and refreshes the cache with them.
+func (c *Client) All() (map[string]string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), c.QueryTimeout)
+	defer cancel()
+
+	return c.all(ctx)
+}
+
+func (c *Client) all(ctx context.Context) (kvs map[string]string, er error) {
+	defer c.metrics.observe("all", time.Now())
+
+	query, args, err := c.render(c.AllSQL, map[string]string{})
 	if err != nil {
 		return nil, err
 	}
+	c.logQuery(query)
 
-	var out bytes.Buffer
-	if err := t.Execute(&out, map[string]string{}); err != nil {
+	db, err := c.openDB(c.readDataSourceName(""))
+	if err != nil {
+		return nil, err
+	}
+
+	if kvs, _, err = c.queryKVs(ctx, db, query, args...); err != nil {
 		return nil, err
 	}
-	query := out.String()
-	log.Printf("D! query: %s", query)
 
-	db, err := sql.Open(c.DriverName, c.DataSourceName)
+	c.replaceCache(kvs)
+
+	return kvs, nil
+}
+
+// ErrResultTooLarge is the error when Keys or All query more rows than the Config.MaxResults.
+type ErrResultTooLarge struct {
+	Limit int
+}
+
+func (e ErrResultTooLarge) Error() string {
+	return fmt.Sprintf("result set exceeds the max results of %d rows", e.Limit)
+}
+
+// queryKVs queries the key values by the query of AllSQL, and returns them with the number of the rows.
+func (c *Client) queryKVs(ctx context.Context, db *sql.DB, query string, args ...interface{}) (
+	kvs map[string]string, n int, err error) {
+	kvs = make(map[string]string)
+	err = c.eachKV(ctx, db, query, args, func(k, v string) error {
+		if n++; c.MaxResults > 0 && n > c.MaxResults {
+			return ErrResultTooLarge{Limit: c.MaxResults}
+		}
+
+		kvs[k] = v
+		return nil
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return kvs, n, nil
+}
+
+// eachKV queries the key values by the query, and calls fn with the rows as they are scanned,
+// until fn returns an error.
+func (c *Client) eachKV(ctx context.Context, db *sql.DB, query string, args []interface{},
+	fn func(k, v string) error) error {
+	return c.eachRawKV(ctx, db, query, args, func(k, v string) error { return fn(c.logicalKey(k), v) })
+}
+
+// eachRawKV is eachKV with the keys as they are stored in the database, e.g. the hashed ones with KeyHashFn.
+func (c *Client) eachRawKV(ctx context.Context, db *sql.DB, query string, args []interface{},
+	fn func(k, v string) error) (er error) {
+	rows, release, err := c.queryContext(ctx, db, "all", "", query, args...)
 	if err != nil {
+		return err
+	}
+
+	defer func() { er = multierr.Combine(er, drainAndClose(rows), release()) }()
+
+	cols, _ := rows.Columns()
+	for rows.Next() {
+		columns := make([]sql.NullString, len(cols))
+		pointers := make([]interface{}, len(cols))
+		for i := range columns {
+			pointers[i] = &columns[i]
+		}
+
+		if err := rows.Scan(pointers...); err != nil {
+			return err
+		}
+		c.metrics.scanned()
+
+		v, err := c.decompress(columns[1].String)
+		if err != nil {
+			return err
+		}
+
+		if err := fn(columns[0].String, v); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// replaceCache replaces the cache with the key values refreshed from the database.
+func (c *Client) replaceCache(kvs map[string]string) {
+	now := c.Now()
+
+	c.cacheLock.Lock()
+	defer c.cacheLock.Unlock()
+
+	for _, k := range c.Cache.Keys() {
+		if _, ok := kvs[k]; !ok || c.noCache[k] || !c.cacheable(k) {
+			c.evict(k)
+		}
+	}
+
+	for k, v := range kvs {
+		if c.noCache[k] || !c.cacheable(k) {
+			continue
+		}
+
+		old, _ := c.Cache.Get(k)
+		c.Cache.Set(k, CacheValue{Value: v, Option: old.Option, UpdateTime: now, QueryHash: c.queryHash,
+			rawOption: old.rawOption})
+	}
+}
+
+// evict removes the key from the cache, the cacheLock must be held.
+func (c *Client) evict(k string) {
+	if _, ok := c.Cache.Get(k); ok {
+		c.Cache.Del(k)
+		c.metrics.evict(1)
+	}
+}
+
+// Keys list the keys in the store.
+func (c *Client) Keys() (keys []string, er error) { return c.KeysContext(background()) }
+
+// KeysContext list the keys in the store like Keys, the query is bound to the ctx.
+func (c *Client) KeysContext(ctx context.Context) (keys []string, er error) {
+	defer c.metrics.observe("keys", time.Now())
+
+	var query string
+	var args []interface{}
+	var err error
+	if c.QueryBuilder != nil {
+		query, args = c.QueryBuilder.BuildKeys()
+	} else if query, args, err = c.render(c.KeysSQL, map[string]string{}); err != nil {
 		return nil, err
 	}
+	c.logQuery(query)
 
-	defer func() { er = multierr.Append(er, db.Close()) }()
+	db, err := c.openDB(c.readDataSourceName(""))
+	if err != nil {
+		return nil, err
+	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	ctx, cancel := c.queryTimeout(ctx)
 	defer cancel()
 
-	rows, err := db.QueryContext(ctx, query)
+	rows, release, err := c.queryContext(ctx, db, "keys", "", query, args...)
 	if err != nil {
 		return nil, err
 	}
 
+	defer func() { er = multierr.Combine(er, drainAndClose(rows), release()) }()
+
+	return c.scanKeys(rows)
+}
+
+// scanKeys scans the logical keys from the rows of KeysSQL.
+func (c *Client) scanKeys(rows *sql.Rows) (keys []string, err error) {
 	cols, _ := rows.Columns()
-	kvs = make(map[string]string)
-	for row := 0; rows.Next(); row++ {
+	keyIndex := 0
+	for i, col := range cols {
+		if c.KeyColumn != "" && strings.EqualFold(col, c.KeyColumn) {
+			keyIndex = i
+			break
+		}
+	}
+
+	for rows.Next() {
 		columns := make([]sql.NullString, len(cols))
 		pointers := make([]interface{}, len(cols))
 		for i := range columns {
 			pointers[i] = &columns[i]
 		}
 
+		if c.MaxResults > 0 && len(keys) == c.MaxResults {
+			return nil, ErrResultTooLarge{Limit: c.MaxResults}
+		}
+
 		if err := rows.Scan(pointers...); err != nil {
 			return nil, err
 		}
+		c.metrics.scanned()
 
-		kvs[columns[0].String] = columns[1].String
+		keys = append(keys, c.logicalKey(columns[keyIndex].String))
 	}
 
-	c.cacheLock.Lock()
-	c.cache = kvs
-	c.cacheLock.Unlock()
+	return keys, rows.Err()
+}
 
-	return kvs, nil
+// parsedTemplate is the template parsed from a SQL template, or the error of parsing.
+type parsedTemplate struct {
+	t   *template.Template
+	err error
 }
 
-// Set stores the given value for the given key.
-// Values are automatically marshalled to JSON or gob (depending on the configuration).
-// The key must not be "" and the value must not be nil.
-func (c *Client) Set(k, v string) (er error) {
-	t, err := template.New("").Parse(c.SetSQL)
+// parse parses the SQL template once, both the parsed template and the error are memoized,
+// so that a malformed template is not parsed again in every operation.
+func (c *Client) parse(sqlTemplate string) (*template.Template, error) {
+	if p, ok := c.templates.Load(sqlTemplate); ok {
+		return p.(parsedTemplate).t, p.(parsedTemplate).err
+	}
+
+	t, err := template.New("").Funcs(c.argFuncs(nil)).Parse(sqlTemplate)
+	p, _ := c.templates.LoadOrStore(sqlTemplate, parsedTemplate{t: t, err: err})
+
+	return p.(parsedTemplate).t, p.(parsedTemplate).err
+}
+
+// render renders the SQL template with the data, and returns the statement with the arguments bound by {{arg}}.
+func (c *Client) render(sqlTemplate string, data interface{}) (query string, args []interface{}, err error) {
+	defer c.metrics.observePhase("render", time.Now())
+
+	parsed, err := c.parse(sqlTemplate)
 	if err != nil {
-		return err
+		return "", nil, err
 	}
 
+	// the memoized template is cloned to bind the arguments of this rendering
+	t, err := parsed.Clone()
+	if err != nil {
+		return "", nil, err
+	}
+	t.Funcs(c.argFuncs(&args))
+
 	var out bytes.Buffer
-	if err := t.Execute(&out, map[string]string{
-		"Key":   k,
-		"Value": v,
-		"Time":  time.Now().Format(`2006-01-02 15:04:05.000`),
-	}); err != nil {
+	if err := t.Execute(&out, data); err != nil {
+		return "", nil, err
+	}
+
+	return out.String(), args, nil
+}
+
+// drainAndClose closes the rows to return the connection to the pool,
+// and returns the error encountered during the iteration if any.
+func drainAndClose(rows *sql.Rows) error {
+	closeErr := rows.Close()
+	if err := rows.Err(); err != nil {
 		return err
 	}
 
-	query := out.String()
-	log.Printf("D! query: %s", query)
+	return closeErr
+}
+
+// Invalidate removes the keys from the cache without touching the database,
+// so that the next Get reloads them from the database.
+func (c *Client) Invalidate(keys ...string) {
+	c.cacheLock.Lock()
+	defer c.cacheLock.Unlock()
+
+	for _, k := range keys {
+		c.evict(k)
+	}
+}
+
+// InvalidateAll clears the cache without touching the database.
+func (c *Client) InvalidateAll() {
+	c.cacheLock.Lock()
+	defer c.cacheLock.Unlock()
+
+	c.metrics.evict(len(c.Cache.Keys()))
+	c.Cache.Clear()
+}
+
+// CacheSnapshot returns a copy of the in-memory cache.
+func (c *Client) CacheSnapshot() map[string]CacheValue {
+	c.cacheLock.Lock()
+	defer c.cacheLock.Unlock()
+
+	keys := c.Cache.Keys()
+	m := make(map[string]CacheValue, len(keys))
+	for _, k := range keys {
+		if v, ok := c.Cache.Get(k); ok {
+			m[k] = v
+		}
+	}
+
+	return m
+}
+
+// LoadCacheSnapshot replaces the in-memory cache with m, e.g. one saved by CacheSnapshot before a restart,
+// without querying the database. The values with gokv.NoCache() are kept out of the cache.
+func (c *Client) LoadCacheSnapshot(m map[string]CacheValue) error {
+	for k := range m {
+		if err := util.CheckKey(k); err != nil {
+			return fmt.Errorf("key:%q, error:%w", k, err)
+		}
+	}
+
+	cache := make(map[string]CacheValue, len(m))
+	noCache := make(map[string]bool)
+	for k, v := range m {
+		if v.Option.NoCache {
+			noCache[k] = true
+		} else if c.cacheable(k) {
+			cache[k] = v
+		}
+	}
+
+	c.cacheLock.Lock()
+	defer c.cacheLock.Unlock()
+
+	c.Cache.Clear()
+	for k, v := range cache {
+		c.Cache.Set(k, v)
+	}
+	c.noCache = noCache
+
+	return nil
+}
+
+// Set stores the given value for the given key.
+// The key must not be "".
+// The value is kept out of the in-memory cache when gokv.NoCache() is applied.
+func (c *Client) Set(k, v string, fns ...gokv.OptionFn) error {
+	return c.SetContext(background(), k, v, fns...)
+}
+
+// SetContext stores the value like Set, the statement is bound to the ctx.
+func (c *Client) SetContext(ctx context.Context, k, v string, fns ...gokv.OptionFn) error {
+	_, err := c.setX(ctx, k, v, fns...)
+	return err
+}
+
+// SetX stores the value like Set, and returns the updated time written as {{.Time}}, truncated to its
+// milliseconds precision, which is also the UpdateTime of the cached value, e.g. as the version token
+// of the following conditional writes. With {{.ServerTime}}, it is the client time of the statement instead.
+func (c *Client) SetX(k, v string, fns ...gokv.OptionFn) (updated time.Time, er error) {
+	return c.setX(background(), k, v, fns...)
+}
+
+func (c *Client) setX(ctx context.Context, k, v string, fns ...gokv.OptionFn) (updated time.Time, er error) {
+	defer c.metrics.observe("set", time.Now())
+
+	if err := c.validateKV(k, v); err != nil {
+		return time.Time{}, err
+	}
+
+	stored, err := c.compress(v)
+	if err != nil {
+		return time.Time{}, err
+	}
 
-	db, err := sql.Open(c.DriverName, c.DataSourceName)
+	oldV, err := c.priorValue(ctx, k)
 	if err != nil {
-		return err
+		return time.Time{}, err
+	}
+
+	if updated, err = c.set(ctx, c.SetSQL, c.setArgs(), k, v, stored, fns...); err != nil {
+		return time.Time{}, err
 	}
 
-	defer func() { er = multierr.Append(er, db.Close()) }()
+	c.audit("set", k, oldV, v)
 
-	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	return updated, nil
+}
+
+// validateKV validates the key and the value by the Validation if any.
+func (c *Client) validateKV(k, v string) error {
+	if c.Validation == nil {
+		return nil
+	}
+
+	return util.Validate(k, v, *c.Validation)
+}
+
+// set stores the value v of the key by the statement of sqlTemplate, value is the argument bound by {{arg .Value}},
+// or by the bound arguments named by setArgs.
+func (c *Client) set(ctx context.Context, sqlTemplate string, setArgs []string, k, v string, value interface{},
+	fns ...gokv.OptionFn) (updated time.Time, er error) {
+	// the same time as formatted to {{.Time}}
+	now := c.Now().Truncate(time.Millisecond)
+	option := gokv.NewOption(fns...)
+	query, args, err := c.setStatement(sqlTemplate, setArgs, k, value, option, now)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	db, err := c.openDB(c.DataSourceName)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	ctx, cancel := c.queryTimeout(ctx)
 	defer cancel()
 
-	if _, err := db.ExecContext(ctx, query); err != nil {
-		return err
+	if _, err := c.execContext(ctx, db, "set", k, query, args...); err != nil {
+		return time.Time{}, err
+	}
+
+	c.cacheSetAt(k, v, option, now)
+
+	return now, nil
+}
+
+// setStatement builds the statement of sqlTemplate, or the QueryBuilder, to store the value of the key at now,
+// the fields named by setArgs are bound as the arguments in order.
+func (c *Client) setStatement(sqlTemplate string, setArgs []string, k string, value interface{}, option gokv.Option,
+	now time.Time) (query string, args []interface{}, err error) {
+	optionData, err := c.marshalOption(k, option)
+	if err != nil {
+		return "", nil, err
 	}
 
+	data, err := c.setData(k, value, optionData, option, now)
+	if err != nil {
+		return "", nil, err
+	}
+
+	if c.QueryBuilder != nil {
+		query, args = c.QueryBuilder.BuildSet(c.backendKey(k), value, optionData)
+	} else if query, args, err = c.render(sqlTemplate, data); err != nil {
+		return "", nil, err
+	} else if len(setArgs) > 0 {
+		if args, err = bindArgs("SetSQL", setArgs, args, data); err != nil {
+			return "", nil, err
+		}
+	}
+	c.logQuery(query)
+
+	return query, args, nil
+}
+
+// setData returns the fields of SetSQL to store the value of the key with the encoded option at now.
+func (c *Client) setData(k string, value interface{}, optionData string, option gokv.Option, now time.Time) (
+	map[string]interface{}, error) {
+	optionColumns, err := c.optionColumnValues(option)
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]interface{}{
+		"Key":        c.backendKey(k),
+		"RawKey":     k,
+		"Value":      value,
+		"Option":     optionData,
+		"Columns":    optionColumns,
+		"Time":       now.In(c.TimeLocation).Format(timeLayout),
+		"ServerTime": c.ServerTimeSQL,
+	}, nil
+}
+
+// cacheSet caches the value just stored, unless it is stored with NoCache.
+func (c *Client) cacheSet(k, v string, option gokv.Option) { c.cacheSetAt(k, v, option, c.Now()) }
+
+// cacheSetAt caches the value of the key updated at the time.
+func (c *Client) cacheSetAt(k, v string, option gokv.Option, updated time.Time) {
 	c.cacheLock.Lock()
-	c.cache[k] = v
-	c.cacheLock.Unlock()
+	defer c.cacheLock.Unlock()
 
-	return nil
+	if option.NoCache {
+		c.evict(k)
+		c.noCache[k] = true
+	} else {
+		delete(c.noCache, k)
+		if c.cacheable(k) {
+			c.Cache.Set(k, CacheValue{Value: v, Option: option, UpdateTime: updated, QueryHash: c.queryHash})
+		}
+	}
 }
 
+// cacheable tells whether the key should be cached by CacheableFn.
+func (c *Client) cacheable(k string) bool { return c.CacheableFn == nil || c.CacheableFn(k) }
+
 // Get retrieves the stored value for the given key.
-// You need to pass a pointer to the value, so in case of a struct
-// the automatic unmarshalling can populate the fields of the object
-// that v points to with the values of the retrieved object's values.
-// If no value is found it returns (false, nil).
-func (c *Client) Get(k string) (found bool, v string, er error) {
+// If no value is found and the generator fn is not nil,
+// the value generated by fn will be stored and returned.
+// If no value is found or generated, it returns (false, "", gokv.Option{}, nil).
+// In the LazyOption mode, the option read from the database is not decoded, use OptionOf instead.
+func (c *Client) Get(k string, fn gokv.GeneratorFn) (found bool, v string, option gokv.Option, er error) {
+	return c.GetContext(background(), k, fn)
+}
+
+// GetContext retrieves the stored value for the given key like Get,
+// the queries and the statements of the generated value are bound to the ctx.
+func (c *Client) GetContext(ctx context.Context, k string, fn gokv.GeneratorFn) (
+	found bool, v string, option gokv.Option, er error) {
+	defer c.metrics.observe("get", time.Now())
+
+	if cv, ok := c.cached(k); ok {
+		return true, cv.Value, cv.Option, nil
+	}
+
+	if found, v, option, er = c.load(ctx, k); er != nil || found {
+		return found, v, option, er
+	}
+
+	return c.generateMissing(ctx, k, fn)
+}
+
+// GetFresh retrieves the stored value for the given key from the database like Get, bypassing the cache read
+// for the reads which must not see a stale value, while the value loaded is still cached.
+// The cached value is evicted when the key is missing from the database.
+func (c *Client) GetFresh(k string, fn gokv.GeneratorFn) (found bool, v string, option gokv.Option, er error) {
+	defer c.metrics.observe("get", time.Now())
+
+	ctx := background()
+	if found, v, option, er = c.load(ctx, k); er != nil || found {
+		return found, v, option, er
+	}
+
+	c.Invalidate(k)
+
+	return c.generateMissing(ctx, k, fn)
+}
+
+// generateMissing generates the value of the missing key by fn if it is not nil.
+func (c *Client) generateMissing(ctx context.Context, k string, fn gokv.GeneratorFn) (
+	found bool, v string, option gokv.Option, er error) {
+	if fn == nil {
+		return false, "", option, nil
+	}
+
+	if c.GenerateLock {
+		return c.generateLocked(ctx, k, fn)
+	}
+
+	return c.generate(ctx, k, fn)
+}
+
+// GetWithSource retrieves the stored value for the given key like Get without a generator,
+// and tells whether the value is from the cache or the database.
+func (c *Client) GetWithSource(k string) (found bool, v string, option gokv.Option, fromCache bool, er error) {
+	defer c.metrics.observe("get", time.Now())
+
+	if cv, ok := c.cached(k); ok {
+		return true, cv.Value, cv.Option, true, nil
+	}
+
+	found, v, option, er = c.load(background(), k)
+
+	return found, v, option, false, er
+}
+
+// cached returns the cached value of the key if it is not expired, and counts the cache hit or miss.
+func (c *Client) cached(k string) (CacheValue, bool) {
 	c.cacheLock.Lock()
-	if v, ok := c.cache[k]; ok {
-		c.cacheLock.Unlock()
+	cv, ok := c.validCache(k)
+	c.cacheLock.Unlock()
+
+	if ok {
+		c.metrics.hit()
+		return cv, true
+	}
+
+	c.metrics.miss()
+
+	return CacheValue{}, false
+}
+
+// validCache returns the cached value of the key unless it is expired, the cacheLock must be held.
+// The value cached by a different GetSQL, e.g. by another version of the client sharing the Cache
+// or by a loaded snapshot, is evicted, so that the cache heals itself after the GetSQL changes.
+// Nothing is valid after Close, so that the reads fail with ErrClientClosed instead of hitting the cache.
+func (c *Client) validCache(k string) (CacheValue, bool) {
+	if atomic.LoadInt32(&c.closed) == 1 {
+		return CacheValue{}, false
+	}
+
+	cv, ok := c.Cache.Get(k)
+	if !ok {
+		return CacheValue{}, false
+	}
+
+	if cv.QueryHash != c.queryHash {
+		c.evict(k)
+		return CacheValue{}, false
+	}
+
+	return cv, !cv.Expired(c.Now())
+}
+
+// hashQuery returns the hash of the SQL template in hex.
+func hashQuery(query string) string {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(query))
+	return strconv.FormatUint(h.Sum64(), 16)
+}
+
+// load queries the value of the key from the database, and caches it unless it is NoCache.
+func (c *Client) load(ctx context.Context, k string) (found bool, v string, option gokv.Option, er error) {
+	found, v, rawOption, err := c.get(ctx, k)
+	if err != nil || !found {
+		return false, "", option, err
+	}
+
+	if option, err = c.loaded(k, v, rawOption); err != nil {
+		return false, "", option, err
+	}
+
+	return true, v, option, nil
+}
 
-		return true, v, nil
+// loaded decodes the option of the value loaded of the key and caches them.
+func (c *Client) loaded(k, v string, rawOption []byte) (option gokv.Option, err error) {
+	if !c.LazyOption {
+		if option, err = c.decodeOption(k, rawOption); err != nil {
+			return option, err
+		}
+	}
+
+	c.cacheLock.Lock()
+	if option.NoCache || c.noCache[k] {
+		option.NoCache = true
+		c.noCache[k] = true
+	} else if c.cacheable(k) {
+		c.Cache.Set(k, CacheValue{Value: v, Option: option, UpdateTime: c.Now(), QueryHash: c.queryHash,
+			rawOption: rawOption})
 	}
 	c.cacheLock.Unlock()
 
-	t, err := template.New("").Parse(c.GetSQL)
+	return option, nil
+}
+
+// generate generates the value of the key by fn and stores it.
+func (c *Client) generate(ctx context.Context, k string, fn gokv.GeneratorFn) (
+	found bool, v string, option gokv.Option, er error) {
+	v, fns, err := fn(k)
 	if err != nil {
-		return false, "", err
+		return false, "", option, err
 	}
 
-	var out bytes.Buffer
-	if err := t.Execute(&out, map[string]string{"Key": k}); err != nil {
-		return false, "", err
+	if err := c.SetContext(ctx, k, v, fns...); err != nil {
+		return false, "", option, err
+	}
+
+	return true, v, gokv.NewOption(fns...), nil
+}
+
+// OptionOf returns the option of the key, which is decoded on demand in the LazyOption mode.
+func (c *Client) OptionOf(k string) (option gokv.Option, found bool, er error) {
+	c.cacheLock.Lock()
+	if cv, ok := c.validCache(k); ok {
+		defer c.cacheLock.Unlock()
+
+		if cv.rawOption != nil {
+			if cv.Option, er = c.decodeOption(k, cv.rawOption); er != nil {
+				return option, false, er
+			}
+
+			cv.rawOption = nil
+			c.Cache.Set(k, cv)
+		}
+
+		return cv.Option, true, nil
+	}
+	c.cacheLock.Unlock()
+
+	found, _, rawOption, err := c.get(background(), k)
+	if err != nil || !found {
+		return option, false, err
+	}
+
+	option, err = c.decodeOption(k, rawOption)
+
+	return option, err == nil, err
+}
+
+// decodeOption decodes the option of the key from the option column.
+func (c *Client) decodeOption(k string, rawOption []byte) (option gokv.Option, err error) {
+	if len(rawOption) > 0 {
+		defer c.metrics.observePhase("codec", time.Now())
+
+		if err = c.codecOf(k).Unmarshal(rawOption, &option); err != nil {
+			err = fmt.Errorf("key:%s, error:%w", k, codec.WrapError("unmarshal", &option, err))
+		}
 	}
 
-	query := out.String()
-	log.Printf("D! query: %s", query)
+	return option, err
+}
 
-	db, err := sql.Open(c.DriverName, c.DataSourceName)
+// get queries the value and the raw option of the key from the database.
+func (c *Client) get(ctx context.Context, k string) (found bool, v string, rawOption []byte, er error) {
+	if c.batcher != nil {
+		return c.batcher.get(ctx, k)
+	}
+
+	query, args, err := c.getQuery(k)
 	if err != nil {
-		return false, "", err
+		return false, "", nil, err
 	}
 
-	defer func() { er = multierr.Append(er, db.Close()) }()
+	db, err := c.openDB(c.readDataSourceName(k))
+	if err != nil {
+		return false, "", nil, err
+	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	ctx, cancel := c.queryTimeout(ctx)
 	defer cancel()
 
-	rows, err := db.QueryContext(ctx, query)
+	rows, release, err := c.queryContext(ctx, db, "get", k, query, args...)
+	if err != nil {
+		return false, "", nil, err
+	}
+
+	defer func() { er = multierr.Combine(er, drainAndClose(rows), release()) }()
+
+	return c.scanGet(k, rows)
+}
+
+// getOn gets the value of the key on the connection conn, bypassing the replica and the batcher.
+func (c *Client) getOn(ctx context.Context, conn *sql.Conn, k string) (
+	found bool, v string, rawOption []byte, er error) {
+	query, args, err := c.getQuery(k)
 	if err != nil {
-		return false, "", err
+		return false, "", nil, err
 	}
 
+	defer c.logSlow("get", k, time.Now())
+	c.metrics.query()
+	rows, err := conn.QueryContext(ctx, query, args...)
+	if err != nil {
+		return false, "", nil, c.mapError(err)
+	}
+
+	defer func() { er = multierr.Append(er, drainAndClose(rows)) }()
+
+	return c.scanGet(k, rows)
+}
+
+// getQuery builds the logged query of GetSQL for the key.
+func (c *Client) getQuery(k string) (query string, args []interface{}, err error) {
+	if c.QueryBuilder != nil {
+		query, args = c.QueryBuilder.BuildGet(c.backendKey(k))
+	} else if query, args, err = c.renderArgs("GetSQL", c.GetSQL,
+		map[string]interface{}{"Key": c.backendKey(k), "RawKey": k}); err != nil {
+		return "", nil, err
+	}
+	c.logQuery(query)
+
+	return query, args, nil
+}
+
+// scanGet scans the value and the raw option of the key from the rows of GetSQL.
+func (c *Client) scanGet(k string, rows *sql.Rows) (found bool, v string, rawOption []byte, err error) {
+	found, err = c.scanRows(k, rows, func(cols []string) (err error) {
+		if v, rawOption, err = c.scanValue(k, rows, cols); err != nil {
+			return err
+		}
+
+		if c.RequireOption && rawOption == nil {
+			return fmt.Errorf("key:%s, error:%w", k, ErrOptionRequired)
+		}
+		return nil
+	})
+	if err != nil || !found {
+		return false, "", nil, err
+	}
+
+	return true, v, rawOption, nil
+}
+
+// scanRows scans the rows of GetSQL of the key by scan, following MultiValuePolicy.
+func (c *Client) scanRows(k string, rows *sql.Rows, scan func(cols []string) error) (found bool, err error) {
 	cols, _ := rows.Columns()
+	if err := c.checkColumns("GetSQL", cols); err != nil {
+		return false, err
+	}
+
 	row := 0
 
 	for ; rows.Next(); row++ {
 		if row >= 1 {
-			return false, "", fmt.Errorf("key:%s, error:%w", k, ErrTooManyValues)
+			switch c.MultiValuePolicy {
+			case MultiValueFirst:
+				continue // drains the remaining rows
+			case MultiValueLast:
+			default:
+				return false, fmt.Errorf("key:%s, error:%w", k, ErrTooManyValues)
+			}
 		}
 
-		columns := make([]sql.NullString, len(cols))
-		pointers := make([]interface{}, len(cols))
-		for i := range columns {
-			pointers[i] = &columns[i]
+		if err := scan(cols); err != nil {
+			return false, err
 		}
+	}
 
-		if err := rows.Scan(pointers...); err != nil {
-			return false, "", err
-		}
+	return row >= 1, nil
+}
+
+// checkColumns checks the columns of the value and the option selected by the SQL of the name
+// in the StrictColumns mode.
+func (c *Client) checkColumns(name string, cols []string) error {
+	if c.StrictColumns && len(c.OptionColumns) > 0 && len(cols) != 1+len(c.OptionColumns) {
+		return fmt.Errorf("%s selects %d columns instead of value and %d option columns, error:%w",
+			name, len(cols), len(c.OptionColumns), ErrColumnCount)
+	} else if c.StrictColumns && len(c.OptionColumns) == 0 && len(cols) != 2 {
+		return fmt.Errorf("%s selects %d columns instead of value and option, error:%w",
+			name, len(cols), ErrColumnCount)
+	}
+
+	return nil
+}
+
+// scanValue scans the current row of GetSQL into the decompressed value and the raw option.
+func (c *Client) scanValue(k string, rows *sql.Rows, cols []string) (v string, rawOption []byte, err error) {
+	columns, err := c.scanColumns(rows, len(cols))
+	if err != nil {
+		return "", nil, err
+	}
+
+	return c.decodeValue(k, cols, columns)
+}
+
+// scanColumns scans the current row into the strings of the n columns.
+func (c *Client) scanColumns(rows *sql.Rows, n int) ([]sql.NullString, error) {
+	columns := make([]sql.NullString, n)
+	pointers := make([]interface{}, n)
+	for i := range columns {
+		pointers[i] = &columns[i]
+	}
+
+	if err := rows.Scan(pointers...); err != nil {
+		return nil, err
+	}
+	c.metrics.scanned()
+
+	return columns, nil
+}
+
+// decodeValue decodes the columns of the row of GetSQL into the decompressed value and the raw option.
+func (c *Client) decodeValue(k string, cols []string, columns []sql.NullString) (
+	v string, rawOption []byte, err error) {
+	valueIndex, optionIndex := c.valueColumns(cols)
+	if valueIndex < 0 {
+		return "", nil, fmt.Errorf("key:%s, column:%s, error:%w", k, c.ValueColumn, ErrValueColumn)
+	}
 
-		v = columns[0].String
+	if v, err = c.decompress(columns[valueIndex].String); err != nil {
+		return "", nil, err
+	}
+	if len(c.OptionColumns) > 0 {
+		if rawOption, err = c.optionFromColumns(k, cols, columns); err != nil {
+			return "", nil, err
+		}
+	} else if optionIndex < len(cols) && columns[optionIndex].String != "" {
+		rawOption = []byte(columns[optionIndex].String)
 	}
 
-	if row == 1 {
-		c.cacheLock.Lock()
-		c.cache[k] = v
-		c.cacheLock.Unlock()
+	return v, rawOption, nil
+}
 
-		return true, v, nil
+// valueColumns returns the indexes of the value column, which is -1 when the ValueColumn is not selected,
+// and the encoded option column, which is the first of the other columns.
+func (c *Client) valueColumns(cols []string) (value, option int) {
+	if c.ValueColumn == "" {
+		return 0, 1
+	}
+
+	for i, col := range cols {
+		if strings.EqualFold(col, c.ValueColumn) {
+			if i == 0 {
+				return 0, 1
+			}
+			return i, 0
+		}
 	}
 
-	return false, "", nil
+	return -1, -1
 }
 
 // Del deletes the stored value for the given key.
 // Deleting a non-existing key-value pair does NOT lead to an error.
 // The key must not be "".
-func (c *Client) Del(k string) (er error) {
+func (c *Client) Del(k string) (er error) { return c.DelContext(background(), k) }
+
+// DelContext deletes the stored value for the given key like Del, the statement is bound to the ctx.
+func (c *Client) DelContext(ctx context.Context, k string) (er error) {
+	defer c.metrics.observe("del", time.Now())
+
+	// the failure of the statement is only logged, so the closed client is checked ahead
+	if atomic.LoadInt32(&c.closed) == 1 {
+		return ErrClientClosed
+	}
+
+	oldV, err := c.priorValue(ctx, k)
+	if err != nil {
+		return err
+	}
+
 	c.cacheLock.Lock()
-	delete(c.cache, k)
+	c.evict(k)
+	delete(c.noCache, k)
 	c.cacheLock.Unlock()
 
 	defer func() {
-		if err := c.del(k); err != nil {
-			log.Printf("W! failed to del %v", err)
+		if err := c.del(ctx, k); err != nil {
+			c.Logger.Printf("W! failed to del %v", err)
+		} else {
+			c.audit("del", k, oldV, "")
 		}
 	}()
 
 	return nil
 
 }
-func (c *Client) del(k string) (er error) {
-	t, err := template.New("").Parse(c.DelSQL)
+func (c *Client) del(ctx context.Context, k string) (er error) {
+	query, args, err := c.delStatement(k)
 	if err != nil {
 		return err
 	}
 
-	var out bytes.Buffer
-	if err := t.Execute(&out, map[string]string{
-		"Key":  k,
-		"Time": time.Now().Format(`2006-01-02 15:04:05.000`),
-	}); err != nil {
-		return err
-	}
-
-	query := out.String()
-	log.Printf("D! query: %s", query)
-
-	db, err := sql.Open(c.DriverName, c.DataSourceName)
+	db, err := c.openDB(c.DataSourceName)
 	if err != nil {
 		return err
 	}
 
-	defer func() { er = multierr.Append(er, db.Close()) }()
-
-	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	ctx, cancel := c.queryTimeout(ctx)
 	defer cancel()
 
-	if _, err := db.ExecContext(ctx, query); err != nil {
+	if _, err := c.execContext(ctx, db, "del", k, query, args...); err != nil {
 		return err
 	}
 
 	return nil
 }
+
+// delStatement builds the statement of DelSQL, or the QueryBuilder, to delete the value of the key.
+func (c *Client) delStatement(k string) (query string, args []interface{}, err error) {
+	if c.QueryBuilder != nil {
+		query, args = c.QueryBuilder.BuildDel(c.backendKey(k))
+	} else if query, args, err = c.renderArgs("DelSQL", c.DelSQL, map[string]interface{}{
+		"Key":        c.backendKey(k),
+		"RawKey":     k,
+		"Time":       c.formatTime(),
+		"ServerTime": c.ServerTimeSQL,
+	}); err != nil {
+		return "", nil, err
+	}
+	c.logQuery(query)
+
+	return query, args, nil
+}
@@ -1,8 +1,14 @@
 package sqlc_test
 
 import (
+	"database/sql/driver"
+	"errors"
 	"fmt"
+	"github.com/bingoohuang/gokv"
+	"github.com/bingoohuang/gokv/pkg/codec"
 	"github.com/bingoohuang/gokv/pkg/sqlc"
+	"github.com/bingoohuang/gokv/pkg/storetest"
+	"github.com/bingoohuang/gokv/pkg/util"
 	_ "github.com/go-sql-driver/mysql"
 	sqle "github.com/src-d/go-mysql-server"
 	"github.com/src-d/go-mysql-server/auth"
@@ -12,10 +18,120 @@ import (
 	"github.com/stretchr/testify/assert"
 	"log"
 	"net"
+	"regexp"
+	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 )
 
 func TestSQL(t *testing.T) {
+	client := sqlc.NewClient(sqlc.Config{
+		DataSourceName: startTestServer(t),
+		SetSQL:         updateSetSQL,
+	})
+
+	k := "Key1"
+	assert.Nil(t, client.Set(k, "bingoohuang"))
+
+	found, v, _, err := client.Get(k, nil)
+	assert.Nil(t, err)
+	assert.True(t, found)
+	assert.Equal(t, "bingoohuang", v)
+
+	err = client.Del(k)
+	assert.Nil(t, err)
+
+	found, v, _, err = client.Get(k, nil)
+	assert.Nil(t, err)
+	assert.False(t, found)
+
+	client.Get("Key2", nil)
+	client.Get("Key3", nil)
+}
+
+func TestNoCache(t *testing.T) {
+	dsn := startTestServer(t)
+	client := sqlc.NewClient(sqlc.Config{DataSourceName: dsn, SetSQL: insertSetSQL})
+
+	assert.Nil(t, client.Set("Key5", "nocache", gokv.NoCache()))
+
+	found, v, option, err := client.Get("Key4", func(k string) (string, []gokv.OptionFn, error) {
+		return "generated", []gokv.OptionFn{gokv.NoCache()}, nil
+	})
+	assert.Nil(t, err)
+	assert.True(t, found)
+	assert.Equal(t, "generated", v)
+	assert.True(t, option.NoCache)
+
+	snapshot := client.CacheSnapshot()
+	assert.NotContains(t, snapshot, "Key5")
+	assert.NotContains(t, snapshot, "Key4")
+
+	other := sqlc.NewClient(sqlc.Config{DataSourceName: dsn})
+	found, v, _, err = other.Get("Key5", nil)
+	assert.Nil(t, err)
+	assert.True(t, found)
+	assert.Equal(t, "nocache", v)
+
+	found, v, _, err = other.Get("Key4", nil)
+	assert.Nil(t, err)
+	assert.True(t, found)
+	assert.Equal(t, "generated", v)
+
+	found, _, option, err = client.Get("Key5", nil)
+	assert.Nil(t, err)
+	assert.True(t, found)
+	assert.True(t, option.NoCache)
+	assert.NotContains(t, client.CacheSnapshot(), "Key5")
+}
+
+const (
+	insertSetSQL = "insert into kv(k, v, state, updated, created, o) " +
+		"values('{{.Key}}', '{{.Value}}', 1, '{{.Time}}', '{{.Time}}', '{{.Option}}')"
+	updateSetSQL = "update kv set v = '{{.Value}}', updated = '{{.Time}}' where k = '{{.Key}}' and state = 1"
+	optionGetSQL = "select v, o from kv where k = '{{.Key}}' and state = 1"
+)
+
+func TestCodecResolver(t *testing.T) {
+	dsn := startTestServer(t)
+	resolver := func(key string) codec.Codec {
+		if strings.HasPrefix(key, "old:") {
+			return codec.NewBase64(codec.Gob)
+		}
+
+		return nil
+	}
+
+	config := sqlc.Config{
+		DataSourceName: dsn,
+		SetSQL:         insertSetSQL,
+		GetSQL:         optionGetSQL,
+		Codec:          codec.JSON,
+		CodecResolver:  resolver,
+	}
+
+	client := sqlc.NewClient(config)
+	assert.Nil(t, client.Set("new:k", "v1", gokv.NoCache()))
+	assert.Nil(t, client.Set("old:k", "v2", gokv.NoCache()))
+
+	other := sqlc.NewClient(config)
+	for k, v := range map[string]string{"new:k": "v1", "old:k": "v2"} {
+		found, value, option, err := other.Get(k, nil)
+		assert.Nil(t, err)
+		assert.True(t, found)
+		assert.Equal(t, v, value)
+		assert.True(t, option.NoCache)
+	}
+
+	// the option of new:k is JSON, which can not be decoded as gob.
+	config.CodecResolver = func(string) codec.Codec { return codec.NewBase64(codec.Gob) }
+	_, _, _, err := sqlc.NewClient(config).Get("new:k", nil)
+	assert.NotNil(t, err)
+}
+
+// startTestServer starts a go-mysql-server with the test database and returns its DSN.
+func startTestServer(t *testing.T) string {
 	driver := sqle.NewDefault()
 	db, err := createTestDatabase("testdb")
 	assert.Nil(t, err)
@@ -40,28 +156,9 @@ func TestSQL(t *testing.T) {
 		}
 	}()
 
-	client := sqlc.NewClient(sqlc.Config{
-		DataSourceName: fmt.Sprintf("user:pass@tcp(localhost:%d)/testdb", port),
-		SetSQL:         "update kv set v = '{{.Value}}', updated = '{{.Time}}' where k = '{{.Key}}' and state = 1",
-	})
-
-	k := "Key1"
-	assert.Nil(t, client.Set(k, "bingoohuang"))
-
-	found, v, err := client.Get(k)
-	assert.Nil(t, err)
-	assert.True(t, found)
-	assert.Equal(t, "bingoohuang", v)
-
-	err = client.Del(k)
-	assert.Nil(t, err)
-
-	found, v, err = client.Get(k)
-	assert.Nil(t, err)
-	assert.False(t, found)
+	t.Cleanup(func() { _ = s.Close() })
 
-	client.Get("Key2")
-	client.Get("Key3")
+	return fmt.Sprintf("user:pass@tcp(localhost:%d)/testdb", port)
 }
 
 func createTestDatabase(dbName string) (*memory.Database, error) {
@@ -73,7 +170,9 @@ func createTestDatabase(dbName string) (*memory.Database, error) {
 		{Name: "v", Type: sql.Text, Nullable: false, Source: tableName},
 		{Name: "state", Type: sql.Int8, Nullable: false, Source: tableName},
 		{Name: "updated", Type: sql.VarChar(30), Nullable: true, Source: tableName},
-		{Name: "created", Type: sql.VarChar(30), Nullable: true, Source: tableName},
+		{Name: "created", Type: sql.Timestamp, Nullable: true, Source: tableName},
+		{Name: "o", Type: sql.Text, Nullable: true, Source: tableName},
+		{Name: "ver", Type: sql.Int64, Nullable: true, Source: tableName},
 	})
 
 	fmt.Println(table.String())
@@ -82,9 +181,9 @@ func createTestDatabase(dbName string) (*memory.Database, error) {
 	ctx := sql.NewEmptyContext()
 
 	rows := []sql.Row{
-		sql.NewRow("Key1", `"value1"`, 1, nil, nil),
-		sql.NewRow("Key2", `"value2"`, 1, nil, nil),
-		sql.NewRow("Key3", `"value3"`, 1, nil, nil),
+		sql.NewRow("Key1", `"value1"`, 1, nil, nil, nil, int64(1)),
+		sql.NewRow("Key2", `"value2"`, 1, nil, nil, nil, int64(1)),
+		sql.NewRow("Key3", `"value3"`, 1, nil, nil, nil, int64(1)),
 	}
 
 	for _, row := range rows {
@@ -93,5 +192,656 @@ func createTestDatabase(dbName string) (*memory.Database, error) {
 		}
 	}
 
+	// kvo stores the option fields in dedicated columns
+	db.AddTable("kvo", memory.NewTable("kvo", sql.Schema{
+		{Name: "k", Type: sql.VarChar(10), Nullable: false, Source: "kvo", PrimaryKey: true},
+		{Name: "v", Type: sql.Text, Nullable: false, Source: "kvo"},
+		{Name: "ttl", Type: sql.Int64, Nullable: true, Source: "kvo"},
+		{Name: "no_cache", Type: sql.VarChar(5), Nullable: true, Source: "kvo"},
+	}))
+
 	return db, nil
 }
+
+func TestAsStore(t *testing.T) {
+	client := sqlc.NewClient(sqlc.Config{DataSourceName: startTestServer(t), SetSQL: insertSetSQL})
+	storetest.TestStore(t, client.AsStore())
+}
+
+func TestRestartRefresh(t *testing.T) {
+	dsn := startTestServer(t)
+	client := sqlc.NewClient(sqlc.Config{DataSourceName: dsn, RefreshInterval: 10 * time.Millisecond})
+	client.StartRefresh() // already running, no-op
+
+	assert.Eventually(t, func() bool {
+		_, ok := client.CacheSnapshot()["Key1"]
+		return ok
+	}, time.Second, 10*time.Millisecond)
+
+	assert.Nil(t, client.Close())
+	client.StopRefresh() // already stopped, no-op
+
+	writer := sqlc.NewClient(sqlc.Config{DataSourceName: dsn, SetSQL: insertSetSQL})
+	defer writer.Close()
+	assert.Nil(t, writer.Set("Key4", "v4"))
+
+	time.Sleep(50 * time.Millisecond)
+	assert.NotContains(t, client.CacheSnapshot(), "Key4")
+
+	client.StartRefresh()
+	defer client.Close()
+
+	assert.Eventually(t, func() bool {
+		_, ok := client.CacheSnapshot()["Key4"]
+		return ok
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestTTLWithFakeClock(t *testing.T) {
+	dsn := startTestServer(t)
+	now := time.Date(2020, 10, 1, 0, 0, 0, 0, time.UTC)
+	client := sqlc.NewClient(sqlc.Config{
+		DataSourceName: dsn,
+		SetSQL:         insertSetSQL,
+		Now:            func() time.Time { return now },
+	})
+	defer client.Close()
+
+	assert.Nil(t, client.Set("Key4", "v1", gokv.TTL(time.Minute)))
+	assert.Equal(t, now, client.CacheSnapshot()["Key4"].UpdateTime)
+
+	writer := sqlc.NewClient(sqlc.Config{DataSourceName: dsn, SetSQL: updateSetSQL})
+	defer writer.Close()
+	assert.Nil(t, writer.Set("Key4", "v2"))
+
+	now = now.Add(59 * time.Second)
+	_, v, _, err := client.Get("Key4", nil)
+	assert.Nil(t, err)
+	assert.Equal(t, "v1", v)
+
+	now = now.Add(time.Second)
+	_, v, _, err = client.Get("Key4", nil)
+	assert.Nil(t, err)
+	assert.Equal(t, "v2", v)
+	assert.Equal(t, now, client.CacheSnapshot()["Key4"].UpdateTime)
+}
+
+func TestRowsReturnedToPool(t *testing.T) {
+	d, driverName := newFakeDriver()
+	d.Query = func(query string, _ []driver.NamedValue) ([]string, [][]driver.Value, error) {
+		switch query {
+		case "select k, v from kv":
+			return []string{"k", "v"}, [][]driver.Value{{"k1", "v1"}, {"k2", "v2"}}, nil
+		case "select v from kv where k = 'dup'":
+			return []string{"v"}, [][]driver.Value{{"v1"}, {"v2"}, {"v3"}}, nil
+		case "select v from kv where k = 'bad'":
+			return nil, nil, errors.New("bad query")
+		default:
+			return []string{"v"}, [][]driver.Value{{"v1"}}, nil
+		}
+	}
+
+	client := sqlc.NewClient(sqlc.Config{
+		DriverName: driverName,
+		AllSQL:     "select k, v from kv",
+		GetSQL:     "select v from kv where k = '{{.Key}}'",
+	})
+	defer client.Close()
+
+	assertReturned := func() {
+		conns, rows := d.Opened()
+		assert.Equal(t, 1, conns, "connections should be returned to the pool")
+		assert.Equal(t, 0, rows, "rows should be closed")
+	}
+
+	_, err := client.All()
+	assert.Nil(t, err)
+	assertReturned()
+
+	_, _, _, err = client.Get("k1", nil)
+	assert.Nil(t, err)
+	assertReturned()
+
+	_, _, _, err = client.Get("dup", nil)
+	assert.True(t, errors.Is(err, sqlc.ErrTooManyValues))
+	assertReturned()
+
+	_, _, _, err = client.Get("bad", nil)
+	assert.NotNil(t, err)
+	assertReturned()
+
+	assert.Nil(t, client.Set("k3", "v3"))
+	assertReturned()
+
+	assert.Nil(t, client.Del("k3"))
+	assertReturned()
+}
+
+func TestKeysWithKeyColumn(t *testing.T) {
+	dsn := startTestServer(t)
+
+	client := sqlc.NewClient(sqlc.Config{DataSourceName: dsn})
+	defer client.Close()
+
+	keys, err := client.Keys()
+	assert.Nil(t, err)
+	assert.ElementsMatch(t, []string{"Key1", "Key2", "Key3"}, keys)
+
+	client = sqlc.NewClient(sqlc.Config{
+		DataSourceName: dsn,
+		KeysSQL:        "select updated, k from kv where state = 1",
+		KeyColumn:      "k",
+	})
+	defer client.Close()
+
+	keys, err = client.Keys()
+	assert.Nil(t, err)
+	assert.ElementsMatch(t, []string{"Key1", "Key2", "Key3"}, keys)
+}
+
+func TestServerTime(t *testing.T) {
+	dsn := startTestServer(t)
+	clientTime := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+	client := sqlc.NewClient(sqlc.Config{
+		DataSourceName: dsn,
+		SetSQL: "insert into kv(k, v, state, updated, created) " +
+			"values('{{.Key}}', '{{.Value}}', 1, '{{.Time}}', {{.ServerTime}})",
+		Now: func() time.Time { return clientTime },
+	})
+	defer client.Close()
+
+	assert.Nil(t, client.Set("Key4", "v4"))
+
+	reader := sqlc.NewClient(sqlc.Config{
+		DataSourceName: dsn,
+		GetSQL:         "select created from kv where k = '{{.Key}}' and state = 1",
+	})
+	defer reader.Close()
+
+	_, created, _, err := reader.Get("Key4", nil)
+	assert.Nil(t, err)
+	assert.False(t, strings.HasPrefix(created, "2000-01-01"), created)
+	assert.True(t, strings.HasPrefix(created, time.Now().UTC().Format("2006-01-02")), created)
+
+	assert.Equal(t, "CURRENT_TIMESTAMP", sqlc.ServerTimeSQL("postgres"))
+}
+
+// countingCodec counts the Unmarshal calls of the inner codec.
+type countingCodec struct {
+	codec.Codec
+	unmarshals int32
+}
+
+func (c *countingCodec) Unmarshal(data []byte, v interface{}) error {
+	atomic.AddInt32(&c.unmarshals, 1)
+	return c.Codec.Unmarshal(data, v)
+}
+
+func TestLazyOption(t *testing.T) {
+	dsn := startTestServer(t)
+	writer := sqlc.NewClient(sqlc.Config{DataSourceName: dsn, SetSQL: insertSetSQL})
+	defer writer.Close()
+	assert.Nil(t, writer.Set("Key4", "v4", gokv.TTL(time.Hour)))
+
+	counting := &countingCodec{Codec: codec.JSON}
+	client := sqlc.NewClient(sqlc.Config{
+		DataSourceName: dsn,
+		GetSQL:         optionGetSQL,
+		Codec:          counting,
+		LazyOption:     true,
+	})
+	defer client.Close()
+
+	found, v, option, err := client.Get("Key4", nil)
+	assert.Nil(t, err)
+	assert.True(t, found)
+	assert.Equal(t, "v4", v)
+	assert.Equal(t, gokv.Option{}, option)
+	assert.Equal(t, int32(0), atomic.LoadInt32(&counting.unmarshals))
+
+	for i := 0; i < 2; i++ {
+		option, found, err = client.OptionOf("Key4")
+		assert.Nil(t, err)
+		assert.True(t, found)
+		assert.Equal(t, time.Hour, option.TTL)
+		assert.Equal(t, int32(1), atomic.LoadInt32(&counting.unmarshals))
+	}
+
+	_, found, err = client.OptionOf("Key9")
+	assert.Nil(t, err)
+	assert.False(t, found)
+}
+
+func TestTimeLocation(t *testing.T) {
+	now := time.Date(2020, 10, 1, 12, 0, 0, 0, time.FixedZone("UTC-5", -5*3600))
+	config := sqlc.Config{
+		SetSQL: "insert into kv(k, v, updated) values('{{.Key}}', '{{.Value}}', '{{.Time}}')",
+		DelSQL: "update kv set state = 0, updated = '{{.Time}}' where k = '{{.Key}}'",
+		Now:    func() time.Time { return now },
+	}
+
+	for location, expected := range map[*time.Location]string{
+		nil:                              "2020-10-01 17:00:00.000",
+		time.FixedZone("UTC+8", 8*3600):  "2020-10-02 01:00:00.000",
+		time.FixedZone("UTC-5", -5*3600): "2020-10-01 12:00:00.000",
+	} {
+		d, driverName := newFakeDriver()
+		config.DriverName = driverName
+		config.TimeLocation = location
+		client := sqlc.NewClient(config)
+
+		assert.Nil(t, client.Set("k", "v"))
+		assert.Nil(t, client.Del("k"))
+		assert.Nil(t, client.Close())
+
+		assert.Equal(t, []string{
+			"insert into kv(k, v, updated) values('k', 'v', '" + expected + "')",
+			"update kv set state = 0, updated = '" + expected + "' where k = 'k'",
+		}, d.Statements)
+	}
+}
+
+func TestRequireOption(t *testing.T) {
+	dsn := startTestServer(t)
+	writer := sqlc.NewClient(sqlc.Config{DataSourceName: dsn, SetSQL: insertSetSQL})
+	defer writer.Close()
+	assert.Nil(t, writer.Set("Key4", "v4", gokv.TTL(time.Hour)))
+
+	malformed := sqlc.NewClient(sqlc.Config{
+		DataSourceName: dsn,
+		SetSQL:         strings.Replace(insertSetSQL, "'{{.Option}}'", "'malformed'", 1),
+	})
+	defer malformed.Close()
+	assert.Nil(t, malformed.Set("Key5", "v5"))
+
+	for _, required := range []bool{false, true} {
+		client := sqlc.NewClient(sqlc.Config{DataSourceName: dsn, GetSQL: optionGetSQL, RequireOption: required})
+		defer client.Close()
+
+		found, _, option, err := client.Get("Key4", nil)
+		assert.Nil(t, err)
+		assert.True(t, found)
+		assert.Equal(t, time.Hour, option.TTL)
+
+		found, v, option, err := client.Get("Key1", nil)
+		if required {
+			assert.True(t, errors.Is(err, sqlc.ErrOptionRequired))
+			assert.False(t, found)
+		} else {
+			assert.Nil(t, err)
+			assert.Equal(t, `"value1"`, v)
+			assert.Equal(t, gokv.Option{}, option)
+		}
+
+		_, _, _, err = client.Get("Key5", nil)
+		assert.NotNil(t, err)
+
+		found, _, _, err = client.Get("Key9", nil)
+		assert.Nil(t, err)
+		assert.False(t, found)
+	}
+}
+
+func TestLoadCacheSnapshot(t *testing.T) {
+	dsn := startTestServer(t)
+
+	client := sqlc.NewClient(sqlc.Config{DataSourceName: dsn})
+	_, err := client.All()
+	assert.Nil(t, err)
+	snapshot := client.CacheSnapshot()
+	assert.Nil(t, client.Close())
+
+	d, driverName := newFakeDriver()
+	restarted := sqlc.NewClient(sqlc.Config{DriverName: driverName})
+	defer restarted.Close()
+
+	assert.True(t, errors.Is(restarted.LoadCacheSnapshot(map[string]sqlc.CacheValue{"": {}}), util.ErrEmptyKey))
+	assert.Nil(t, restarted.LoadCacheSnapshot(snapshot))
+	assert.Equal(t, snapshot, restarted.CacheSnapshot())
+
+	for _, k := range []string{"Key1", "Key2", "Key3"} {
+		found, v, _, err := restarted.Get(k, nil)
+		assert.Nil(t, err)
+		assert.True(t, found)
+		assert.Equal(t, snapshot[k].Value, v)
+	}
+	assert.Equal(t, 0, d.StatementCount())
+}
+
+func TestStrictColumns(t *testing.T) {
+	dsn := startTestServer(t)
+	writer := sqlc.NewClient(sqlc.Config{DataSourceName: dsn, SetSQL: insertSetSQL})
+	defer writer.Close()
+	assert.Nil(t, writer.Set("Key4", "v4", gokv.TTL(time.Hour)))
+
+	for _, strict := range []bool{false, true} {
+		for getSQL, columns := range map[string]int{
+			"select v from kv where k = '{{.Key}}' and state = 1": 1,
+			optionGetSQL: 2,
+			"select v, o, updated from kv where k = '{{.Key}}' and state = 1": 3,
+		} {
+			client := sqlc.NewClient(sqlc.Config{DataSourceName: dsn, GetSQL: getSQL, StrictColumns: strict})
+
+			found, v, option, err := client.Get("Key4", nil)
+			if strict && columns != 2 {
+				assert.True(t, errors.Is(err, sqlc.ErrColumnCount))
+				assert.False(t, found)
+			} else {
+				assert.Nil(t, err)
+				assert.True(t, found)
+				assert.Equal(t, "v4", v)
+				if columns > 1 {
+					assert.Equal(t, time.Hour, option.TTL)
+				}
+			}
+
+			assert.Nil(t, client.Close())
+		}
+	}
+}
+
+const setIfVersionSQL = "update kv set v = {{arg .Value}}, ver = ver + 1 " +
+	"where k = {{arg .Key}} and ver = {{arg .Version}}"
+
+func TestVersioned(t *testing.T) {
+	dsn := startTestServer(t)
+	client := sqlc.NewClient(sqlc.Config{
+		DataSourceName:  dsn + "?interpolateParams=true",
+		VersionGetSQL:   "select v, ver from kv where k = '{{.Key}}' and state = 1",
+		SetIfVersionSQL: setIfVersionSQL,
+	})
+	defer client.Close()
+
+	s := client.AsStore()
+	v, version, found, err := gokv.GetWithVersion(s, "Key1")
+	assert.Nil(t, err)
+	assert.True(t, found)
+	assert.Equal(t, `"value1"`, v)
+	assert.Equal(t, "1", version)
+
+	// the test server does not report the affected rows, which is checked with the fake driver below
+	_, err = gokv.SetIfVersion(s, "Key1", "v2", version)
+	assert.Nil(t, err)
+
+	v, version, found, err = client.GetWithVersion("Key1")
+	assert.Nil(t, err)
+	assert.True(t, found)
+	assert.Equal(t, "v2", v)
+	assert.Equal(t, "2", version)
+
+	_, _, found, err = client.GetWithVersion("Key9")
+	assert.Nil(t, err)
+	assert.False(t, found)
+
+	plain := sqlc.NewClient(sqlc.Config{DataSourceName: dsn})
+	defer plain.Close()
+
+	_, _, _, err = gokv.GetWithVersion(plain.AsStore(), "Key1")
+	assert.True(t, errors.Is(err, gokv.ErrNotSupported))
+	_, err = gokv.SetIfVersion(plain.AsStore(), "Key1", "v3", "2")
+	assert.True(t, errors.Is(err, gokv.ErrNotSupported))
+}
+
+func TestSetIfVersionConflict(t *testing.T) {
+	d, driverName := newFakeDriver()
+	d.Exec = func(_ string, args []driver.NamedValue) (driver.Result, error) {
+		if args[len(args)-1].Value == "2" {
+			return driver.RowsAffected(1), nil
+		}
+		return driver.RowsAffected(0), nil
+	}
+
+	client := sqlc.NewClient(sqlc.Config{DriverName: driverName, SetIfVersionSQL: setIfVersionSQL})
+	defer client.Close()
+
+	ok, err := client.SetIfVersion("k", "v", "2")
+	assert.Nil(t, err)
+	assert.True(t, ok)
+
+	ok, err = client.SetIfVersion("k", "v", "1")
+	assert.Nil(t, err)
+	assert.False(t, ok)
+
+	ok, err = client.SetIfVersion("k", "v", "1 or 1 = 1")
+	assert.Nil(t, err)
+	assert.False(t, ok, "the bound version is never part of the statement")
+
+	// the version interpolated raw is required to be an integer
+	raw := sqlc.NewClient(sqlc.Config{
+		DriverName:      driverName,
+		SetIfVersionSQL: "update kv set v = {{arg .Value}} where k = {{arg .Key}} and ver = {{ .Version }}",
+	})
+	defer raw.Close()
+
+	_, err = raw.SetIfVersion("k", "v", "1 or 1 = 1")
+	assert.True(t, errors.Is(err, sqlc.ErrInvalidVersion))
+	ok, err = raw.SetIfVersion("k", "v", "2")
+	assert.Nil(t, err)
+	assert.False(t, ok)
+}
+
+func TestInvalidate(t *testing.T) {
+	d, driverName := newFakeDriver()
+	d.Query = func(string, []driver.NamedValue) ([]string, [][]driver.Value, error) {
+		return []string{"v"}, [][]driver.Value{{"reloaded"}}, nil
+	}
+	client := sqlc.NewClient(sqlc.Config{DriverName: driverName})
+	defer client.Close()
+
+	assert.Nil(t, client.Set("k1", "v1"))
+	assert.Nil(t, client.Set("k2", "v2"))
+	assert.Nil(t, client.Set("k3", "v3"))
+	assert.Equal(t, 3, d.StatementCount())
+
+	found, v, _, err := client.Get("k1", nil)
+	assert.Nil(t, err)
+	assert.True(t, found)
+	assert.Equal(t, "v1", v)
+	assert.Equal(t, 3, d.StatementCount(), "served from the cache")
+
+	client.Invalidate("k1", "k9")
+	assert.NotContains(t, client.CacheSnapshot(), "k1")
+	assert.Contains(t, client.CacheSnapshot(), "k2")
+
+	found, v, _, err = client.Get("k1", nil)
+	assert.Nil(t, err)
+	assert.True(t, found)
+	assert.Equal(t, "reloaded", v)
+	assert.Equal(t, 4, d.StatementCount(), "reloaded from the database")
+
+	client.InvalidateAll()
+	assert.Empty(t, client.CacheSnapshot())
+
+	_, _, _, err = client.Get("k2", nil)
+	assert.Nil(t, err)
+	assert.Equal(t, 5, d.StatementCount())
+}
+
+func TestMultiValuePolicy(t *testing.T) {
+	d, driverName := newFakeDriver()
+	d.Query = func(string, []driver.NamedValue) ([]string, [][]driver.Value, error) {
+		return []string{"v"}, [][]driver.Value{{"v1"}, {"v2"}, {"v3"}}, nil
+	}
+
+	for policy, expected := range map[sqlc.MultiValuePolicy]string{
+		sqlc.MultiValueError: "",
+		sqlc.MultiValueFirst: "v1",
+		sqlc.MultiValueLast:  "v3",
+	} {
+		client := sqlc.NewClient(sqlc.Config{DriverName: driverName, MultiValuePolicy: policy})
+
+		found, v, _, err := client.Get("dup", nil)
+		if policy == sqlc.MultiValueError {
+			assert.True(t, errors.Is(err, sqlc.ErrTooManyValues))
+			assert.False(t, found)
+		} else {
+			assert.Nil(t, err)
+			assert.True(t, found)
+		}
+		assert.Equal(t, expected, v)
+
+		assert.Nil(t, client.Close())
+		conns, rows := d.Opened()
+		assert.Equal(t, 0, conns)
+		assert.Equal(t, 0, rows)
+	}
+}
+
+func TestValidation(t *testing.T) {
+	dsn := startTestServer(t)
+
+	client := sqlc.NewClient(sqlc.Config{
+		DataSourceName:  dsn,
+		SetSQL:          insertSetSQL,
+		SetIfVersionSQL: setIfVersionSQL,
+		Validation: &util.ValidationConfig{
+			MaxKeyLen: 10, MaxValueLen: 20, KeyPattern: regexp.MustCompile(`^\w+$`),
+		},
+	})
+	defer client.Close()
+
+	err := client.Set("bad key!", "")
+	assert.True(t, errors.Is(err, util.ErrKeyCharset))
+	assert.True(t, errors.Is(err, util.ErrEmptyValue))
+
+	assert.Nil(t, client.Set("Key4", "value4"))
+
+	// every write path is validated
+	err = client.SetMany(map[string]string{"Key5": "value5", "bad key!": "value"})
+	assert.True(t, errors.Is(err, util.ErrKeyCharset))
+	assert.True(t, errors.Is(client.SetBytes("Key5", nil), util.ErrEmptyValue))
+	_, err = client.SetIfVersion("bad key!", "value", "1")
+	assert.True(t, errors.Is(err, util.ErrKeyCharset))
+
+	found, _, _, err := client.Get("Key5", nil)
+	assert.Nil(t, err)
+	assert.False(t, found, "nothing is written by the invalid writes")
+}
+
+func TestGetWithSource(t *testing.T) {
+	client := sqlc.NewClient(sqlc.Config{DataSourceName: startTestServer(t)})
+	defer client.Close()
+
+	found, v, _, fromCache, err := client.GetWithSource("Key1")
+	assert.Nil(t, err)
+	assert.True(t, found)
+	assert.Equal(t, `"value1"`, v)
+	assert.False(t, fromCache)
+
+	found, v, _, fromCache, err = client.GetWithSource("Key1")
+	assert.Nil(t, err)
+	assert.True(t, found)
+	assert.Equal(t, `"value1"`, v)
+	assert.True(t, fromCache)
+
+	found, _, _, fromCache, err = client.GetWithSource("Key9")
+	assert.Nil(t, err)
+	assert.False(t, found)
+	assert.False(t, fromCache)
+}
+
+func TestGetFresh(t *testing.T) {
+	dsn := startTestServer(t)
+	client := sqlc.NewClient(sqlc.Config{DataSourceName: dsn, SetSQL: insertSetSQL})
+	defer client.Close()
+	assert.Nil(t, client.Set("Key4", "v1"))
+
+	writer := sqlc.NewClient(sqlc.Config{DataSourceName: dsn, SetSQL: updateSetSQL})
+	defer writer.Close()
+	assert.Nil(t, writer.Set("Key4", "v2"))
+
+	_, v, _, err := client.Get("Key4", nil)
+	assert.Nil(t, err)
+	assert.Equal(t, "v1", v, "the stale value is cached")
+
+	found, v, _, err := client.GetFresh("Key4", nil)
+	assert.Nil(t, err)
+	assert.True(t, found)
+	assert.Equal(t, "v2", v)
+	assert.Equal(t, "v2", client.CacheSnapshot()["Key4"].Value, "the fresh value is cached")
+
+	assert.Nil(t, writer.Del("Key4"))
+	found, _, _, err = client.GetFresh("Key4", nil)
+	assert.Nil(t, err)
+	assert.False(t, found)
+	assert.NotContains(t, client.CacheSnapshot(), "Key4")
+}
+
+func TestMigrationCodec(t *testing.T) {
+	gobOption, err := codec.Gob.Marshal(gokv.Option{TTL: time.Minute})
+	assert.Nil(t, err)
+
+	d, driverName := newFakeDriver()
+	d.Query = func(query string, _ []driver.NamedValue) ([]string, [][]driver.Value, error) {
+		if strings.Contains(query, "'legacy'") {
+			return []string{"v", "o"}, [][]driver.Value{{"v1", gobOption}}, nil
+		}
+		return []string{"v", "o"}, [][]driver.Value{{"v2", []byte(`{"TTL":3600000000000}`)}}, nil
+	}
+
+	client := sqlc.NewClient(sqlc.Config{
+		DriverName:     driverName,
+		GetSQL:         optionGetSQL,
+		SetSQL:         insertSetSQL,
+		MigrationCodec: true,
+	})
+	defer client.Close()
+
+	for k, ttl := range map[string]time.Duration{"legacy": time.Minute, "new": time.Hour} {
+		found, _, option, err := client.Get(k, nil)
+		assert.Nil(t, err)
+		assert.True(t, found)
+		assert.Equal(t, ttl, option.TTL)
+	}
+
+	assert.Nil(t, client.Set("k", "v", gokv.TTL(time.Second)))
+	assert.Contains(t, d.Statements[len(d.Statements)-1], `'{"TTL":1000000000,"NoCache":false}'`)
+}
+
+func TestEach(t *testing.T) {
+	client := sqlc.NewClient(sqlc.Config{DataSourceName: startTestServer(t)})
+	defer client.Close()
+
+	errStop := errors.New("stop")
+	var visited []string
+	err := client.Each(func(k, v string) error {
+		visited = append(visited, k)
+		return errStop
+	})
+	assert.Equal(t, errStop, err)
+	assert.Len(t, visited, 1)
+	assert.Empty(t, client.CacheSnapshot())
+
+	kvs := make(map[string]string)
+	assert.Nil(t, client.Each(func(k, v string) error { kvs[k] = v; return nil }))
+	assert.Equal(t, map[string]string{"Key1": `"value1"`, "Key2": `"value2"`, "Key3": `"value3"`}, kvs)
+}
+
+func TestCacheableFn(t *testing.T) {
+	client := sqlc.NewClient(sqlc.Config{
+		DataSourceName: startTestServer(t),
+		SetSQL:         insertSetSQL,
+		CacheableFn:    func(key string) bool { return strings.HasPrefix(key, "hot:") },
+	})
+	defer client.Close()
+
+	assert.Nil(t, client.Set("hot:k1", "v1"))
+	assert.Nil(t, client.Set("cold:k2", "v2"))
+
+	for _, k := range []string{"hot:k1", "cold:k2", "Key1"} {
+		found, _, _, err := client.Get(k, nil)
+		assert.Nil(t, err)
+		assert.True(t, found)
+	}
+
+	_, err := client.All()
+	assert.Nil(t, err)
+
+	snapshot := client.CacheSnapshot()
+	assert.Len(t, snapshot, 1)
+	assert.Contains(t, snapshot, "hot:k1")
+
+	_, _, _, fromCache, err := client.GetWithSource("cold:k2")
+	assert.Nil(t, err)
+	assert.False(t, fromCache)
+}
@@ -0,0 +1,28 @@
+package sqlc
+
+import "sync/atomic"
+
+// Stats are the counters of the database usage of the client.
+type Stats struct {
+	// Opens is the number of the databases opened by sql.Open.
+	Opens uint64
+	// Closes is the number of the databases closed by Close.
+	Closes uint64
+	// Queries is the number of the queries and the statements executed, including the retries.
+	Queries uint64
+	// RowsScanned is the number of the rows scanned from the query results.
+	RowsScanned uint64
+	// Prepares is the number of the statements prepared in the PrepareStatements mode.
+	Prepares uint64
+}
+
+// Stats returns the counters of the database usage since the client is created.
+func (c *Client) Stats() Stats {
+	return Stats{
+		Opens:       atomic.LoadUint64(&c.metrics.opens),
+		Closes:      atomic.LoadUint64(&c.metrics.closes),
+		Queries:     atomic.LoadUint64(&c.metrics.queries),
+		RowsScanned: atomic.LoadUint64(&c.metrics.rowsScanned),
+		Prepares:    atomic.LoadUint64(&c.metrics.prepares),
+	}
+}
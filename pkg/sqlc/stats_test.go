@@ -0,0 +1,44 @@
+package sqlc_test
+
+import (
+	"database/sql/driver"
+	"github.com/bingoohuang/gokv/pkg/sqlc"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func TestStats(t *testing.T) {
+	d, driverName := newFakeDriver()
+	d.Query = func(query string, _ []driver.NamedValue) ([]string, [][]driver.Value, error) {
+		if strings.Contains(query, "where k =") {
+			return []string{"v"}, [][]driver.Value{{"v1"}}, nil
+		}
+		return []string{"k", "v"}, [][]driver.Value{{"k1", "v1"}, {"k2", "v2"}}, nil
+	}
+
+	client := sqlc.NewClient(sqlc.Config{DriverName: driverName})
+	defer client.Close()
+
+	assert.Equal(t, sqlc.Stats{}, client.Stats())
+
+	_, _, _, err := client.Get("k1", nil)
+	assert.Nil(t, err)
+	assert.Equal(t, sqlc.Stats{Opens: 1, Queries: 1, RowsScanned: 1}, client.Stats())
+
+	_, _, _, err = client.Get("k1", nil)
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(1), client.Stats().Queries, "the cache hit queries nothing")
+
+	_, err = client.All()
+	assert.Nil(t, err)
+	assert.Equal(t, sqlc.Stats{Opens: 1, Queries: 2, RowsScanned: 3}, client.Stats(),
+		"the database is shared by the operations")
+
+	assert.Nil(t, client.Set("k3", "v3"))
+	stats := client.Stats()
+	assert.Equal(t, uint64(d.StatementCount()), stats.Queries, "one per query or statement")
+
+	assert.Nil(t, client.Close())
+	assert.Equal(t, uint64(1), client.Stats().Closes)
+}
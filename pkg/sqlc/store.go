@@ -0,0 +1,36 @@
+package sqlc
+
+import "github.com/bingoohuang/gokv"
+
+// AsStore adapts the client to the simpler gokv.Store interface,
+// so that it can be composed with the generic store decorators.
+func (c *Client) AsStore() gokv.Store { return &store{c: c} }
+
+type store struct {
+	c *Client
+}
+
+func (s *store) All() (map[string]string, error) { return s.c.All() }
+func (s *store) Set(k, v string) error           { return s.c.Set(k, v) }
+func (s *store) Del(k string) error              { return s.c.Del(k) }
+
+func (s *store) GetWithVersion(k string) (v, version string, found bool, err error) {
+	return s.c.GetWithVersion(k)
+}
+
+func (s *store) SetIfVersion(k, v, expectedVersion string) (ok bool, err error) {
+	return s.c.SetIfVersion(k, v, expectedVersion)
+}
+
+func (s *store) Get(k string) (string, error) {
+	found, v, _, err := s.c.Get(k, nil)
+	if err != nil {
+		return "", err
+	}
+
+	if !found {
+		return "", gokv.ErrKeyNotFound
+	}
+
+	return v, nil
+}
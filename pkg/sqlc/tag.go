@@ -0,0 +1,65 @@
+package sqlc
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/bingoohuang/gokv/pkg/codec"
+	"time"
+)
+
+// OptionTag returns the tag of the key, ok is false when the key or the tag is missing.
+// In the LazyOption mode, only the tags are decoded from the cached raw option when it is encoded by codec.JSON,
+// otherwise the whole option is decoded.
+func (c *Client) OptionTag(k, tagKey string) (v string, ok bool, er error) {
+	c.cacheLock.Lock()
+	if cv, cached := c.validCache(k); cached {
+		c.cacheLock.Unlock()
+
+		if cv.rawOption == nil {
+			v, ok = cv.Option.Tags[tagKey]
+			return v, ok, nil
+		}
+
+		return c.decodeTag(k, cv.rawOption, tagKey)
+	}
+	c.cacheLock.Unlock()
+
+	found, _, rawOption, err := c.get(background(), k)
+	if err != nil || !found {
+		return "", false, err
+	}
+
+	return c.decodeTag(k, rawOption, tagKey)
+}
+
+// decodeTag decodes the tag from the raw option.
+func (c *Client) decodeTag(k string, rawOption []byte, tagKey string) (v string, ok bool, err error) {
+	if len(rawOption) == 0 {
+		return "", false, nil
+	}
+
+	if _, isJSON := c.codecOf(k).(codec.JSONCodec); !isJSON {
+		option, err := c.decodeOption(k, rawOption)
+		v, ok = option.Tags[tagKey]
+		return v, ok, err
+	}
+
+	defer c.metrics.observePhase("codec", time.Now())
+
+	// the other fields of the option and the other tags are skipped without decoding
+	var option struct{ Tags map[string]json.RawMessage }
+	if err := json.Unmarshal(rawOption, &option); err != nil {
+		return "", false, fmt.Errorf("key:%s, error:%w", k, codec.WrapError("unmarshal", &option, err))
+	}
+
+	raw, ok := option.Tags[tagKey]
+	if !ok {
+		return "", false, nil
+	}
+
+	if err := json.Unmarshal(raw, &v); err != nil {
+		return "", false, fmt.Errorf("key:%s, error:%w", k, codec.WrapError("unmarshal", &v, err))
+	}
+
+	return v, true, nil
+}
@@ -0,0 +1,49 @@
+package sqlc_test
+
+import (
+	"github.com/bingoohuang/gokv"
+	"github.com/bingoohuang/gokv/pkg/codec"
+	"github.com/bingoohuang/gokv/pkg/sqlc"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestOptionTag(t *testing.T) {
+	dsn := startTestServer(t)
+	writer := sqlc.NewClient(sqlc.Config{DataSourceName: dsn, SetSQL: insertSetSQL})
+	defer writer.Close()
+	assert.Nil(t, writer.Set("Key4", "v4", gokv.Tag("owner", "bingoo"), gokv.Tag("env", "prod"), gokv.Tag("tier", "gold")))
+
+	lazy := sqlc.NewClient(sqlc.Config{DataSourceName: dsn, GetSQL: optionGetSQL, LazyOption: true})
+	defer lazy.Close()
+
+	eager := sqlc.NewClient(sqlc.Config{DataSourceName: dsn, GetSQL: optionGetSQL})
+	defer eager.Close()
+
+	// the option is decoded as a whole by the other codecs
+	wrapped := sqlc.NewClient(sqlc.Config{DataSourceName: dsn, GetSQL: optionGetSQL, LazyOption: true,
+		Codec: &countingCodec{Codec: codec.JSON}})
+	defer wrapped.Close()
+
+	for _, client := range []*sqlc.Client{lazy, eager, wrapped} {
+		_, _, _, err := client.Get("Key4", nil)
+		assert.Nil(t, err)
+
+		v, ok, err := client.OptionTag("Key4", "env")
+		assert.Nil(t, err)
+		assert.True(t, ok)
+		assert.Equal(t, "prod", v)
+
+		_, ok, err = client.OptionTag("Key4", "missing")
+		assert.Nil(t, err)
+		assert.False(t, ok)
+
+		_, ok, err = client.OptionTag("Key9", "env")
+		assert.Nil(t, err)
+		assert.False(t, ok)
+	}
+
+	option, _, err := lazy.OptionOf("Key4")
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"owner": "bingoo", "env": "prod", "tier": "gold"}, option.Tags)
+}
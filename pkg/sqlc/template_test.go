@@ -0,0 +1,127 @@
+package sqlc_test
+
+import (
+	"context"
+	"errors"
+	"github.com/bingoohuang/gokv"
+	"github.com/bingoohuang/gokv/pkg/sqlc"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestMalformedTemplateMemoized(t *testing.T) {
+	d, driverName := newFakeDriver()
+	client := sqlc.NewClient(sqlc.Config{DriverName: driverName, GetSQL: "select v from kv where k = '{{.Key'"})
+	defer client.Close()
+
+	_, _, _, err1 := client.Get("k", nil)
+	assert.NotNil(t, err1)
+	_, _, _, err2 := client.Get("k", nil)
+	assert.True(t, err1 == err2, "the parse error should be memoized")
+	assert.Equal(t, 0, d.StatementCount())
+}
+
+// BenchmarkRender compares the Set with a memoized template to the Set with a template parsed for the first time.
+func BenchmarkRender(b *testing.B) {
+	log.SetOutput(io.Discard)
+	defer log.SetOutput(os.Stderr)
+
+	_, driverName := newFakeDriver()
+
+	b.Run("parse", func(b *testing.B) {
+		client := sqlc.NewClient(sqlc.Config{DriverName: driverName})
+		defer client.Close()
+
+		for i := 0; i < b.N; i++ {
+			// a distinct template every time
+			client.SetSQL = sqlc.DefaultSetSQL + " -- " + strconv.Itoa(i)
+			_ = client.Set("k", "v")
+		}
+	})
+
+	b.Run("memoized", func(b *testing.B) {
+		client := sqlc.NewClient(sqlc.Config{DriverName: driverName})
+		defer client.Close()
+
+		for i := 0; i < b.N; i++ {
+			_ = client.Set("k", "v")
+		}
+	})
+}
+
+func TestPrepare(t *testing.T) {
+	d, driverName := newFakeDriver()
+	client := sqlc.NewClient(sqlc.Config{DriverName: driverName})
+	defer client.Close()
+
+	assert.Nil(t, client.Prepare(context.Background()))
+	assert.Equal(t, 0, d.StatementCount())
+
+	malformed := sqlc.NewClient(sqlc.Config{DriverName: driverName, GetSQL: "select v from kv where k = '{{.Key'"})
+	defer malformed.Close()
+
+	err := malformed.Prepare(context.Background())
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "GetSQL: ")
+
+	// the parse error is memoized by Prepare
+	_, _, _, getErr := malformed.Get("k", nil)
+	assert.True(t, errors.Is(err, getErr))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.True(t, errors.Is(client.Prepare(ctx), context.Canceled))
+}
+
+func TestPrepareStatements(t *testing.T) {
+	d, driverName := newFakeDriver()
+	client := sqlc.NewClient(sqlc.Config{
+		DriverName:        driverName,
+		GetSQL:            "select v from kv where k = ? and state = 1",
+		SetSQL:            "insert into kv(k, v) values(?, ?)",
+		DelSQL:            "delete from kv where k = ?",
+		ArgBindings:       map[string][]string{"GetSQL": {"Key"}, "SetSQL": {"Key", "Value"}, "DelSQL": {"Key"}},
+		PrepareStatements: true,
+	})
+	defer client.Close()
+
+	assert.Nil(t, client.Prepare(context.Background()))
+	assert.Equal(t, uint64(5), client.Stats().Prepares, "Get, Set, Del, Keys and All are prepared")
+	assert.Equal(t, 0, d.StatementCount())
+
+	assert.Nil(t, client.Set("k1", "v1"))
+	client.Invalidate("k1")
+	_, _, _, err := client.Get("k1", nil)
+	assert.Nil(t, err)
+	_, err = client.Keys()
+	assert.Nil(t, err)
+	assert.Nil(t, client.Del("k1"))
+
+	assert.Equal(t, uint64(5), client.Stats().Prepares, "the prepared statements are reused")
+	assert.Equal(t, 4, d.StatementCount())
+
+	interpolated := sqlc.NewClient(sqlc.Config{DriverName: driverName, PrepareStatements: true})
+	defer interpolated.Close()
+	assert.Nil(t, interpolated.Prepare(context.Background()))
+	assert.Equal(t, uint64(2), interpolated.Stats().Prepares, "only Keys and All have no template actions")
+}
+
+// BenchmarkSetOption reports the allocations of Set with an option encoded by the JSON codec.
+func BenchmarkSetOption(b *testing.B) {
+	log.SetOutput(io.Discard)
+	defer log.SetOutput(os.Stderr)
+
+	_, driverName := newFakeDriver()
+	client := sqlc.NewClient(sqlc.Config{DriverName: driverName})
+	defer client.Close()
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_ = client.Set("k", "v", gokv.TTL(time.Minute), gokv.Tag("owner", "bingoo"))
+	}
+}
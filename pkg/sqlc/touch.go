@@ -0,0 +1,68 @@
+package sqlc
+
+import (
+	"context"
+	"github.com/bingoohuang/gokv"
+	"time"
+)
+
+// Touch extends the expiry of the key to ttl from now without changing its value by TouchSQL,
+// which should only update the expiry or the update time column, found is false when no row is affected.
+// The TTL and the update time of the cached value are updated on success.
+// gokv.ErrNotSupported is returned when TouchSQL is not set.
+func (c *Client) Touch(k string, ttl time.Duration) (found bool, er error) {
+	defer c.metrics.observe("touch", time.Now())
+
+	if c.TouchSQL == "" {
+		return false, gokv.ErrNotSupported
+	}
+
+	now := c.Now()
+	query, args, err := c.render(c.TouchSQL, map[string]interface{}{
+		"Key":        c.backendKey(k),
+		"RawKey":     k,
+		"TTL":        int64(ttl / time.Second),
+		"Time":       now.In(c.TimeLocation).Format(timeLayout),
+		"Expiry":     now.Add(ttl).In(c.TimeLocation).Format(timeLayout),
+		"ServerTime": c.ServerTimeSQL,
+	})
+	if err != nil {
+		return false, err
+	}
+	c.logQuery(query)
+
+	oldV, err := c.priorValue(background(), k)
+	if err != nil {
+		return false, err
+	}
+
+	db, err := c.openDB(c.DataSourceName)
+	if err != nil {
+		return false, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), c.QueryTimeout)
+	defer cancel()
+
+	result, err := c.execContext(ctx, db, "touch", k, query, args...)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil || affected == 0 {
+		return false, err
+	}
+
+	c.cacheLock.Lock()
+	if cv, ok := c.Cache.Get(k); ok {
+		cv.Option.TTL = ttl
+		cv.UpdateTime = now
+		c.Cache.Set(k, cv)
+	}
+	c.cacheLock.Unlock()
+
+	c.audit("touch", k, oldV, oldV)
+
+	return true, nil
+}
@@ -0,0 +1,78 @@
+package sqlc_test
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"github.com/bingoohuang/gokv"
+	"github.com/bingoohuang/gokv/pkg/sqlc"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestTouch(t *testing.T) {
+	dsn := startTestServer(t)
+
+	now := time.Date(2021, 3, 1, 10, 0, 0, 0, time.Local)
+	client := sqlc.NewClient(sqlc.Config{
+		DataSourceName: dsn,
+		SetSQL:         insertSetSQL,
+		TouchSQL:       "update kv set updated = '{{.Expiry}}' where k = '{{.Key}}' and state = 1",
+		Now:            func() time.Time { return now },
+	})
+	defer client.Close()
+
+	assert.Nil(t, client.Set("Key4", "value4", gokv.TTL(time.Minute)))
+
+	now = now.Add(30 * time.Second)
+	_, err := client.Touch("Key4", time.Hour)
+	assert.Nil(t, err)
+
+	db, err := sql.Open("mysql", dsn)
+	assert.Nil(t, err)
+	defer db.Close()
+
+	var v, updated string
+	assert.Nil(t, db.QueryRow("select v, updated from kv where k = 'Key4'").Scan(&v, &updated))
+	assert.Equal(t, "value4", v)
+	assert.Equal(t, "2021-03-01 11:00:30.000", updated)
+}
+
+func TestTouchCache(t *testing.T) {
+	d, driverName := newFakeDriver()
+	d.Exec = func(_ string, args []driver.NamedValue) (driver.Result, error) {
+		if len(args) > 0 && args[0].Value == "k2" {
+			return driver.RowsAffected(0), nil
+		}
+		return driver.RowsAffected(1), nil
+	}
+
+	now := time.Date(2021, 3, 1, 10, 0, 0, 0, time.Local)
+
+	sink := &recordingSink{}
+	client := sqlc.NewClient(sqlc.Config{
+		DriverName: driverName,
+		TouchSQL:   "update kv set ttl = {{.TTL}} where k = {{arg .Key}}",
+		Now:        func() time.Time { return now },
+		AuditSink:  sink,
+	})
+	defer client.Close()
+
+	assert.Nil(t, client.Set("k1", "v1", gokv.TTL(time.Minute)))
+	now = now.Add(30 * time.Second)
+
+	found, err := client.Touch("k1", time.Hour)
+	assert.Nil(t, err)
+	assert.True(t, found)
+	assert.Equal(t, time.Hour, client.CacheSnapshot()["k1"].Option.TTL)
+	assert.Equal(t, now, client.CacheSnapshot()["k1"].UpdateTime)
+
+	found, err = client.Touch("k2", time.Minute)
+	assert.Nil(t, err)
+	assert.False(t, found)
+	assert.Equal(t, auditRecord{Op: "touch", Key: "k1", Old: "v1", New: "v1"}, sink.records[len(sink.records)-1],
+		"only the touched keys are audited")
+
+	_, err = sqlc.NewClient(sqlc.Config{DriverName: driverName}).Touch("k1", time.Minute)
+	assert.Equal(t, gokv.ErrNotSupported, err)
+}
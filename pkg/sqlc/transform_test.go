@@ -0,0 +1,56 @@
+package sqlc_test
+
+import (
+	"database/sql"
+	"github.com/bingoohuang/gokv/pkg/sqlc"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func TestValueTransform(t *testing.T) {
+	dsn := startTestServer(t)
+
+	const getSQL = "select from_base64(v) from kv where k = '{{.Key}}' and state = 1"
+	for _, config := range []sqlc.Config{
+		{SetSQL: "insert into kv(k, v, state, created) values('{{.Key}}', to_base64('{{.Value}}'), 1, '{{.Time}}')"},
+		{SetSQL: "insert into kv(k, v, state, created) values('{{.Key}}', to_base64({{arg .Value}}), 1, '{{.Time}}')"},
+		{
+			SetSQL:  "insert into kv(k, v, state, created) values(?, to_base64(?), 1, ?)",
+			SetArgs: []string{"Key", "Value", "Time"},
+		},
+	} {
+		logger := &recordingLogger{}
+		config.DataSourceName = dsn + "?interpolateParams=true"
+		config.GetSQL = getSQL
+		config.Logger = logger
+		config.RedactFn = func(sql string) string { return sql }
+
+		client := sqlc.NewClient(config)
+		assert.Nil(t, client.Set("Key4", "secret"))
+
+		db, err := sql.Open("mysql", dsn)
+		assert.Nil(t, err)
+
+		var stored string
+		assert.Nil(t, db.QueryRow("select v from kv where k = 'Key4'").Scan(&stored))
+		assert.Equal(t, "c2VjcmV0", stored, "the value is transformed on write")
+
+		client.InvalidateAll()
+		found, v, _, err := client.Get("Key4", nil)
+		assert.Nil(t, err)
+		assert.True(t, found)
+		assert.Equal(t, "secret", v, "the value is reversed on read")
+
+		if strings.Contains(config.SetSQL, "'{{.Value}}'") {
+			assert.Contains(t, logger.messages[0], "to_base64('secret')")
+		} else {
+			assert.NotContains(t, logger.messages[0], "secret", "the raw value is bound")
+		}
+
+		_, err = db.Exec("delete from kv where k = 'Key4'")
+		assert.Nil(t, err)
+		assert.Nil(t, db.Close())
+		assert.Nil(t, client.Close())
+	}
+}
@@ -0,0 +1,47 @@
+package sqlc
+
+import (
+	"fmt"
+	"github.com/bingoohuang/gokv"
+	"github.com/bingoohuang/gokv/pkg/codec"
+	"time"
+)
+
+// SetTyped marshals the value by the codec of the key (see Config.Codec and Config.CodecResolver)
+// and stores it for the given key. Values stored by SetTyped must be read by GetTyped,
+// so that the same codec is used on both sides.
+func (c *Client) SetTyped(k string, v interface{}, fns ...gokv.OptionFn) error {
+	_, err := c.SetTypedX(k, v, fns...)
+	return err
+}
+
+// SetTypedX stores the value like SetTyped, and returns the updated time like SetX.
+func (c *Client) SetTypedX(k string, v interface{}, fns ...gokv.OptionFn) (updated time.Time, err error) {
+	start := time.Now()
+	data, err := c.codecOf(k).Marshal(v)
+	c.metrics.observePhase("codec", start)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("key:%s, error:%w", k, codec.WrapError("marshal", v, err))
+	}
+
+	return c.SetX(k, string(data), fns...)
+}
+
+// GetTyped retrieves the value for the given key and unmarshals it into dest
+// by the codec of the key (see Config.Codec and Config.CodecResolver), pairing with SetTyped.
+// If no value is found it returns (false, nil) and dest is untouched.
+func (c *Client) GetTyped(k string, dest interface{}) (found bool, err error) {
+	found, v, _, err := c.Get(k, nil)
+	if err != nil || !found {
+		return false, err
+	}
+
+	start := time.Now()
+	err = c.codecOf(k).Unmarshal([]byte(v), dest)
+	c.metrics.observePhase("codec", start)
+	if err != nil {
+		return false, fmt.Errorf("key:%s, error:%w", k, codec.WrapError("unmarshal", dest, err))
+	}
+
+	return true, nil
+}
@@ -0,0 +1,105 @@
+package sqlc_test
+
+import (
+	"errors"
+	"github.com/bingoohuang/gokv"
+	"github.com/bingoohuang/gokv/pkg/codec"
+	"github.com/bingoohuang/gokv/pkg/sqlc"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+type address struct {
+	City string
+	Zip  string
+}
+
+type person struct {
+	Name    string
+	Age     int
+	Address address
+}
+
+func TestGetTyped(t *testing.T) {
+	dsn := startTestServer(t)
+	writer := sqlc.NewClient(sqlc.Config{DataSourceName: dsn, SetSQL: insertSetSQL})
+	defer writer.Close()
+
+	bingoo := person{Name: "bingoo", Age: 18, Address: address{City: "Beijing", Zip: "100000"}}
+	data, err := codec.JSON.Marshal(bingoo)
+	assert.Nil(t, err)
+	assert.Nil(t, writer.Set("Key4", string(data)))
+
+	client := sqlc.NewClient(sqlc.Config{DataSourceName: dsn})
+	defer client.Close()
+
+	var p person
+	found, err := client.GetTyped("Key4", &p)
+	assert.Nil(t, err)
+	assert.True(t, found)
+	assert.Equal(t, bingoo, p)
+
+	found, err = client.GetTyped("Key9", &p)
+	assert.Nil(t, err)
+	assert.False(t, found)
+
+	var n int
+	_, err = client.GetTyped("Key4", &n)
+	var codecErr *codec.Error
+	assert.True(t, errors.As(err, &codecErr))
+	assert.Equal(t, "unmarshal", codecErr.Op)
+	assert.Contains(t, err.Error(), "Key4")
+	assert.Contains(t, err.Error(), "*int")
+
+	err = client.SetTyped("Key5", make(chan int))
+	assert.Contains(t, err.Error(), "marshal chan int")
+}
+
+func TestSetTypedRoundTrip(t *testing.T) {
+	config := sqlc.Config{DataSourceName: startTestServer(t), SetSQL: insertSetSQL}
+	writer := sqlc.NewClient(config)
+	defer writer.Close()
+
+	bingoo := person{Name: "bingoo", Age: 18, Address: address{City: "Beijing", Zip: "100000"}}
+	assert.Nil(t, writer.SetTyped("Key4", bingoo, gokv.NoCache()))
+
+	reader := sqlc.NewClient(config)
+	defer reader.Close()
+
+	var p person
+	found, err := reader.GetTyped("Key4", &p)
+	assert.Nil(t, err)
+	assert.True(t, found)
+	assert.Equal(t, bingoo, p)
+
+	_, v, _, err := reader.Get("Key4", nil)
+	assert.Nil(t, err)
+	assert.JSONEq(t, `{"Name":"bingoo","Age":18,"Address":{"City":"Beijing","Zip":"100000"}}`, v)
+
+	assert.NotNil(t, writer.SetTyped("Key5", func() {}))
+}
+
+func TestSetX(t *testing.T) {
+	d, driverName := newFakeDriver()
+	now := time.Date(2023, 5, 6, 7, 8, 9, 123456789, time.UTC)
+
+	client := sqlc.NewClient(sqlc.Config{
+		DriverName: driverName,
+		SetSQL:     "update kv set v = '{{.Value}}', updated = '{{.Time}}' where k = '{{.Key}}'",
+		Now:        func() time.Time { return now },
+	})
+	defer client.Close()
+
+	updated, err := client.SetX("k1", "v1")
+	assert.Nil(t, err)
+	assert.Equal(t, time.Date(2023, 5, 6, 7, 8, 9, 123000000, time.UTC), updated, "truncated to the milliseconds")
+	assert.Equal(t, "update kv set v = 'v1', updated = '2023-05-06 07:08:09.123' where k = 'k1'", d.Statements[0])
+	assert.Equal(t, updated, client.CacheSnapshot()["k1"].UpdateTime)
+
+	now = now.Add(time.Second)
+	updated, err = client.SetTypedX("k2", person{Name: "bingoo"})
+	assert.Nil(t, err)
+	assert.Equal(t, time.Date(2023, 5, 6, 7, 8, 10, 123000000, time.UTC), updated)
+	assert.Equal(t, updated, client.CacheSnapshot()["k2"].UpdateTime)
+}
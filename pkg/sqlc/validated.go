@@ -0,0 +1,45 @@
+package sqlc
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// ErrUnknownDriver is the error of a DriverName not registered to database/sql,
+// usually because the driver package is not imported, e.g. _ "github.com/go-sql-driver/mysql".
+type ErrUnknownDriver struct {
+	Name string
+	// Available is the names of the registered drivers.
+	Available []string
+}
+
+func (e ErrUnknownDriver) Error() string {
+	return fmt.Sprintf("unknown sql driver %q (forgotten import?), available: %s",
+		e.Name, strings.Join(e.Available, ", "))
+}
+
+// NewClientValidated creates a Client like NewClient, but fails early with ErrUnknownDriver
+// when the DriverName is not registered, or with the errors of the malformed SQL templates (see Prepare).
+func NewClientValidated(c Config) (*Client, error) {
+	client := NewClient(c)
+
+	if err := client.validate(); err != nil {
+		_ = client.Close()
+		return nil, err
+	}
+
+	return client, nil
+}
+
+func (c *Client) validate() error {
+	drivers := sql.Drivers()
+	for _, d := range drivers {
+		if d == c.DriverName {
+			return c.Prepare(context.Background())
+		}
+	}
+
+	return ErrUnknownDriver{Name: c.DriverName, Available: drivers}
+}
@@ -0,0 +1,25 @@
+package sqlc_test
+
+import (
+	"errors"
+	"github.com/bingoohuang/gokv/pkg/sqlc"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestNewClientValidated(t *testing.T) {
+	_, err := sqlc.NewClientValidated(sqlc.Config{DriverName: "bogus"})
+
+	var unknown sqlc.ErrUnknownDriver
+	assert.True(t, errors.As(err, &unknown))
+	assert.Equal(t, "bogus", unknown.Name)
+	assert.Contains(t, unknown.Available, "mysql")
+	assert.Contains(t, err.Error(), "mysql")
+
+	_, err = sqlc.NewClientValidated(sqlc.Config{DriverName: "mysql", GetSQL: "select v from kv where k = '{{.Key'"})
+	assert.NotNil(t, err)
+
+	client, err := sqlc.NewClientValidated(sqlc.Config{DriverName: "mysql"})
+	assert.Nil(t, err)
+	assert.Nil(t, client.Close())
+}
@@ -0,0 +1,36 @@
+package sqlc_test
+
+import (
+	"errors"
+	"github.com/bingoohuang/gokv"
+	"github.com/bingoohuang/gokv/pkg/sqlc"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestValueColumn(t *testing.T) {
+	dsn := startTestServer(t)
+	writer := sqlc.NewClient(sqlc.Config{DataSourceName: dsn, SetSQL: insertSetSQL})
+	defer writer.Close()
+	assert.Nil(t, writer.Set("Key4", "v4", gokv.TTL(time.Hour)))
+
+	client := sqlc.NewClient(sqlc.Config{
+		DataSourceName: dsn,
+		GetSQL:         "select o, concat(k, ':', v) as value from kv where k = '{{.Key}}' and state = 1",
+		ValueColumn:    "value",
+	})
+	defer client.Close()
+
+	found, v, option, err := client.Get("Key4", nil)
+	assert.Nil(t, err)
+	assert.True(t, found)
+	assert.Equal(t, "Key4:v4", v, "the computed value is selected by its name")
+	assert.Equal(t, time.Hour, option.TTL, "the option is the other column")
+
+	missing := sqlc.NewClient(sqlc.Config{DataSourceName: dsn, ValueColumn: "value"})
+	defer missing.Close()
+
+	_, _, _, err = missing.Get("Key4", nil)
+	assert.True(t, errors.Is(err, sqlc.ErrValueColumn))
+}
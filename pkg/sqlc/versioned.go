@@ -0,0 +1,143 @@
+package sqlc
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/bingoohuang/gokv"
+	"go.uber.org/multierr"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+var _ gokv.Versioned = (*Client)(nil)
+
+// ErrInvalidVersion is the error of a non-integer version interpolated raw by {{.Version}} in SetIfVersionSQL.
+var ErrInvalidVersion = errors.New("non-integer version interpolated by {{.Version}}")
+
+// rawVersion matches the version interpolated raw into SetIfVersionSQL.
+var rawVersion = regexp.MustCompile(`{{-?\s*\.Version\s*-?}}`)
+
+// GetWithVersion retrieves the value for the given key with its version selected by VersionGetSQL,
+// bypassing the cache. gokv.ErrNotSupported is returned when VersionGetSQL is not set.
+func (c *Client) GetWithVersion(k string) (v, version string, found bool, er error) {
+	defer c.metrics.observe("get", time.Now())
+
+	if c.VersionGetSQL == "" {
+		return "", "", false, gokv.ErrNotSupported
+	}
+
+	query, args, err := c.renderArgs("VersionGetSQL", c.VersionGetSQL,
+		map[string]interface{}{"Key": c.backendKey(k), "RawKey": k})
+	if err != nil {
+		return "", "", false, err
+	}
+	c.logQuery(query)
+
+	db, err := c.openDB(c.DataSourceName)
+	if err != nil {
+		return "", "", false, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), c.QueryTimeout)
+	defer cancel()
+
+	rows, release, err := c.queryContext(ctx, db, "get", k, query, args...)
+	if err != nil {
+		return "", "", false, err
+	}
+
+	defer func() { er = multierr.Combine(er, drainAndClose(rows), release()) }()
+
+	for row := 0; rows.Next(); row++ {
+		if row >= 1 {
+			return "", "", false, fmt.Errorf("key:%s, error:%w", k, ErrTooManyValues)
+		}
+
+		var value, ver sql.NullString
+		if err := rows.Scan(&value, &ver); err != nil {
+			return "", "", false, err
+		}
+		c.metrics.scanned()
+
+		if v, err = c.decompress(value.String); err != nil {
+			return "", "", false, err
+		}
+
+		version, found = ver.String, true
+	}
+
+	return v, version, found, nil
+}
+
+// SetIfVersion stores the value by SetIfVersionSQL, which should only update the row of the expected version
+// {{.Version}} and change its version, so that ok is false when no row is affected.
+// The key is evicted from the cache either way. gokv.ErrNotSupported is returned when SetIfVersionSQL is not set.
+func (c *Client) SetIfVersion(k, v, expectedVersion string) (ok bool, er error) {
+	defer c.metrics.observe("set", time.Now())
+
+	if c.SetIfVersionSQL == "" {
+		return false, gokv.ErrNotSupported
+	}
+
+	if err := c.validateKV(k, v); err != nil {
+		return false, err
+	}
+
+	if rawVersion.MatchString(c.SetIfVersionSQL) {
+		if _, err := strconv.ParseInt(expectedVersion, 10, 64); err != nil {
+			return false, fmt.Errorf("key:%s, version:%q, error:%w", k, expectedVersion, ErrInvalidVersion)
+		}
+	}
+
+	stored, err := c.compress(v)
+	if err != nil {
+		return false, err
+	}
+
+	query, args, err := c.renderArgs("SetIfVersionSQL", c.SetIfVersionSQL, map[string]interface{}{
+		"Key":        c.backendKey(k),
+		"RawKey":     k,
+		"Value":      stored,
+		"Version":    expectedVersion,
+		"Time":       c.formatTime(),
+		"ServerTime": c.ServerTimeSQL,
+	})
+	if err != nil {
+		return false, err
+	}
+	c.logQuery(query)
+
+	oldV, err := c.priorValue(background(), k)
+	if err != nil {
+		return false, err
+	}
+
+	db, err := c.openDB(c.DataSourceName)
+	if err != nil {
+		return false, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), c.QueryTimeout)
+	defer cancel()
+
+	c.cacheLock.Lock()
+	c.evict(k)
+	c.cacheLock.Unlock()
+
+	result, err := c.execContext(ctx, db, "set", k, query, args...)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil || affected == 0 {
+		return false, err
+	}
+
+	c.audit("set", k, oldV, v)
+
+	return true, nil
+}
@@ -0,0 +1,39 @@
+// Package storetest provides a conformance suite for gokv.Store implementations.
+package storetest
+
+import (
+	"errors"
+	"github.com/bingoohuang/gokv"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+// TestStore runs the conformance suite against the given store.
+// The store should not contain keys prefixed with "storetest".
+func TestStore(t *testing.T, s gokv.Store) {
+	const k = "storetest1"
+
+	_, err := s.Get(k)
+	assert.True(t, errors.Is(err, gokv.ErrKeyNotFound), "Get a missing key should return ErrKeyNotFound, got %v", err)
+
+	assert.Nil(t, s.Set(k, "v1"))
+
+	v, err := s.Get(k)
+	assert.Nil(t, err)
+	assert.Equal(t, "v1", v)
+
+	all, err := s.All()
+	assert.Nil(t, err)
+	assert.Equal(t, "v1", all[k])
+
+	assert.Nil(t, s.Del(k))
+
+	_, err = s.Get(k)
+	assert.True(t, errors.Is(err, gokv.ErrKeyNotFound), "Get a deleted key should return ErrKeyNotFound, got %v", err)
+
+	all, err = s.All()
+	assert.Nil(t, err)
+	assert.NotContains(t, all, k)
+
+	assert.Nil(t, s.Del("storetest-missing"), "Del a missing key should not lead to an error")
+}
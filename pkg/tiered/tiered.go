@@ -0,0 +1,180 @@
+// Package tiered provides a gokv.Store of a bounded hot tier in memory over a persistent cold tier.
+package tiered
+
+import (
+	"container/list"
+	"github.com/bingoohuang/gokv"
+	"sync"
+)
+
+// Store is a gokv.Store of an LRU in memory as the hot tier over any gokv.Store as the cold tier.
+// The writes go to both the tiers, the reads check the hot tier and then the cold one, promoting the values
+// found in the cold tier to the hot one, and the least recently used values are evicted from the hot tier
+// beyond its capacity, which demotes them to the cold tier, where they are still available.
+type Store struct {
+	Cold     gokv.Store
+	capacity int
+
+	lock    sync.Mutex
+	lru     *list.List
+	entries map[string]*list.Element
+	// reads are the reads of the cold tier in progress by the keys,
+	// which are not promoted when the keys are written meanwhile.
+	reads map[string]*coldRead
+}
+
+type entry struct {
+	k, v string
+}
+
+// coldRead is the reads of a key from the cold tier in progress.
+type coldRead struct {
+	readers int
+	// stale is set by the writes of the key during the reads.
+	stale bool
+}
+
+// New creates a Store of a hot tier of at most capacity values over the cold tier.
+func New(cold gokv.Store, capacity int) *Store {
+	if capacity <= 0 {
+		capacity = 1
+	}
+
+	return &Store{
+		Cold:     cold,
+		capacity: capacity,
+		lru:      list.New(),
+		entries:  make(map[string]*list.Element),
+		reads:    make(map[string]*coldRead),
+	}
+}
+
+// Hot returns the keys of the hot tier from the most recently used one.
+func (s *Store) Hot() []string {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	keys := make([]string, 0, s.lru.Len())
+	for e := s.lru.Front(); e != nil; e = e.Next() {
+		keys = append(keys, e.Value.(*entry).k)
+	}
+
+	return keys
+}
+
+// All returns the key values of the cold tier, which holds all of them.
+func (s *Store) All() (map[string]string, error) { return s.Cold.All() }
+
+// Get retrieves the value from the hot tier, or from the cold tier and promotes it to the hot one,
+// unless the key is written during the read, whose value may be stale then.
+func (s *Store) Get(k string) (string, error) {
+	s.lock.Lock()
+	if e, ok := s.entries[k]; ok {
+		s.lru.MoveToFront(e)
+		v := e.Value.(*entry).v
+		s.lock.Unlock()
+
+		return v, nil
+	}
+
+	r, ok := s.reads[k]
+	if !ok {
+		r = &coldRead{}
+		s.reads[k] = r
+	}
+	r.readers++
+	s.lock.Unlock()
+
+	v, err := s.Cold.Get(k)
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if r.readers--; r.readers == 0 {
+		delete(s.reads, k)
+	}
+
+	if err != nil {
+		return "", err
+	}
+
+	if !r.stale {
+		s.put(k, v)
+	}
+
+	return v, nil
+}
+
+// Set stores the value to the cold tier and the hot one.
+func (s *Store) Set(k, v string) error {
+	if err := s.Cold.Set(k, v); err != nil {
+		return err
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.wrote(k)
+	s.put(k, v)
+
+	return nil
+}
+
+// Del deletes the value from both the tiers.
+// The key is evicted again after the cold tier deletes it, in case a Get promoted it in between.
+func (s *Store) Del(k string) error {
+	s.evict(k)
+
+	if err := s.Cold.Del(k); err != nil {
+		return err
+	}
+
+	s.evict(k)
+
+	return nil
+}
+
+func (s *Store) evict(k string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.wrote(k)
+	if e, ok := s.entries[k]; ok {
+		s.lru.Remove(e)
+		delete(s.entries, k)
+	}
+}
+
+// wrote marks the cold reads of the key in progress as stale, the lock must be held.
+func (s *Store) wrote(k string) {
+	if r, ok := s.reads[k]; ok {
+		r.stale = true
+	}
+}
+
+// Close closes the cold tier if it is a gokv.Closer.
+func (s *Store) Close() error {
+	if c, ok := s.Cold.(gokv.Closer); ok {
+		return c.Close()
+	}
+
+	return nil
+}
+
+// put puts the value to the hot tier as the most recently used one, evicting the least recently used ones
+// beyond the capacity, which are already in the cold tier. The lock must be held.
+func (s *Store) put(k, v string) {
+	if e, ok := s.entries[k]; ok {
+		e.Value.(*entry).v = v
+		s.lru.MoveToFront(e)
+		return
+	}
+
+	s.entries[k] = s.lru.PushFront(&entry{k: k, v: v})
+
+	for s.lru.Len() > s.capacity {
+		e := s.lru.Back()
+		s.lru.Remove(e)
+		delete(s.entries, e.Value.(*entry).k)
+	}
+}
@@ -0,0 +1,110 @@
+package tiered_test
+
+import (
+	"errors"
+	"github.com/bingoohuang/gokv"
+	"github.com/bingoohuang/gokv/pkg/memory"
+	"github.com/bingoohuang/gokv/pkg/storetest"
+	"github.com/bingoohuang/gokv/pkg/tiered"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+// countingStore counts the Get calls of the cold tier.
+type countingStore struct {
+	*memory.Store
+	gets int
+}
+
+func (s *countingStore) Get(k string) (string, error) {
+	s.gets++
+	return s.Store.Get(k)
+}
+
+func TestStore(t *testing.T) {
+	storetest.TestStore(t, tiered.New(memory.New(), 2))
+}
+
+func TestTiers(t *testing.T) {
+	cold := &countingStore{Store: memory.New()}
+	s := tiered.New(cold, 2)
+
+	for _, k := range []string{"k1", "k2", "k3"} {
+		assert.Nil(t, s.Set(k, "v"+k))
+	}
+	assert.Equal(t, []string{"k3", "k2"}, s.Hot(), "k1 is demoted")
+
+	v, err := s.Get("k2")
+	assert.Nil(t, err)
+	assert.Equal(t, "vk2", v)
+	assert.Equal(t, 0, cold.gets, "k2 is served by the hot tier")
+
+	v, err = s.Get("k1")
+	assert.Nil(t, err)
+	assert.Equal(t, "vk1", v, "the demoted value survives in the cold tier")
+	assert.Equal(t, 1, cold.gets)
+	assert.Equal(t, []string{"k1", "k2"}, s.Hot(), "k1 is promoted, and k3 is demoted")
+
+	_, err = s.Get("k1")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, cold.gets)
+
+	kvs, err := s.All()
+	assert.Nil(t, err)
+	assert.Len(t, kvs, 3)
+
+	assert.Nil(t, s.Del("k1"))
+	assert.Equal(t, []string{"k2"}, s.Hot())
+	_, err = s.Get("k1")
+	assert.True(t, errors.Is(err, gokv.ErrKeyNotFound))
+}
+
+// gatedStore is a cold tier whose Get holds the value read until the gate is opened.
+type gatedStore struct {
+	*memory.Store
+	reading chan struct{}
+	gate    chan struct{}
+}
+
+func (s *gatedStore) Get(k string) (string, error) {
+	v, err := s.Store.Get(k)
+	s.reading <- struct{}{}
+	<-s.gate
+
+	return v, err
+}
+
+func TestConcurrentPromotion(t *testing.T) {
+	for _, write := range []string{"del", "set"} {
+		cold := &gatedStore{Store: memory.New(), reading: make(chan struct{}, 1), gate: make(chan struct{})}
+		assert.Nil(t, cold.Store.Set("k", "old"))
+		s := tiered.New(cold, 2)
+
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			v, err := s.Get("k")
+			assert.Nil(t, err)
+			assert.Equal(t, "old", v)
+		}()
+
+		<-cold.reading
+		if write == "del" {
+			assert.Nil(t, s.Del("k"))
+		} else {
+			assert.Nil(t, s.Set("k", "new"))
+		}
+		close(cold.gate)
+		<-done
+
+		// the stale value read before the write is not promoted to the hot tier
+		v, err := s.Get("k")
+		if write == "del" {
+			assert.True(t, errors.Is(err, gokv.ErrKeyNotFound), write)
+			assert.Empty(t, s.Hot())
+		} else {
+			assert.Nil(t, err)
+			assert.Equal(t, "new", v, write)
+		}
+	}
+}
@@ -0,0 +1,16 @@
+// Package util contains the helpers shared by the gokv stores.
+package util
+
+import "errors"
+
+// ErrEmptyKey is the error of an empty key.
+var ErrEmptyKey = errors.New("key must not be empty")
+
+// CheckKey checks the key is valid for the stores.
+func CheckKey(k string) error {
+	if k == "" {
+		return ErrEmptyKey
+	}
+
+	return nil
+}
@@ -0,0 +1,73 @@
+package util
+
+import (
+	"errors"
+	"fmt"
+	"go.uber.org/multierr"
+	"regexp"
+	"unicode/utf8"
+)
+
+var (
+	// ErrEmptyValue is the error of an empty value.
+	ErrEmptyValue = errors.New("value must not be empty")
+	// ErrKeyTooLong is the error of a key longer than ValidationConfig.MaxKeyLen.
+	ErrKeyTooLong = errors.New("key is too long")
+	// ErrValueTooLong is the error of a value longer than ValidationConfig.MaxValueLen.
+	ErrValueTooLong = errors.New("value is too long")
+	// ErrKeyCharset is the error of a key not matching ValidationConfig.KeyPattern.
+	ErrKeyCharset = errors.New("key contains disallowed characters")
+	// ErrInvalidUTF8 is the error of a key or value which is not valid UTF-8 when ValidationConfig.RequireUTF8 is set.
+	ErrInvalidUTF8 = errors.New("invalid UTF-8")
+)
+
+// ValidationConfig is the key value invariants required by a store.
+type ValidationConfig struct {
+	// MaxKeyLen is the max length of the keys in bytes, 0 for unlimited.
+	MaxKeyLen int
+	// MaxValueLen is the max length of the values in bytes, 0 for unlimited.
+	MaxValueLen int
+	// KeyPattern is the allowed pattern of the keys, e.g. ^[\w.:-]+$, nil for any.
+	KeyPattern *regexp.Regexp
+	// AllowEmptyKey allows the empty keys.
+	AllowEmptyKey bool
+	// AllowEmptyValue allows the empty values.
+	AllowEmptyValue bool
+	// RequireUTF8 requires the keys and the values are valid UTF-8.
+	RequireUTF8 bool
+}
+
+// Validate checks the key and the value against the config, and returns all the violations
+// aggregated by multierr, which can be classified by errors.Is with the errors above.
+func Validate(k, v string, cfg ValidationConfig) (err error) {
+	if k == "" {
+		if !cfg.AllowEmptyKey {
+			err = multierr.Append(err, ErrEmptyKey)
+		}
+	} else {
+		if cfg.MaxKeyLen > 0 && len(k) > cfg.MaxKeyLen {
+			err = multierr.Append(err, fmt.Errorf("%w: %d > %d", ErrKeyTooLong, len(k), cfg.MaxKeyLen))
+		}
+		if cfg.RequireUTF8 && !utf8.ValidString(k) {
+			err = multierr.Append(err, fmt.Errorf("key %w", ErrInvalidUTF8))
+		}
+		if cfg.KeyPattern != nil && !cfg.KeyPattern.MatchString(k) {
+			err = multierr.Append(err, fmt.Errorf("%w: %q", ErrKeyCharset, k))
+		}
+	}
+
+	if v == "" {
+		if !cfg.AllowEmptyValue {
+			err = multierr.Append(err, ErrEmptyValue)
+		}
+	} else {
+		if cfg.MaxValueLen > 0 && len(v) > cfg.MaxValueLen {
+			err = multierr.Append(err, fmt.Errorf("%w: %d > %d", ErrValueTooLong, len(v), cfg.MaxValueLen))
+		}
+		if cfg.RequireUTF8 && !utf8.ValidString(v) {
+			err = multierr.Append(err, fmt.Errorf("value %w", ErrInvalidUTF8))
+		}
+	}
+
+	return err
+}
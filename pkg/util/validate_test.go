@@ -0,0 +1,63 @@
+package util_test
+
+import (
+	"errors"
+	"github.com/bingoohuang/gokv/pkg/util"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/multierr"
+	"regexp"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+var testValidation = util.ValidationConfig{
+	MaxKeyLen:   10,
+	MaxValueLen: 20,
+	KeyPattern:  regexp.MustCompile(`^[\w.:-]+$`),
+	RequireUTF8: true,
+}
+
+func TestValidate(t *testing.T) {
+	assert.Nil(t, util.Validate("key1", "value1", testValidation))
+
+	err := util.Validate("", "", testValidation)
+	assert.True(t, errors.Is(err, util.ErrEmptyKey))
+	assert.True(t, errors.Is(err, util.ErrEmptyValue))
+	assert.Len(t, multierr.Errors(err), 2)
+
+	assert.Nil(t, util.Validate("", "", util.ValidationConfig{AllowEmptyKey: true, AllowEmptyValue: true}))
+
+	err = util.Validate("a key too long", strings.Repeat("v", 21), testValidation)
+	assert.True(t, errors.Is(err, util.ErrKeyTooLong))
+	assert.True(t, errors.Is(err, util.ErrKeyCharset))
+	assert.True(t, errors.Is(err, util.ErrValueTooLong))
+	assert.Len(t, multierr.Errors(err), 3)
+
+	err = util.Validate("key\xff", "\xfe", testValidation)
+	assert.True(t, errors.Is(err, util.ErrInvalidUTF8))
+	assert.True(t, errors.Is(err, util.ErrKeyCharset))
+}
+
+func FuzzValidate(f *testing.F) {
+	f.Add("key1", "value1")
+	f.Add("", "")
+	f.Add("a key too long", "a value which is too long")
+	f.Add("key\xff", "\xfe")
+
+	f.Fuzz(func(t *testing.T, k, v string) {
+		err := util.Validate(k, v, testValidation)
+
+		// the classification is consistent with the invariants
+		assert.Equal(t, k == "", errors.Is(err, util.ErrEmptyKey))
+		assert.Equal(t, v == "", errors.Is(err, util.ErrEmptyValue))
+		assert.Equal(t, len(k) > 10, errors.Is(err, util.ErrKeyTooLong))
+		assert.Equal(t, len(v) > 20, errors.Is(err, util.ErrValueTooLong))
+		assert.Equal(t, k != "" && !testValidation.KeyPattern.MatchString(k), errors.Is(err, util.ErrKeyCharset))
+		assert.Equal(t, (k != "" && !utf8.ValidString(k)) || (v != "" && !utf8.ValidString(v)),
+			errors.Is(err, util.ErrInvalidUTF8))
+
+		// the validation is deterministic
+		assert.Equal(t, err, util.Validate(k, v, testValidation))
+	})
+}
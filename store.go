@@ -1,17 +1,47 @@
 package gokv
 
+import (
+	"context"
+	"errors"
+)
+
+// ErrKeyNotFound is the error returned when the key is not found in the store.
+var ErrKeyNotFound = errors.New("key not found")
+
+// ErrReadOnly is the error returned when modifying a read-only store.
+var ErrReadOnly = errors.New("store is read-only")
+
+// ErrConflict is the error returned when a write conflicts with the current state of the store,
+// e.g. a duplicate key or a failed precondition.
+var ErrConflict = errors.New("write conflict")
+
 type Store interface {
 	// Keys list the keys in the store.
 	All() (map[string]string, error)
 	// Set stores the given value for the given key.
 	Set(k, v string) error
 	// Get retrieves the value for the given key.
+	// ErrKeyNotFound is returned when the key does not exist.
 	Get(k string) (v string, err error)
 	// Del deletes the stored value for the given key.
 	// Deleting a non-existing key-value pair does NOT lead to an error.
 	Del(k string) error
 }
 
+// ContextStore is a Store whose operations are bound to a context,
+// so that they can be canceled or given a deadline by the caller.
+type ContextStore interface {
+	// AllContext lists the key values in the store.
+	AllContext(ctx context.Context) (map[string]string, error)
+	// SetContext stores the given value for the given key.
+	SetContext(ctx context.Context, k, v string) error
+	// GetContext retrieves the value for the given key.
+	// ErrKeyNotFound is returned when the key does not exist.
+	GetContext(ctx context.Context, k string) (v string, err error)
+	// DelContext deletes the stored value for the given key.
+	DelContext(ctx context.Context, k string) error
+}
+
 type Closer interface {
 	// Close must be called when the work with the key-value store is done.
 	// Most (if not all) implementations are meant to be used long-lived,
@@ -0,0 +1,36 @@
+package gokv
+
+import (
+	"context"
+	"io"
+)
+
+// StreamStore is implemented by the stores which can stream the large values without materializing them,
+// e.g. the object storages or the large objects of databases.
+type StreamStore interface {
+	// GetStream opens the value of the key for reading, found is false when the key does not exist.
+	// The caller must close the reader.
+	GetStream(ctx context.Context, k string) (r io.ReadCloser, found bool, err error)
+	// SetStream stores the value of the key read from r until EOF.
+	SetStream(ctx context.Context, k string, r io.Reader) error
+}
+
+// GetStream opens the value of the key from s for reading, ErrNotSupported is returned when s is not a StreamStore.
+func GetStream(ctx context.Context, s Store, k string) (r io.ReadCloser, found bool, err error) {
+	ss, ok := s.(StreamStore)
+	if !ok {
+		return nil, false, ErrNotSupported
+	}
+
+	return ss.GetStream(ctx, k)
+}
+
+// SetStream stores the value read from r to s, ErrNotSupported is returned when s is not a StreamStore.
+func SetStream(ctx context.Context, s Store, k string, r io.Reader) error {
+	ss, ok := s.(StreamStore)
+	if !ok {
+		return ErrNotSupported
+	}
+
+	return ss.SetStream(ctx, k, r)
+}
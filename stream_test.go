@@ -0,0 +1,56 @@
+package gokv_test
+
+import (
+	"bytes"
+	"context"
+	"github.com/bingoohuang/gokv"
+	"github.com/bingoohuang/gokv/pkg/memory"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"strings"
+	"testing"
+)
+
+// streamStore streams the values in and out of a memory.Store.
+type streamStore struct {
+	*memory.Store
+}
+
+func (s streamStore) GetStream(_ context.Context, k string) (io.ReadCloser, bool, error) {
+	v, err := s.Get(k)
+	if err != nil {
+		return nil, false, nil
+	}
+
+	return io.NopCloser(strings.NewReader(v)), true, nil
+}
+
+func (s streamStore) SetStream(_ context.Context, k string, r io.Reader) error {
+	var buf bytes.Buffer
+	if _, err := io.Copy(&buf, r); err != nil {
+		return err
+	}
+
+	return s.Set(k, buf.String())
+}
+
+func TestStream(t *testing.T) {
+	ctx := context.Background()
+
+	assert.Equal(t, gokv.ErrNotSupported, gokv.SetStream(ctx, memory.New(), "k", strings.NewReader("v")))
+	_, _, err := gokv.GetStream(ctx, memory.New(), "k")
+	assert.Equal(t, gokv.ErrNotSupported, err)
+
+	s := streamStore{Store: memory.New()}
+	large := strings.Repeat("0123456789", 100000)
+	assert.Nil(t, gokv.SetStream(ctx, s, "k", strings.NewReader(large)))
+
+	r, found, err := gokv.GetStream(ctx, s, "k")
+	assert.Nil(t, err)
+	assert.True(t, found)
+
+	data, err := io.ReadAll(r)
+	assert.Nil(t, err)
+	assert.Nil(t, r.Close())
+	assert.Equal(t, large, string(data))
+}
@@ -0,0 +1,35 @@
+package gokv
+
+import "errors"
+
+// ErrNotSupported is the error returned when the store does not support the operation.
+var ErrNotSupported = errors.New("operation not supported")
+
+// Versioned is implemented by the stores supporting optimistic concurrency by versions of the values.
+type Versioned interface {
+	// GetWithVersion retrieves the value for the given key with its version.
+	GetWithVersion(k string) (v, version string, found bool, err error)
+	// SetIfVersion stores the value only if the current version of the key is expectedVersion,
+	// ok is false when the version does not match.
+	SetIfVersion(k, v, expectedVersion string) (ok bool, err error)
+}
+
+// GetWithVersion retrieves the value with its version from s, ErrNotSupported is returned when s is not Versioned.
+func GetWithVersion(s Store, k string) (v, version string, found bool, err error) {
+	vs, ok := s.(Versioned)
+	if !ok {
+		return "", "", false, ErrNotSupported
+	}
+
+	return vs.GetWithVersion(k)
+}
+
+// SetIfVersion stores the value to s conditionally, ErrNotSupported is returned when s is not Versioned.
+func SetIfVersion(s Store, k, v, expectedVersion string) (ok bool, err error) {
+	vs, isVersioned := s.(Versioned)
+	if !isVersioned {
+		return false, ErrNotSupported
+	}
+
+	return vs.SetIfVersion(k, v, expectedVersion)
+}